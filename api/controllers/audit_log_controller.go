@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+	"strconv"
+)
+
+// auditLogCSVHeader the columns of the exported audit logs
+var auditLogCSVHeader = []string{
+	"id", "created_at", "actor_id", "actor", "impersonator", "action", "resource", "resource_id",
+	"outcome", "error", "ip", "request_id", "changes",
+}
+
+type AuditLogController struct {
+	logger       lib.Logger
+	auditService services.AuditService
+}
+
+// Query
+// @Tags AuditLog
+// @Summary AuditLog Query
+// @Produce application/json
+// @Param data query models.AuditLogQueryParam true "AuditLogQueryParam"
+// @Success 200 {object} echox.Response{data=models.AuditLogQueryResult} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/audit-logs [get]
+func (c AuditLogController) Query(ctx echo.Context) error {
+	param := new(models.AuditLogQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	qr, err := c.auditService.WithContext(ctx.Request().Context()).Query(param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
+}
+
+// Export
+// @Tags AuditLog
+// @Summary AuditLog Export, the audit logs of the query as CSV regardless of its pagination
+// @Produce text/csv
+// @Param data query models.AuditLogQueryParam true "AuditLogQueryParam"
+// @Success 200 {string} string "audit-logs.csv"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/audit-logs/export [get]
+func (c AuditLogController) Export(ctx echo.Context) error {
+	param := new(models.AuditLogQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	response := ctx.Response()
+	writer := csv.NewWriter(response)
+
+	// the response starts with the first log, an error before it is still answered in JSON
+	started := false
+	start := func() error {
+		started = true
+		response.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		response.Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit-logs.csv"`)
+		response.WriteHeader(http.StatusOK)
+		return writer.Write(auditLogCSVHeader)
+	}
+
+	err := c.auditService.WithContext(ctx.Request().Context()).Export(param, func(log *models.AuditLog) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		changes, _ := json.Marshal(log.Changes)
+		return writer.Write([]string{
+			strconv.FormatUint(log.RecordID, 10),
+			log.CreatedAt.Format(constants.TimeFormat),
+			log.ActorID,
+			log.Actor,
+			log.Impersonator,
+			log.Action,
+			log.Resource,
+			log.ResourceID,
+			log.Outcome,
+			log.Error,
+			log.IP,
+			log.RequestID,
+			string(changes),
+		})
+	})
+	if err != nil && !started {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	} else if err != nil {
+		c.logger.Zap.Errorf("Audit log export error: %v", err)
+	} else if !started {
+		if err := start(); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// NewAuditLogController creates a new audit log controller
+func NewAuditLogController(
+	logger lib.Logger,
+	auditService services.AuditService,
+) AuditLogController {
+	return AuditLogController{
+		logger:       logger,
+		auditService: auditService,
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 type CaptchaController struct {
 	captcha lib.Captcha
+	metrics lib.Metrics
 }
 
 // GetCaptcha
@@ -23,6 +24,7 @@ type CaptchaController struct {
 // @Router /api/v1/publics/captcha [get]
 func (c CaptchaController) GetCaptcha(ctx echo.Context) error {
 	id, b64s, err := c.captcha.Generate()
+	c.metrics.CaptchaGenerated(err)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -45,6 +47,7 @@ func (c CaptchaController) VerifyCaptcha(ctx echo.Context) error {
 	}
 
 	ok := c.captcha.Verify(verify.Id, verify.Code, false)
+	c.metrics.CaptchaVerified(ok)
 	if !ok {
 		return echox.Response{Code: http.StatusBadRequest, Message: errors.CaptchaAnswerCodeNoMatch}.JSON(ctx)
 	}
@@ -54,6 +57,6 @@ func (c CaptchaController) VerifyCaptcha(ctx echo.Context) error {
 }
 
 // NewCaptchaController creates new captcha controller
-func NewCaptchaController(captcha lib.Captcha) CaptchaController {
-	return CaptchaController{captcha: captcha}
+func NewCaptchaController(captcha lib.Captcha, metrics lib.Metrics) CaptchaController {
+	return CaptchaController{captcha: captcha, metrics: metrics}
 }
@@ -9,4 +9,11 @@ var Module = fx.Options(
 	fx.Provide(NewUserController),
 	fx.Provide(NewRoleController),
 	fx.Provide(NewMenuController),
+	fx.Provide(NewRbacController),
+	fx.Provide(NewTrashController),
+	fx.Provide(NewWebhookController),
+	fx.Provide(NewCronController),
+	fx.Provide(NewAuditLogController),
+	fx.Provide(NewLoginLogController),
+	fx.Provide(NewSysController),
 )
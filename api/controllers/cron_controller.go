@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+)
+
+type CronController struct {
+	logger        lib.Logger
+	cronScheduler services.CronScheduler
+}
+
+// Query
+// @Tags Cron
+// @Summary Cron Job Query, with the next run and the last run of every job
+// @Produce application/json
+// @Success 200 {object} echox.Response{data=models.CronJobs} "ok"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/cron/jobs [get]
+func (c CronController) Query(ctx echo.Context) error {
+	jobs, err := c.cronScheduler.Jobs()
+	if err != nil {
+		return echox.Response{Code: http.StatusInternalServerError, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: jobs}.JSON(ctx)
+}
+
+// NewCronController creates a new cron controller
+func NewCronController(
+	logger lib.Logger,
+	cronScheduler services.CronScheduler,
+) CronController {
+	return CronController{
+		logger:        logger,
+		cronScheduler: cronScheduler,
+	}
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+)
+
+type LoginLogController struct {
+	logger          lib.Logger
+	loginLogService services.LoginLogService
+}
+
+// Query
+// @Tags LoginLog
+// @Summary LoginLog Query, the logins, logouts and token refreshes of every user
+// @Produce application/json
+// @Param data query models.LoginLogQueryParam true "LoginLogQueryParam"
+// @Success 200 {object} echox.Response{data=models.LoginLogQueryResult} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/login-logs [get]
+func (c LoginLogController) Query(ctx echo.Context) error {
+	param := new(models.LoginLogQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	qr, err := c.loginLogService.WithContext(ctx.Request().Context()).Query(param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
+}
+
+// NewLoginLogController creates a new login log controller
+func NewLoginLogController(
+	logger lib.Logger,
+	loginLogService services.LoginLogService,
+) LoginLogController {
+	return LoginLogController{
+		logger:          logger,
+		loginLogService: loginLogService,
+	}
+}
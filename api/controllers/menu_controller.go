@@ -2,9 +2,9 @@ package controllers
 
 import (
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 	"manuel71sj/go-api-template/api/services"
 	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
 	"manuel71sj/go-api-template/models/dto"
@@ -32,7 +32,7 @@ func (c MenuController) Query(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	qr, err := c.menuService.Query(param)
+	qr, err := c.menuService.WithContext(ctx.Request().Context()).Query(param)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -44,6 +44,24 @@ func (c MenuController) Query(ctx echo.Context) error {
 	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
 }
 
+// Export
+// @Tags Menu
+// @Summary Menu Export
+// @Produce application/x-yaml
+// @Success 200 {string} string "menu.yaml"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/menus/export [get]
+func (c MenuController) Export(ctx echo.Context) error {
+	data, err := c.menuService.WithContext(ctx.Request().Context()).ExportMenuFile()
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="menu.yaml"`)
+	return ctx.Blob(http.StatusOK, "application/x-yaml", data)
+}
+
 // Get
 // @Tags Menu
 // @Summary Menu Get By ID
@@ -54,11 +72,12 @@ func (c MenuController) Query(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/menus/{id} [get]
 func (c MenuController) Get(ctx echo.Context) error {
-	menu, err := c.menuService.Get(ctx.Param("id"))
+	menu, err := c.menuService.WithContext(ctx.Request().Context()).Get(ctx.Param("id"))
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
+	echox.SetETag(ctx, menu.Version)
 	return echox.Response{Code: http.StatusOK, Data: menu}.JSON(ctx)
 }
 
@@ -77,11 +96,10 @@ func (c MenuController) Create(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
 	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
 	menu.CreatedBy = claims.Username
 
-	id, err := c.menuService.WithTrx(trxHandle).Create(menu)
+	id, err := c.menuService.WithContext(ctx.Request().Context()).Create(menu)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -95,8 +113,11 @@ func (c MenuController) Create(ctx echo.Context) error {
 // @Produce application/json
 // @Param id path int true "menu id"
 // @Param data body models.Menu true "Menu"
+// @Param If-Match header string false "ETag of the record, instead of the version in the body, absent or * updates any version"
 // @Success 200 {object} echox.Response "ok"
 // @failure 400 {object} echox.Response "bad request"
+// @failure 409 {object} echox.Response{data=models.Menu} "record changed since the version of the body, data is the current record"
+// @failure 412 {object} echox.Response{data=models.Menu} "record changed since the If-Match etag, data is the current record"
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/menus/{id} [put]
 func (c MenuController) Update(ctx echo.Context) error {
@@ -105,8 +126,48 @@ func (c MenuController) Update(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
-	if err := c.menuService.WithTrx(trxHandle).Update(ctx.Param("id"), menu); err != nil {
+	// without a version in the body, the If-Match header is the precondition of the update
+	precondition := menu.Version == 0
+	if precondition {
+		version, err := echox.IfMatchVersion(ctx)
+		if err != nil {
+			return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+		}
+		menu.Version = version
+	}
+
+	menuService := c.menuService.WithContext(ctx.Request().Context())
+	if err := menuService.Update(ctx.Param("id"), menu); errors.Is(err, errors.DatabaseVersionConflict) {
+		// the current menu lets the client merge the changes
+		current, _ := menuService.Get(ctx.Param("id"))
+		code := http.StatusConflict
+		if precondition {
+			code = http.StatusPreconditionFailed
+		}
+		return echox.Response{Code: code, Message: err, Data: current}.JSON(ctx)
+	} else if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// MoveTree
+// @Tags Menu
+// @Summary Menu Move And Reorder
+// @Produce application/json
+// @Param data body models.MenuMoveParam true "MenuMoveParam"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/menus/tree [put]
+func (c MenuController) MoveTree(ctx echo.Context) error {
+	param := new(models.MenuMoveParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	if err := c.menuService.WithContext(ctx.Request().Context()).MoveMenus(param.Menus); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -123,8 +184,7 @@ func (c MenuController) Update(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/menus/{id} [delete]
 func (c MenuController) Delete(ctx echo.Context) error {
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
-	if err := c.menuService.WithTrx(trxHandle).Delete(ctx.Param("id")); err != nil {
+	if err := c.menuService.WithContext(ctx.Request().Context()).Delete(ctx.Param("id")); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -141,7 +201,7 @@ func (c MenuController) Delete(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/menus/{id}/enable [patch]
 func (c MenuController) Enable(ctx echo.Context) error {
-	if err := c.menuService.UpdateStatus(ctx.Param("id"), 1); err != nil {
+	if err := c.menuService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), 1); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -158,7 +218,7 @@ func (c MenuController) Enable(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/menus/{id}/disable [patch]
 func (c MenuController) Disable(ctx echo.Context) error {
-	if err := c.menuService.UpdateStatus(ctx.Param("id"), -1); err != nil {
+	if err := c.menuService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), -1); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -175,7 +235,7 @@ func (c MenuController) Disable(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/menus/{id}/actions [get]
 func (c MenuController) GetActions(ctx echo.Context) error {
-	actions, err := c.menuService.GetMenuActions(ctx.Param("id"))
+	actions, err := c.menuService.WithContext(ctx.Request().Context()).GetMenuActions(ctx.Param("id"))
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -199,8 +259,7 @@ func (c MenuController) UpdateActions(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
-	if err := c.menuService.WithTrx(trxHandle).UpdateActions(ctx.Param("id"), actions); err != nil {
+	if err := c.menuService.WithContext(ctx.Request().Context()).UpdateActions(ctx.Param("id"), actions); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
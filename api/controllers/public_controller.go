@@ -6,6 +6,7 @@ import (
 	"manuel71sj/go-api-template/constants"
 	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
 	"manuel71sj/go-api-template/models/dto"
 	"manuel71sj/go-api-template/pkg/echox"
 	"net/http"
@@ -13,11 +14,13 @@ import (
 )
 
 type PublicController struct {
-	userService services.UserService
-	authService services.AuthService
-	captcha     lib.Captcha
-	logger      lib.Logger
-	config      lib.Config
+	userService     services.UserService
+	authService     services.AuthService
+	loginLogService services.LoginLogService
+	captcha         lib.Captcha
+	logger          lib.Logger
+	config          lib.Config
+	metrics         lib.Metrics
 }
 
 type route struct {
@@ -58,7 +61,7 @@ func (c PublicController) SysRoutes(ctx echo.Context) error {
 func (c PublicController) UserInfo(ctx echo.Context) error {
 	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
 
-	userInfo, err := c.userService.GetUserInfo(claims.ID)
+	userInfo, err := c.userService.WithContext(ctx.Request().Context()).GetUserInfo(claims.ID)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -70,6 +73,7 @@ func (c PublicController) UserInfo(ctx echo.Context) error {
 // @Tags Public
 // @Summary UserMenuTree
 // @Produce application/json
+// @Param Accept-Language header string false "locale of the menu names, the user locale takes precedence"
 // @Success 200 {string} echox.Response{data=models.MenuTrees} "ok"
 // @failure 400 {string} echox.Response "bad request"
 // @failure 500 {string} echox.Response "internal error"
@@ -77,7 +81,7 @@ func (c PublicController) UserInfo(ctx echo.Context) error {
 func (c PublicController) MenuTree(ctx echo.Context) error {
 	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
 
-	menuTrees, err := c.userService.GetUserMenuTrees(claims.ID)
+	menuTrees, err := c.userService.WithContext(ctx.Request().Context()).GetUserMenuTrees(claims.ID, echox.AcceptLanguages(ctx)...)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -85,6 +89,25 @@ func (c PublicController) MenuTree(ctx echo.Context) error {
 	return echox.Response{Code: http.StatusOK, Data: menuTrees}.JSON(ctx)
 }
 
+// UserPermissions
+// @Tags Public
+// @Summary UserPermissions
+// @Produce application/json
+// @Success 200 {string} echox.Response{data=models.UserPermissions} "ok"
+// @failure 400 {string} echox.Response "bad request"
+// @failure 500 {string} echox.Response "internal error"
+// @Router /api/v1/publics/user/permissions [get]
+func (c PublicController) UserPermissions(ctx echo.Context) error {
+	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
+
+	permissions, err := c.userService.WithContext(ctx.Request().Context()).GetUserPermissions(claims.ID)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: permissions}.JSON(ctx)
+}
+
 // UserLogin
 // @Tags Public
 // @Summary UserLogin
@@ -102,24 +125,63 @@ func (c PublicController) UserLogin(ctx echo.Context) error {
 	}
 
 	if c.config.Auth.Captcha.Enable {
+		var err error
 		if login.CaptchaID == "" || login.CaptchaCode == "" {
-			return echox.Response{Code: http.StatusBadRequest, Message: errors.CaptchaAnswerCodeEmpty}.JSON(ctx)
+			err = errors.CaptchaAnswerCodeEmpty
+		} else {
+			ok := c.captcha.Verify(login.CaptchaID, login.CaptchaCode, false)
+			c.metrics.CaptchaVerified(ok)
+			if !ok {
+				err = errors.CaptchaAnswerCodeNoMatch
+			}
 		}
-		if !c.captcha.Verify(login.CaptchaID, login.CaptchaCode, false) {
-			return echox.Response{Code: http.StatusBadRequest, Message: errors.CaptchaAnswerCodeNoMatch}.JSON(ctx)
+
+		if err != nil {
+			c.recordLogin(ctx, models.LoginEventCaptcha, "", login.Username, err)
+			return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 		}
 	}
 
-	user, err := c.userService.Verify(login.Username, login.Password)
+	user, err := c.userService.WithContext(ctx.Request().Context()).Verify(login.Username, login.Password)
 	if err != nil {
+		c.recordLogin(ctx, models.LoginEventLogin, "", login.Username, err)
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	token, err := c.authService.GenerateToken(user)
+	token, err := c.authService.WithContext(ctx.Request().Context()).GenerateToken(user)
 	if err != nil {
+		c.recordLogin(ctx, models.LoginEventLogin, user.ID, user.Username, err)
 		return echox.Response{Code: http.StatusInternalServerError, Message: errors.AuthTokenGenerateFail}.JSON(ctx)
 	}
 
+	c.recordLogin(ctx, models.LoginEventLogin, user.ID, user.Username, nil)
+	return echox.Response{Code: http.StatusOK, Data: echo.Map{"token": token}}.JSON(ctx)
+}
+
+// UserRefresh
+// @Tags Public
+// @Summary UserRefresh, a new token for the current user while the user may still log in
+// @Produce application/json
+// @Success 200 {string} echox.Response "ok"
+// @failure 400 {string} echox.Response "bad request"
+// @failure 500 {string} echox.Response "internal error"
+// @Router /api/v1/publics/user/refresh [post]
+func (c PublicController) UserRefresh(ctx echo.Context) error {
+	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
+
+	user, err := c.userService.WithContext(ctx.Request().Context()).Reverify(claims.ID)
+	if err != nil {
+		c.recordLogin(ctx, models.LoginEventRefresh, claims.ID, claims.Username, err)
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	token, err := c.authService.WithContext(ctx.Request().Context()).GenerateToken(user)
+	if err != nil {
+		c.recordLogin(ctx, models.LoginEventRefresh, user.ID, user.Username, err)
+		return echox.Response{Code: http.StatusInternalServerError, Message: errors.AuthTokenGenerateFail}.JSON(ctx)
+	}
+
+	c.recordLogin(ctx, models.LoginEventRefresh, user.ID, user.Username, nil)
 	return echox.Response{Code: http.StatusOK, Data: echo.Map{"token": token}}.JSON(ctx)
 }
 
@@ -132,25 +194,46 @@ func (c PublicController) UserLogin(ctx echo.Context) error {
 func (c PublicController) UserLogout(ctx echo.Context) error {
 	claims, ok := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
 	if ok {
-		_ = c.authService.DestroyToken(claims.Username)
+		err := c.authService.WithContext(ctx.Request().Context()).DestroyToken(claims.Username)
+		c.recordLogin(ctx, models.LoginEventLogout, claims.ID, claims.Username, err)
 	}
 
 	return echox.Response{Code: http.StatusOK}.JSON(ctx)
 }
 
+// recordLogin writes the login log of the event of the user, a failure when err is not nil
+func (c PublicController) recordLogin(ctx echo.Context, event, userID, username string, err error) {
+	request := ctx.Request()
+
+	requestID := ctx.Response().Header().Get(echo.HeaderXRequestID)
+	if requestID == "" {
+		requestID = request.Header.Get(echo.HeaderXRequestID)
+	}
+
+	client := &dto.LoginClient{IP: ctx.RealIP(), UserAgent: request.UserAgent(), RequestID: requestID}
+	log := models.NewLoginLog(event, userID, username, client, err)
+
+	c.metrics.LoginEvent(log.Event, log.Outcome)
+	c.loginLogService.Record(request.Context(), log)
+}
+
 // NewPublicController creates new public controller
 func NewPublicController(
 	userService services.UserService,
 	authService services.AuthService,
+	loginLogService services.LoginLogService,
 	captcha lib.Captcha,
 	logger lib.Logger,
 	config lib.Config,
+	metrics lib.Metrics,
 ) PublicController {
 	return PublicController{
-		userService: userService,
-		authService: authService,
-		captcha:     captcha,
-		logger:      logger,
-		config:      config,
+		userService:     userService,
+		authService:     authService,
+		loginLogService: loginLogService,
+		captcha:         captcha,
+		logger:          logger,
+		config:          config,
+		metrics:         metrics,
 	}
 }
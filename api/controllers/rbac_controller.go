@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"github.com/labstack/echo/v4"
+	"io"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+)
+
+type RbacController struct {
+	logger      lib.Logger
+	rbacService services.RbacService
+}
+
+// Export
+// @Tags Rbac
+// @Summary Rbac Export
+// @Produce application/x-yaml,application/json
+// @Param data query models.RbacExportParam true "RbacExportParam"
+// @Success 200 {object} models.RbacBundle "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/rbac/export [get]
+func (c RbacController) Export(ctx echo.Context) error {
+	param := new(models.RbacExportParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	bundle, err := c.rbacService.WithContext(ctx.Request().Context()).Export(param.IncludeUsers)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	data, err := c.rbacService.Encode(bundle, param.Format)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	if param.Format == "json" {
+		ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="rbac.json"`)
+		return ctx.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, data)
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="rbac.yaml"`)
+	return ctx.Blob(http.StatusOK, "application/x-yaml", data)
+}
+
+// Import
+// @Tags Rbac
+// @Summary Rbac Import
+// @Accept application/x-yaml,application/json
+// @Produce application/json
+// @Param dry_run query bool false "dry run"
+// @Param strategy query string false "skip, overwrite or fail"
+// @Param data body models.RbacBundle true "RbacBundle"
+// @Success 200 {object} echox.Response{data=models.RbacImportResult} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/rbac/import [post]
+func (c RbacController) Import(ctx echo.Context) error {
+	param := new(models.RbacImportParam)
+	// The body is the bundle itself, so only the query params are bound here
+	if err := (&echo.DefaultBinder{}).BindQueryParams(ctx, param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	if err := ctx.Validate(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	data, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	bundle, err := c.rbacService.Decode(data)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
+	param.Operator = claims.Username
+
+	result, err := c.rbacService.WithContext(ctx.Request().Context()).Import(bundle, param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: result}.JSON(ctx)
+}
+
+// NewRbacController creates new rbac controller
+func NewRbacController(
+	logger lib.Logger,
+	rbacService services.RbacService,
+) RbacController {
+	return RbacController{
+		logger:      logger,
+		rbacService: rbacService,
+	}
+}
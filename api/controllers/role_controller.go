@@ -2,9 +2,9 @@ package controllers
 
 import (
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 	"manuel71sj/go-api-template/api/services"
 	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
 	"manuel71sj/go-api-template/models/dto"
@@ -32,7 +32,7 @@ func (c RoleController) Query(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	qr, err := c.roleService.Query(param)
+	qr, err := c.roleService.WithContext(ctx.Request().Context()).Query(param)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -50,7 +50,7 @@ func (c RoleController) Query(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/roles.all [get]
 func (c RoleController) GetAll(ctx echo.Context) error {
-	qr, err := c.roleService.Query(&models.RoleQueryParam{
+	qr, err := c.roleService.WithContext(ctx.Request().Context()).Query(&models.RoleQueryParam{
 		PaginationParam: dto.PaginationParam{PageSize: 999, Current: 1},
 	})
 	if err != nil {
@@ -70,11 +70,12 @@ func (c RoleController) GetAll(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/roles/{id} [get]
 func (c RoleController) Get(ctx echo.Context) error {
-	role, err := c.roleService.Get(ctx.Param("id"))
+	role, err := c.roleService.WithContext(ctx.Request().Context()).Get(ctx.Param("id"))
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
+	echox.SetETag(ctx, role.Version)
 	return echox.Response{Code: http.StatusOK, Data: role}.JSON(ctx)
 }
 
@@ -93,11 +94,10 @@ func (c RoleController) Create(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
 	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
 	role.CreatedBy = claims.Username
 
-	id, err := c.roleService.WithTrx(trxHandle).Create(role)
+	id, err := c.roleService.WithContext(ctx.Request().Context()).Create(role)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -111,8 +111,11 @@ func (c RoleController) Create(ctx echo.Context) error {
 // @Produce application/json
 // @Param id path int true "role id"
 // @Param data body models.Role true "Role"
+// @Param If-Match header string false "ETag of the record, instead of the version in the body, absent or * updates any version"
 // @Success 200 {object} echox.Response "ok"
 // @failure 400 {object} echox.Response "bad request"
+// @failure 409 {object} echox.Response{data=models.Role} "record changed since the version of the body, data is the current record"
+// @failure 412 {object} echox.Response{data=models.Role} "record changed since the If-Match etag, data is the current record"
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/roles/{id} [put]
 func (c RoleController) Update(ctx echo.Context) error {
@@ -121,8 +124,26 @@ func (c RoleController) Update(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
-	if err := c.roleService.WithTrx(trxHandle).Update(ctx.Param("id"), role); err != nil {
+	// without a version in the body, the If-Match header is the precondition of the update
+	precondition := role.Version == 0
+	if precondition {
+		version, err := echox.IfMatchVersion(ctx)
+		if err != nil {
+			return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+		}
+		role.Version = version
+	}
+
+	roleService := c.roleService.WithContext(ctx.Request().Context())
+	if err := roleService.Update(ctx.Param("id"), role); errors.Is(err, errors.DatabaseVersionConflict) {
+		// the current role lets the client merge the changes
+		current, _ := roleService.Get(ctx.Param("id"))
+		code := http.StatusConflict
+		if precondition {
+			code = http.StatusPreconditionFailed
+		}
+		return echox.Response{Code: code, Message: err, Data: current}.JSON(ctx)
+	} else if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -139,8 +160,7 @@ func (c RoleController) Update(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/roles/{id} [delete]
 func (c RoleController) Delete(ctx echo.Context) error {
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
-	if err := c.roleService.WithTrx(trxHandle).Delete(ctx.Param("id")); err != nil {
+	if err := c.roleService.WithContext(ctx.Request().Context()).Delete(ctx.Param("id")); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -157,7 +177,7 @@ func (c RoleController) Delete(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/roles/{id}/enable [put]
 func (c RoleController) Enable(ctx echo.Context) error {
-	if err := c.roleService.UpdateStatus(ctx.Param("id"), 1); err != nil {
+	if err := c.roleService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), 1); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -174,7 +194,7 @@ func (c RoleController) Enable(ctx echo.Context) error {
 // @failure 500 {object} echox.Response "internal error"
 // @Router /api/v1/roles/{id}/disable [put]
 func (c RoleController) Disable(ctx echo.Context) error {
-	if err := c.roleService.UpdateStatus(ctx.Param("id"), -1); err != nil {
+	if err := c.roleService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), -1); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
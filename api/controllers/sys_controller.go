@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+)
+
+type SysController struct {
+	logger           lib.Logger
+	db               lib.Database
+	menuCacheService services.MenuCacheService
+}
+
+// CacheStats
+// @Tags Sys
+// @Summary Sys Cache Stats, the hits and misses of the menu cache
+// @Produce application/json
+// @Success 200 {object} echox.Response{data=[]dto.CacheStat} "ok"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/sys/cache [get]
+func (c SysController) CacheStats(ctx echo.Context) error {
+	return echox.Response{Code: http.StatusOK, Data: c.menuCacheService.Stats()}.JSON(ctx)
+}
+
+// DatabaseStats
+// @Tags Sys
+// @Summary Sys Database Stats, the pools and the health of the primary and of the replicas
+// @Produce application/json
+// @Success 200 {object} echox.Response{data=[]lib.DatabaseStat} "ok"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/sys/database [get]
+func (c SysController) DatabaseStats(ctx echo.Context) error {
+	return echox.Response{Code: http.StatusOK, Data: c.db.Stats()}.JSON(ctx)
+}
+
+// NewSysController creates a new sys controller
+func NewSysController(
+	logger lib.Logger,
+	db lib.Database,
+	menuCacheService services.MenuCacheService,
+) SysController {
+	return SysController{
+		logger:           logger,
+		db:               db,
+		menuCacheService: menuCacheService,
+	}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+)
+
+type TrashController struct {
+	logger       lib.Logger
+	trashService services.TrashService
+}
+
+// Query
+// @Tags Trash
+// @Summary Trash Query
+// @Produce application/json
+// @Param resource path string true "users, roles or menus"
+// @Param data query models.TrashQueryParam true "TrashQueryParam"
+// @Success 200 {object} echox.Response{data=models.TrashQueryResult} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/trash/{resource} [get]
+func (c TrashController) Query(ctx echo.Context) error {
+	param := new(models.TrashQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	qr, err := c.trashService.WithContext(ctx.Request().Context()).Query(param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
+}
+
+// Restore
+// @Tags Trash
+// @Summary Trash Restore By ID
+// @Produce application/json
+// @Param resource path string true "users, roles or menus"
+// @Param id path string true "resource id"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/trash/{resource}/{id}/restore [post]
+func (c TrashController) Restore(ctx echo.Context) error {
+	if err := c.trashService.WithContext(ctx.Request().Context()).Restore(ctx.Param("resource"), ctx.Param("id")); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// Purge
+// @Tags Trash
+// @Summary Trash Purge By ID
+// @Produce application/json
+// @Param resource path string true "users, roles or menus"
+// @Param id path string true "resource id"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/trash/{resource}/{id} [delete]
+func (c TrashController) Purge(ctx echo.Context) error {
+	if err := c.trashService.WithContext(ctx.Request().Context()).Purge(ctx.Param("resource"), ctx.Param("id")); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// NewTrashController creates new trash controller
+func NewTrashController(
+	logger lib.Logger,
+	trashService services.TrashService,
+) TrashController {
+	return TrashController{
+		logger:       logger,
+		trashService: trashService,
+	}
+}
@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 	"manuel71sj/go-api-template/api/services"
 	"manuel71sj/go-api-template/constants"
 	"manuel71sj/go-api-template/errors"
@@ -15,8 +14,9 @@ import (
 )
 
 type UserController struct {
-	userService services.UserService
-	logger      lib.Logger
+	userService     services.UserService
+	loginLogService services.LoginLogService
+	logger          lib.Logger
 }
 
 // Query
@@ -38,7 +38,7 @@ func (c UserController) Query(ctx echo.Context) error {
 		param.RoleIDs = strings.Split(v, ",")
 	}
 
-	qr, err := c.userService.Query(param)
+	qr, err := c.userService.WithContext(ctx.Request().Context()).Query(param)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -57,7 +57,6 @@ func (c UserController) Query(ctx echo.Context) error {
 // @Router /api/v1/users [post]
 func (c UserController) Create(ctx echo.Context) error {
 	user := new(models.User)
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
 
 	if err := ctx.Bind(user); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
@@ -65,10 +64,10 @@ func (c UserController) Create(ctx echo.Context) error {
 		return echox.Response{Code: http.StatusBadRequest, Message: errors.UserPasswordRequired}.JSON(ctx)
 	}
 
-	claims, _ := ctx.Get(constants.CurrentUser).(dto.JwtClaims)
+	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
 	user.CreatedBy = claims.Username
 
-	qr, err := c.userService.WithTrx(trxHandle).Create(user)
+	qr, err := c.userService.WithContext(ctx.Request().Context()).Create(user)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -86,34 +85,81 @@ func (c UserController) Create(ctx echo.Context) error {
 // @Failure 500 {object} echox.Response "internal server error"
 // @Router /api/v1/users/{id} [get]
 func (c UserController) Get(ctx echo.Context) error {
-	user, err := c.userService.Get(ctx.Param("id"))
+	user, err := c.userService.WithContext(ctx.Request().Context()).Get(ctx.Param("id"))
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
+	echox.SetETag(ctx, user.Version)
 	return echox.Response{Code: http.StatusOK, Data: user}.JSON(ctx)
 }
 
+// QueryLoginLogs
+// @Tags User
+// @Summary User Login Log Query, the logins, logouts and token refreshes of the user
+// @Produce application/json
+// @Param id path string true "user id"
+// @Param data query models.LoginLogQueryParam true "LoginLogQueryParam"
+// @Success 200 {object} echox.Response{data=models.LoginLogQueryResult} "ok"
+// @Failure 400 {object} echox.Response "bad request"
+// @Failure 500 {object} echox.Response "internal server error"
+// @Router /api/v1/users/{id}/login-logs [get]
+func (c UserController) QueryLoginLogs(ctx echo.Context) error {
+	param := new(models.LoginLogQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	param.UserID = ctx.Param("id")
+
+	qr, err := c.loginLogService.WithContext(ctx.Request().Context()).Query(param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
+}
+
 // Update
 // @Tags User
 // @Summary User Update By ID
 // @Produce application/json
 // @Param id path int true "user id"
 // @Param data body models.User true "User"
+// @Param If-Match header string false "ETag of the record, instead of the version in the body, absent or * updates any version"
 // @Success 200 {object} echox.Response "ok"
 // @Failure 400 {object} echox.Response "bad request"
+// @Failure 409 {object} echox.Response{data=models.User} "record changed since the version of the body, data is the current record"
+// @Failure 412 {object} echox.Response{data=models.User} "record changed since the If-Match etag, data is the current record"
 // @Failure 500 {object} echox.Response "internal server error"
 // @Router /api/v1/users/{id} [put]
 func (c UserController) Update(ctx echo.Context) error {
 	user := new(models.User)
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
 
 	if err := ctx.Bind(user); err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
-	err := c.userService.WithTrx(trxHandle).Update(ctx.Param("id"), user)
-	if err != nil {
+	// without a version in the body, the If-Match header is the precondition of the update
+	precondition := user.Version == 0
+	if precondition {
+		version, err := echox.IfMatchVersion(ctx)
+		if err != nil {
+			return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+		}
+		user.Version = version
+	}
+
+	userService := c.userService.WithContext(ctx.Request().Context())
+	if err := userService.Update(ctx.Param("id"), user); errors.Is(err, errors.DatabaseVersionConflict) {
+		// the current user lets the client merge the changes
+		current, _ := userService.Get(ctx.Param("id"))
+		code := http.StatusConflict
+		if precondition {
+			code = http.StatusPreconditionFailed
+		}
+		return echox.Response{Code: code, Message: err, Data: current}.JSON(ctx)
+	} else if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
 
@@ -130,9 +176,7 @@ func (c UserController) Update(ctx echo.Context) error {
 // @Failure 500 {object} echox.Response "internal server error"
 // @Router /api/v1/users/{id} [delete]
 func (c UserController) Delete(ctx echo.Context) error {
-	trxHandle := ctx.Get(constants.DBTransaction).(*gorm.DB)
-
-	err := c.userService.WithTrx(trxHandle).Delete(ctx.Param("id"))
+	err := c.userService.WithContext(ctx.Request().Context()).Delete(ctx.Param("id"))
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -150,7 +194,7 @@ func (c UserController) Delete(ctx echo.Context) error {
 // @Failure 500 {object} echox.Response "internal server error"
 // @Router /api/v1/users/{id}/enable [put]
 func (c UserController) Enable(ctx echo.Context) error {
-	err := c.userService.UpdateStatus(ctx.Param("id"), 1)
+	err := c.userService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), 1)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -168,7 +212,7 @@ func (c UserController) Enable(ctx echo.Context) error {
 // @Failure 500 {object} echox.Response "internal server error"
 // @Router /api/v1/users/{id}/disable [put]
 func (c UserController) Disable(ctx echo.Context) error {
-	err := c.userService.UpdateStatus(ctx.Param("id"), -1)
+	err := c.userService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), -1)
 	if err != nil {
 		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
 	}
@@ -179,10 +223,12 @@ func (c UserController) Disable(ctx echo.Context) error {
 // NewUserController creates new user controller
 func NewUserController(
 	userService services.UserService,
+	loginLogService services.LoginLogService,
 	logger lib.Logger,
 ) UserController {
 	return UserController{
-		userService: userService,
-		logger:      logger,
+		userService:     userService,
+		loginLogService: loginLogService,
+		logger:          logger,
 	}
 }
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+)
+
+type WebhookController struct {
+	logger         lib.Logger
+	webhookService services.WebhookService
+}
+
+// Query
+// @Tags Webhook
+// @Summary Webhook Query
+// @Produce application/json
+// @Param data query models.WebhookQueryParam true "WebhookQueryParam"
+// @Success 200 {object} echox.Response{data=models.WebhookQueryResult} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks [get]
+func (c WebhookController) Query(ctx echo.Context) error {
+	param := new(models.WebhookQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	qr, err := c.webhookService.WithContext(ctx.Request().Context()).Query(param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
+}
+
+// Get
+// @Tags Webhook
+// @Summary Webhook Get By ID
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Success 200 {object} echox.Response{data=models.Webhook} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id} [get]
+func (c WebhookController) Get(ctx echo.Context) error {
+	webhook, err := c.webhookService.WithContext(ctx.Request().Context()).Get(ctx.Param("id"))
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	echox.SetETag(ctx, webhook.Version)
+	return echox.Response{Code: http.StatusOK, Data: webhook}.JSON(ctx)
+}
+
+// Create
+// @Tags Webhook
+// @Summary Webhook Create, an empty secret is generated
+// @Produce application/json
+// @Param data body models.WebhookForm true "WebhookForm"
+// @Success 200 {object} echox.Response "ok, data is the id and the secret, never returned again"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks [post]
+func (c WebhookController) Create(ctx echo.Context) error {
+	form := new(models.WebhookForm)
+	if err := ctx.Bind(form); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	webhook := form.ToWebhook()
+	claims, _ := ctx.Get(constants.CurrentUser).(*dto.JwtClaims)
+	webhook.CreatedBy = claims.Username
+
+	id, err := c.webhookService.WithContext(ctx.Request().Context()).Create(webhook)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: echo.Map{"id": id, "secret": webhook.Secret}}.JSON(ctx)
+}
+
+// Update
+// @Tags Webhook
+// @Summary Webhook Update By ID, an empty secret keeps the secret
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Param data body models.WebhookForm true "WebhookForm"
+// @Param If-Match header string false "ETag of the record, instead of the version in the body, absent or * updates any version"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 409 {object} echox.Response{data=models.Webhook} "record changed since the version of the body, data is the current record"
+// @failure 412 {object} echox.Response{data=models.Webhook} "record changed since the If-Match etag, data is the current record"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id} [put]
+func (c WebhookController) Update(ctx echo.Context) error {
+	form := new(models.WebhookForm)
+	if err := ctx.Bind(form); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	webhook := form.ToWebhook()
+	// without a version in the body, the If-Match header is the precondition of the update
+	precondition := webhook.Version == 0
+	if precondition {
+		version, err := echox.IfMatchVersion(ctx)
+		if err != nil {
+			return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+		}
+		webhook.Version = version
+	}
+
+	webhookService := c.webhookService.WithContext(ctx.Request().Context())
+	if err := webhookService.Update(ctx.Param("id"), webhook); errors.Is(err, errors.DatabaseVersionConflict) {
+		// the current webhook lets the client merge the changes
+		current, _ := webhookService.Get(ctx.Param("id"))
+		code := http.StatusConflict
+		if precondition {
+			code = http.StatusPreconditionFailed
+		}
+		return echox.Response{Code: code, Message: err, Data: current}.JSON(ctx)
+	} else if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// Delete
+// @Tags Webhook
+// @Summary Webhook Delete By ID, with its deliveries
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id} [delete]
+func (c WebhookController) Delete(ctx echo.Context) error {
+	if err := c.webhookService.WithContext(ctx.Request().Context()).Delete(ctx.Param("id")); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// Enable
+// @Tags Webhook
+// @Summary Webhook Enable By ID
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id}/enable [patch]
+func (c WebhookController) Enable(ctx echo.Context) error {
+	if err := c.webhookService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), 1); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// Disable
+// @Tags Webhook
+// @Summary Webhook Disable By ID
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Success 200 {object} echox.Response "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id}/disable [patch]
+func (c WebhookController) Disable(ctx echo.Context) error {
+	if err := c.webhookService.WithContext(ctx.Request().Context()).UpdateStatus(ctx.Param("id"), -1); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK}.JSON(ctx)
+}
+
+// Ping
+// @Tags Webhook
+// @Summary Webhook Ping By ID, sends a webhook.ping event right away
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Success 200 {object} echox.Response{data=models.WebhookDelivery} "ok, data is the delivery and its outcome"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id}/ping [post]
+func (c WebhookController) Ping(ctx echo.Context) error {
+	delivery, err := c.webhookService.WithContext(ctx.Request().Context()).Ping(ctx.Param("id"))
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: delivery}.JSON(ctx)
+}
+
+// RotateSecret
+// @Tags Webhook
+// @Summary Webhook Rotate Secret By ID, the new secret is returned only once
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Success 200 {object} echox.Response "ok, data is the secret"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id}/secret [post]
+func (c WebhookController) RotateSecret(ctx echo.Context) error {
+	secret, err := c.webhookService.WithContext(ctx.Request().Context()).RotateSecret(ctx.Param("id"))
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: echo.Map{"secret": secret}}.JSON(ctx)
+}
+
+// QueryDeliveries
+// @Tags Webhook
+// @Summary Webhook Delivery Query
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Param data query models.WebhookDeliveryQueryParam true "WebhookDeliveryQueryParam"
+// @Success 200 {object} echox.Response{data=models.WebhookDeliveryQueryResult} "ok"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (c WebhookController) QueryDeliveries(ctx echo.Context) error {
+	param := new(models.WebhookDeliveryQueryParam)
+	if err := ctx.Bind(param); err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	qr, err := c.webhookService.WithContext(ctx.Request().Context()).QueryDeliveries(param)
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: qr}.JSON(ctx)
+}
+
+// Redeliver
+// @Tags Webhook
+// @Summary Webhook Delivery Redeliver, sends the delivery again right away
+// @Produce application/json
+// @Param id path string true "webhook id"
+// @Param delivery_id path string true "delivery id"
+// @Success 200 {object} echox.Response{data=models.WebhookDelivery} "ok, data is the delivery and its outcome"
+// @failure 400 {object} echox.Response "bad request"
+// @failure 500 {object} echox.Response "internal error"
+// @Router /api/v1/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (c WebhookController) Redeliver(ctx echo.Context) error {
+	delivery, err := c.webhookService.WithContext(ctx.Request().Context()).Redeliver(ctx.Param("id"), ctx.Param("delivery_id"))
+	if err != nil {
+		return echox.Response{Code: http.StatusBadRequest, Message: err}.JSON(ctx)
+	}
+
+	return echox.Response{Code: http.StatusOK, Data: delivery}.JSON(ctx)
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(
+	logger lib.Logger,
+	webhookService services.WebhookService,
+) WebhookController {
+	return WebhookController{
+		logger:         logger,
+		webhookService: webhookService,
+	}
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/uuid"
+)
+
+// AuditMiddleware puts the actor of the request in its context, for the audit logs of the
+// changes made by the request. A request without X-Request-ID gets one, echoed in the response.
+type AuditMiddleware struct {
+	handler lib.HttpHandler
+	logger  lib.Logger
+
+	auditService services.AuditService
+}
+
+func (m AuditMiddleware) core() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+
+			requestID := request.Header.Get(echo.HeaderXRequestID)
+			if requestID == "" {
+				requestID = uuid.MustString()
+				ctx.Response().Header().Set(echo.HeaderXRequestID, requestID)
+			}
+
+			actor := &dto.AuditActor{IP: ctx.RealIP(), RequestID: requestID}
+			if claims, ok := ctx.Get(constants.CurrentUser).(*dto.JwtClaims); ok {
+				actor.UserID = claims.ID
+				actor.Username = claims.Username
+				actor.Impersonator = claims.Impersonator
+			}
+
+			auditCtx := m.auditService.Begin(request.Context(), actor)
+			ctx.SetRequest(request.WithContext(auditCtx))
+
+			// the failures recorded within the transaction of the route are written after its rollback
+			defer m.auditService.Flush(auditCtx)
+
+			return next(ctx)
+		}
+	}
+}
+
+func (m AuditMiddleware) Setup() {
+	m.logger.Zap.Info("Setting up audit middleware")
+	m.handler.Engine.Use(m.core())
+}
+
+// NewAuditMiddleware creates new audit middleware
+func NewAuditMiddleware(
+	handler lib.HttpHandler,
+	logger lib.Logger,
+	auditService services.AuditService,
+) AuditMiddleware {
+	return AuditMiddleware{
+		handler:      handler,
+		logger:       logger,
+		auditService: auditService,
+	}
+}
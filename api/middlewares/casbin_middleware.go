@@ -2,18 +2,24 @@ package middlewares
 
 import (
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"manuel71sj/go-api-template/api/services"
 	"manuel71sj/go-api-template/constants"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models/dto"
 	"manuel71sj/go-api-template/pkg/echox"
 	"net/http"
+	"time"
 )
 
 type CasbinMiddleware struct {
 	handler lib.HttpHandler
 	logger  lib.Logger
 	config  lib.Config
+	metrics lib.Metrics
+	tracing lib.Tracing
 
 	casbinService services.CasbinService
 }
@@ -36,7 +42,24 @@ func (m CasbinMiddleware) core() echo.MiddlewareFunc {
 				return echox.Response{Code: http.StatusUnauthorized}.JSON(ctx)
 			}
 
-			if ok, err := m.casbinService.Enforcer.Enforce(claims.ID, p, method); err != nil {
+			_, span := m.tracing.Start(request.Context(), "casbin.enforce", trace.WithAttributes(
+				attribute.String("casbin.subject", claims.ID),
+				attribute.String("casbin.object", p),
+				attribute.String("casbin.action", method),
+			))
+
+			start := time.Now()
+			ok, err := m.casbinService.Enforcer.Enforce(claims.ID, p, method)
+			m.metrics.ObserveEnforce(ok, err, time.Since(start))
+
+			span.SetAttributes(attribute.Bool("casbin.allowed", ok))
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+
+			if err != nil {
 				return echox.Response{Code: http.StatusForbidden, Message: err}.JSON(ctx)
 			} else if !ok {
 				return echox.Response{Code: http.StatusForbidden}.JSON(ctx)
@@ -60,12 +83,16 @@ func NewCasbinMiddleware(
 	handler lib.HttpHandler,
 	logger lib.Logger,
 	config lib.Config,
+	metrics lib.Metrics,
+	tracing lib.Tracing,
 	casbinService services.CasbinService,
 ) CasbinMiddleware {
 	return CasbinMiddleware{
 		handler:       handler,
 		logger:        logger,
 		config:        config,
+		metrics:       metrics,
+		tracing:       tracing,
 		casbinService: casbinService,
 	}
 }
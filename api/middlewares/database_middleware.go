@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/lib"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// databaseStickyCookie the cookie of the unix time until which the reads of a client stay on the primary
+const databaseStickyCookie = "db_sticky_until"
+
+// DatabaseMiddleware keeps the reads of a client on the primary database for the sticky window
+// after its writes, so that it reads its own writes despite the replication lag. The window
+// is carried by a cookie from a request to the next ones of the client, the other clients
+// keep reading from the replicas.
+type DatabaseMiddleware struct {
+	handler lib.HttpHandler
+	logger  lib.Logger
+	db      lib.Database
+}
+
+func (m DatabaseMiddleware) core() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			var until time.Time
+			if cookie, err := ctx.Cookie(databaseStickyCookie); err == nil {
+				if seconds, err := strconv.ParseInt(cookie.Value, 10, 64); err == nil {
+					until = time.Unix(seconds, 0)
+				}
+			}
+
+			request := ctx.Request()
+			stickyCtx, stickyUntil := m.db.ContextWithStickiness(request.Context(), until)
+			ctx.SetRequest(request.WithContext(stickyCtx))
+
+			// the writes of the request are done when its response is written
+			ctx.Response().Before(func() {
+				nUntil := stickyUntil()
+				if !nUntil.After(until) {
+					return
+				}
+
+				// rounded up to the second, the window never ends early
+				seconds := nUntil.Add(time.Second - 1).Unix()
+				ctx.SetCookie(&http.Cookie{
+					Name:     databaseStickyCookie,
+					Value:    strconv.FormatInt(seconds, 10),
+					Path:     "/",
+					Expires:  time.Unix(seconds, 0),
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			})
+
+			return next(ctx)
+		}
+	}
+}
+
+func (m DatabaseMiddleware) Setup() {
+	if !m.db.Replicated() {
+		return
+	}
+
+	m.logger.Zap.Info("Setting up database middleware")
+	m.handler.Engine.Use(m.core())
+}
+
+// NewDatabaseMiddleware creates new database middleware
+func NewDatabaseMiddleware(handler lib.HttpHandler, logger lib.Logger, db lib.Database) DatabaseMiddleware {
+	return DatabaseMiddleware{
+		handler: handler,
+		logger:  logger,
+		db:      db,
+	}
+}
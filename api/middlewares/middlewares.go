@@ -3,11 +3,15 @@ package middlewares
 import "go.uber.org/fx"
 
 var Module = fx.Options(
+	fx.Provide(NewTracingMiddleware),
 	fx.Provide(NewCoreMiddleware),
 	fx.Provide(NewCorsMiddleware),
 	fx.Provide(NewZapMiddleware),
+	fx.Provide(NewDatabaseMiddleware),
 	fx.Provide(NewAuthMiddleware),
 	fx.Provide(NewCasbinMiddleware),
+	fx.Provide(NewAuditMiddleware),
+	fx.Provide(NewTransactionMiddleware),
 	fx.Provide(NewMiddlewares),
 )
 
@@ -22,18 +26,24 @@ type Middlewares []IMiddleware
 // NewMiddlewares creates new middlewares
 // Register the middleware that should be applied directly (globally)
 func NewMiddlewares(
+	tracingMiddleware TracingMiddleware,
 	coreMiddleware CoreMiddleware,
 	corsMiddleware CorsMiddleware,
 	zapMiddleware ZapMiddleware,
+	databaseMiddleware DatabaseMiddleware,
 	authMiddleware AuthMiddleware,
 	casbinMiddleware CasbinMiddleware,
+	auditMiddleware AuditMiddleware,
 ) Middlewares {
 	return Middlewares{
+		tracingMiddleware,
 		coreMiddleware,
 		corsMiddleware,
 		zapMiddleware,
+		databaseMiddleware,
 		authMiddleware,
 		casbinMiddleware,
+		auditMiddleware,
 	}
 }
 
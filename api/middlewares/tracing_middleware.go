@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"manuel71sj/go-api-template/lib"
+	"net/http"
+)
+
+// TracingMiddleware starts the server span of the request, the child of the span of its
+// traceparent header. The span is in the context of the request for the spans of the
+// casbin enforcement, of the database statements and of the redis commands.
+type TracingMiddleware struct {
+	handler lib.HttpHandler
+	logger  lib.Logger
+	tracing lib.Tracing
+}
+
+func (m TracingMiddleware) core() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+
+			// by the route template, a request of no route is named by its method only
+			name := "HTTP " + request.Method
+			if route := ctx.Path(); route != "" {
+				name = request.Method + " " + route
+			}
+
+			spanCtx, span := m.tracing.Start(
+				m.tracing.Extract(request.Context(), request.Header),
+				name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(request.Method),
+					semconv.HTTPRoute(ctx.Path()),
+					semconv.URLPath(request.URL.Path),
+					semconv.ClientAddress(ctx.RealIP()),
+					semconv.UserAgentOriginal(request.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			ctx.SetRequest(request.WithContext(spanCtx))
+
+			err := next(ctx)
+			if err != nil {
+				ctx.Error(err)
+			}
+
+			status := ctx.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return nil
+		}
+	}
+}
+
+func (m TracingMiddleware) Setup() {
+	if !m.tracing.Enabled() {
+		return
+	}
+
+	m.logger.Zap.Info("Setting up tracing middleware")
+	m.handler.Engine.Use(m.core())
+}
+
+// NewTracingMiddleware creates new tracing middleware
+func NewTracingMiddleware(handler lib.HttpHandler, logger lib.Logger, tracing lib.Tracing) TracingMiddleware {
+	return TracingMiddleware{
+		handler: handler,
+		logger:  logger,
+		tracing: tracing,
+	}
+}
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/pkg/echox"
+	"net/http"
+	"time"
+)
+
+// TransactionMiddleware runs the handler of a route in a database transaction carried by
+// the request context. Routes opt in with Handle, the others never hold a connection
+// longer than a query.
+type TransactionMiddleware struct {
+	logger lib.Logger
+	db     lib.Database
+}
+
+// Handle begins the transaction before the handler and commits it after,
+// or rolls it back when the handler responds with an error status or panics
+func (m TransactionMiddleware) Handle() echo.MiddlewareFunc {
+	logger := m.logger.DesugarZap.With(zap.String("module", "transaction-mw"))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+
+			// Every row written by the request gets the same timestamps,
+			// so rows soft deleted together can be restored together
+			now := m.db.ORM.NowFunc()
+			txHandle := m.db.ORM.WithContext(request.Context()).
+				Session(&gorm.Session{NowFunc: func() time.Time { return now }}).
+				Begin()
+			if err := txHandle.Error; err != nil {
+				return echox.Response{
+					Code:    http.StatusInternalServerError,
+					Message: errors.Wrap(errors.DatabaseInternalError, err.Error()),
+				}.JSON(ctx)
+			}
+			logger.Info("Beginning database transaction")
+
+			defer func() {
+				if r := recover(); r != nil {
+					// the core middleware recovers and responds
+					logger.Info("Rolling back transaction due to panic")
+					txHandle.Rollback()
+					panic(r)
+				}
+			}()
+
+			// the AfterCommit functions of the handler, e.g. the cache invalidations, run once committed
+			txCtx, committed := lib.ContextWithCommitHooks(lib.ContextWithTransaction(request.Context(), txHandle))
+			ctx.SetRequest(request.WithContext(txCtx))
+
+			if err := next(ctx); err != nil {
+				ctx.Error(err)
+			}
+
+			code := ctx.Response().Status
+			// rollback transaction on server errors
+			if code >= 400 {
+				logger.Info(fmt.Sprintf("Rolling back transaction due to status code: %d", code))
+				txHandle.Rollback()
+			} else {
+				logger.Info("Committing transactions")
+				if err := txHandle.Commit().Error; err != nil {
+					logger.Error(fmt.Sprintf("Trx commit error: %v", err))
+				} else {
+					committed()
+				}
+			}
+
+			return nil
+		}
+	}
+}
+
+// NewTransactionMiddleware creates new database transactions middleware
+func NewTransactionMiddleware(logger lib.Logger, db lib.Database) TransactionMiddleware {
+	return TransactionMiddleware{
+		logger: logger,
+		db:     db,
+	}
+}
@@ -13,6 +13,7 @@ import (
 type ZapMiddleware struct {
 	handler lib.HttpHandler
 	logger  lib.Logger
+	metrics lib.Metrics
 }
 
 func (m ZapMiddleware) core() echo.MiddlewareFunc {
@@ -21,6 +22,7 @@ func (m ZapMiddleware) core() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
 			start := time.Now()
+			logger := logger.With(lib.TraceFields(ctx.Request().Context())...)
 
 			if err := next(ctx); err != nil {
 				logger = logger.With(zap.Error(err))
@@ -29,10 +31,18 @@ func (m ZapMiddleware) core() echo.MiddlewareFunc {
 
 			request := ctx.Request()
 			response := ctx.Response()
+			elapsed := time.Since(start)
+
+			// by the route template, the paths of no route share one series
+			route := ctx.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			m.metrics.ObserveHttp(request.Method, route, response.Status, elapsed)
 
 			fields := []zapcore.Field{
 				zap.String("remote_ip", ctx.RealIP()),
-				zap.String("time", time.Since(start).String()),
+				zap.String("time", elapsed.String()),
 				zap.String("host", request.Host),
 				zap.String("request", fmt.Sprintf("%s %s", request.Method, request.RequestURI)),
 				zap.Int("status", response.Status),
@@ -69,6 +79,6 @@ func (m ZapMiddleware) Setup() {
 }
 
 // NewZapMiddleware creates new zap middleware
-func NewZapMiddleware(handler lib.HttpHandler, logger lib.Logger) ZapMiddleware {
-	return ZapMiddleware{handler: handler, logger: logger}
+func NewZapMiddleware(handler lib.HttpHandler, logger lib.Logger, metrics lib.Metrics) ZapMiddleware {
+	return ZapMiddleware{handler: handler, logger: logger, metrics: metrics}
 }
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+)
+
+// AuditLogRepository database structure
+type AuditLogRepository struct {
+	Repository[models.AuditLog]
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r AuditLogRepository) WithContext(ctx context.Context) AuditLogRepository {
+	r.Repository = r.Repository.WithContext(ctx)
+	return r
+}
+
+func (r AuditLogRepository) Query(param *models.AuditLogQueryParam) (*models.AuditLogQueryResult, error) {
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.Actor; v != "" {
+			db = db.Where("actor = ?", v)
+		}
+
+		if v := param.Action; v != "" {
+			db = db.Where("action = ?", v)
+		}
+
+		if v := param.Resource; v != "" {
+			db = db.Where("resource = ?", v)
+		}
+
+		if v := param.ResourceID; v != "" {
+			db = db.Where("resource_id = ?", v)
+		}
+
+		if v := param.RequestID; v != "" {
+			db = db.Where("request_id = ?", v)
+		}
+
+		if v := param.Outcome; v != "" {
+			db = db.Where("outcome = ?", v)
+		}
+
+		return db
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	qr := &models.AuditLogQueryResult{
+		Pagination: pagination,
+		List:       list,
+	}
+
+	return qr, nil
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db lib.Database, logger lib.Logger) AuditLogRepository {
+	return AuditLogRepository{
+		Repository: NewRepository[models.AuditLog](db, logger),
+	}
+}
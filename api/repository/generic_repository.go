@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models/database"
+	"manuel71sj/go-api-template/models/dto"
+)
+
+// createBatchSize rows per INSERT of CreateInBatches
+const createBatchSize = 100
+
+// QueryParam the list parameters shared by the query params of the models
+type QueryParam interface {
+	GetPaginationParam() dto.PaginationParam
+	ParseFilters() ([]*dto.Filter, error)
+	ParseOrder() (string, error)
+}
+
+// Repository the CRUD shared by the repositories of the models, whose rows are identified
+// by their id column. A resource repository embeds it and adds its own queries.
+type Repository[T any] struct {
+	db     lib.Database
+	logger lib.Logger
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r Repository[T]) WithContext(ctx context.Context) Repository[T] {
+	r.db.ORM = r.db.WithContext(ctx)
+	return r
+}
+
+// Model starts a query on the table of T
+func (r Repository[T]) Model() *gorm.DB {
+	return r.db.ORM.Model(new(T))
+}
+
+// Query lists a page of T narrowed by the scopes, the filters and the sort of param
+func (r Repository[T]) Query(param QueryParam, scopes ...func(db *gorm.DB) *gorm.DB) ([]*T, *dto.Pagination, error) {
+	db := r.Model().Scopes(scopes...)
+
+	filters, err := param.ParseFilters()
+	if err != nil {
+		return nil, nil, err
+	}
+	db = db.Scopes(QueryFilters(filters))
+
+	order, err := param.ParseOrder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	list := make([]*T, 0)
+	pagination, err := QueryPagination(db, param.GetPaginationParam(), order, &list)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return list, pagination, nil
+}
+
+func (r Repository[T]) Get(id string) (*T, error) {
+	item := new(T)
+
+	if ok, err := QueryOne(r.db.ORM.Model(item).Where("id = ?", id), item); errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !ok) {
+		return nil, errors.DatabaseRecordNotFound
+	} else if err != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+	}
+
+	return item, nil
+}
+
+func (r Repository[T]) Create(item *T) error {
+	result := r.db.ORM.Model(item).Create(item)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// CreateInBatches inserts the items with one statement per createBatchSize rows
+func (r Repository[T]) CreateInBatches(items []*T) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	result := r.db.ORM.Model(new(T)).CreateInBatches(items, createBatchSize)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+func (r Repository[T]) Update(id string, item *T) error {
+	result := r.db.ORM.Model(item).Where("id = ?", id).Updates(item)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// UpdateVersioned writes the item only if its version is still the version of the item
+// and increments the version, the items of T must be database.Versioned
+func (r Repository[T]) UpdateVersioned(id string, item *T) error {
+	versioned, ok := any(item).(database.Versioned)
+	if !ok {
+		return errors.Wrapf(errors.DatabaseInternalError, "%T has no version", item)
+	}
+
+	version := versioned.GetVersion()
+	versioned.SetVersion(version + 1)
+
+	result := r.db.ORM.Model(item).Where("id = ? AND version = ?", id, version).Updates(item)
+	if result.Error != nil {
+		// the item keeps the version that is stored
+		versioned.SetVersion(version)
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	} else if result.RowsAffected == 0 {
+		versioned.SetVersion(version)
+		return errors.DatabaseVersionConflict
+	}
+
+	return nil
+}
+
+// UpdateColumns updates only the given columns, zero values included
+func (r Repository[T]) UpdateColumns(id string, item *T, columns ...string) error {
+	result := r.db.ORM.Model(item).Where("id = ?", id).Select(columns).Updates(item)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// UpdateFields sets the values by column and increments the version
+func (r Repository[T]) UpdateFields(id string, values map[string]interface{}) error {
+	fields := make(map[string]interface{}, len(values)+1)
+	for column, value := range values {
+		fields[column] = value
+	}
+	fields["version"] = gorm.Expr("version + 1")
+
+	result := r.db.ORM.Model(new(T)).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+func (r Repository[T]) UpdateStatus(id string, status int) error {
+	return r.UpdateFields(id, map[string]interface{}{"status": status})
+}
+
+func (r Repository[T]) Delete(id string) error {
+	return r.DeleteWhere("id = ?", id)
+}
+
+// DeleteWhere deletes the rows matching the condition
+func (r Repository[T]) DeleteWhere(query string, args ...interface{}) error {
+	item := new(T)
+
+	result := r.db.ORM.Model(item).Where(query, args...).Delete(item)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// NewRepository creates a new repository of T
+func NewRepository[T any](db lib.Database, logger lib.Logger) Repository[T] {
+	return Repository[T]{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"time"
+)
+
+// LoginLogRepository database structure
+type LoginLogRepository struct {
+	Repository[models.LoginLog]
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r LoginLogRepository) WithContext(ctx context.Context) LoginLogRepository {
+	r.Repository = r.Repository.WithContext(ctx)
+	return r
+}
+
+func (r LoginLogRepository) Query(param *models.LoginLogQueryParam) (*models.LoginLogQueryResult, error) {
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.UserID; v != "" {
+			db = db.Where("user_id = ?", v)
+		}
+
+		if v := param.Username; v != "" {
+			db = db.Where("username = ?", v)
+		}
+
+		if v := param.Event; v != "" {
+			db = db.Where("event = ?", v)
+		}
+
+		if v := param.Outcome; v != "" {
+			db = db.Where("outcome = ?", v)
+		}
+
+		if v := param.IP; v != "" {
+			db = db.Where("ip = ?", v)
+		}
+
+		return db
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	qr := &models.LoginLogQueryResult{
+		Pagination: pagination,
+		List:       list,
+	}
+
+	return qr, nil
+}
+
+// LoggedIn reports whether the user logged in successfully since the time, from the column
+// value when column is not empty
+func (r LoginLogRepository) LoggedIn(userID string, since time.Time, column, value string) (bool, error) {
+	db := r.Model().Where("user_id = ? AND event = ? AND outcome = ? AND created_at >= ?",
+		userID, models.LoginEventLogin, models.LoginOutcomeSuccess, since)
+	if column != "" {
+		db = db.Where(column+" = ?", value)
+	}
+
+	var ids []uint64
+	if err := db.Limit(1).Pluck("record_id", &ids).Error; err != nil {
+		return false, errors.Wrap(errors.DatabaseInternalError, err.Error())
+	}
+
+	return len(ids) > 0, nil
+}
+
+// DeleteBefore deletes the login logs created before the time, it returns their count
+func (r LoginLogRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.Model().Where("created_at < ?", before).Delete(new(models.LoginLog))
+	if result.Error != nil {
+		return 0, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return result.RowsAffected, nil
+}
+
+// NewLoginLogRepository creates a new login log repository
+func NewLoginLogRepository(db lib.Database, logger lib.Logger) LoginLogRepository {
+	return LoginLogRepository{
+		Repository: NewRepository[models.LoginLog](db, logger),
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+)
+
+// MenuI18nRepository database structure
+type MenuI18nRepository struct {
+	Repository[models.MenuI18n]
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r MenuI18nRepository) WithContext(ctx context.Context) MenuI18nRepository {
+	r.Repository = r.Repository.WithContext(ctx)
+	return r
+}
+
+func (r MenuI18nRepository) Query(param *models.MenuI18nQueryParam) (*models.MenuI18nQueryResult, error) {
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.MenuID; v != "" {
+			db = db.Where("menu_id = ?", v)
+		}
+
+		if v := param.MenuIDs; len(v) > 0 {
+			db = db.Where("menu_id IN (?)", v)
+		}
+
+		if v := param.Locale; v != "" {
+			db = db.Where("locale = ?", v)
+		}
+
+		return db
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	qr := &models.MenuI18nQueryResult{
+		Pagination: pagination,
+		List:       list,
+	}
+
+	return qr, nil
+}
+
+// DeleteByTarget deletes the names of a menu, or of one of its actions when actionID is set
+func (r MenuI18nRepository) DeleteByTarget(menuID, actionID string) error {
+	return r.DeleteWhere("menu_id = ? AND action_id = ?", menuID, actionID)
+}
+
+func (r MenuI18nRepository) DeleteByMenuID(menuID string) error {
+	return r.DeleteWhere("menu_id = ?", menuID)
+}
+
+// NewMenuI18nRepository creates a new menu i18n repository
+func NewMenuI18nRepository(db lib.Database, logger lib.Logger) MenuI18nRepository {
+	return MenuI18nRepository{
+		Repository: NewRepository[models.MenuI18n](db, logger),
+	}
+}
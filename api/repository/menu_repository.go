@@ -1,68 +1,66 @@
 package repository
 
 import (
+	"context"
 	"gorm.io/gorm"
 	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
+	"strconv"
+	"strings"
 )
 
 // MenuRepository database structure
 type MenuRepository struct {
-	db     lib.Database
-	logger lib.Logger
+	Repository[models.Menu]
 }
 
-// WithTrx enables repository with transaction
-func (m MenuRepository) WithTrx(trxHandle *gorm.DB) MenuRepository {
-	if trxHandle == nil {
-		m.logger.Zap.Error("Transaction Database not found in echo context.")
-		return m
-	}
-
-	m.db.ORM = trxHandle
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (m MenuRepository) WithContext(ctx context.Context) MenuRepository {
+	m.Repository = m.Repository.WithContext(ctx)
 	return m
 }
 
 func (m MenuRepository) Query(param *models.MenuQueryParam) (*models.MenuQueryResult, error) {
-	db := m.db.ORM.Model(&models.Menu{})
-
-	if v := param.IDs; len(v) > 0 {
-		db = db.Where("id IN (?)", v)
-	}
-
-	if v := param.Name; v != "" {
-		db = db.Where("name = ?", v)
-	}
-
-	if v := param.ParentID; v != "" {
-		db = db.Where("parent_id = ?", v)
-	}
-
-	if v := param.PrefixParentPath; v != "" {
-		db = db.Where("parent_path LIKE ?", v+"%")
-	}
-
-	if v := param.Hidden; v != 0 {
-		//db = db.Where("show_status = ?", v)
-		db = db.Where("hidden = ?", v)
-	}
-
-	if v := param.Status; v != 0 {
-		db = db.Where("status = ?", v)
-	}
-
-	if v := param.QueryValue; v != "" {
-		v = "%" + v + "%"
-		db = db.Where("name LIKE ? OR remark LIKE ?", v, v)
-	}
-
-	db = db.Order(param.OrderParam.ParseOrder())
-
-	list := make(models.Menus, 0)
-	pagination, err := QueryPagination(db, param.PaginationParam, &list)
+	list, pagination, err := m.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.IDs; len(v) > 0 {
+			db = db.Where("id IN (?)", v)
+		}
+
+		if v := param.Code; v != "" {
+			db = db.Where("code = ?", v)
+		}
+
+		if v := param.Name; v != "" {
+			db = db.Where("name = ?", v)
+		}
+
+		if v := param.ParentID; v != "" {
+			db = db.Where("parent_id = ?", v)
+		}
+
+		if v := param.PrefixParentPath; v != "" {
+			db = db.Where("parent_path LIKE ?", v+"%")
+		}
+
+		if v := param.Hidden; v != 0 {
+			//db = db.Where("show_status = ?", v)
+			db = db.Where("hidden = ?", v)
+		}
+
+		if v := param.Status; v != 0 {
+			db = db.Where("status = ?", v)
+		}
+
+		if v := param.QueryValue; v != "" {
+			v = "%" + v + "%"
+			db = QueryLike(db, v, "name", "remark")
+		}
+
+		return db
+	})
 	if err != nil {
-		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+		return nil, err
 	}
 
 	qr := &models.MenuQueryResult{
@@ -73,64 +71,50 @@ func (m MenuRepository) Query(param *models.MenuQueryParam) (*models.MenuQueryRe
 	return qr, nil
 }
 
-func (m MenuRepository) Get(id string) (*models.Menu, error) {
-	menu := new(models.Menu)
-
-	if ok, err := QueryOne(m.db.ORM.Model(menu).Where("id = ?", id), menu); err != nil {
-		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
-	} else if !ok {
-		return nil, errors.DatabaseRecordNotFound
-	}
-
-	return menu, nil
-}
-
-func (m MenuRepository) Create(menu *models.Menu) error {
-	result := m.db.ORM.Model(menu).Create(menu)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
+// Update writes the menu only if its version is still menu.Version and increments the version
 func (m MenuRepository) Update(id string, menu *models.Menu) error {
-	result := m.db.ORM.Model(menu).Where("id = ?", id).Updates(menu)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
-func (m MenuRepository) Delete(id string) error {
-	menu := new(models.Menu)
-
-	result := m.db.ORM.Model(menu).Where("id = ?", id).Delete(menu)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
-func (m MenuRepository) UpdateStatus(id string, status int) error {
-	menu := new(models.Menu)
-
-	result := m.db.ORM.Model(menu).Where("id = ?", id).Update("status", status)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
+	return m.UpdateVersioned(id, menu)
 }
 
 func (m MenuRepository) UpdateParentPath(id string, parentPath string) error {
-	menu := new(models.Menu)
+	return m.UpdateFields(id, map[string]interface{}{"parent_path": parentPath})
+}
 
-	result := m.db.ORM.Model(menu).Where("id = ?", id).Update("parent_path", parentPath)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+// menuPositionBatchSize keeps the CASE expressions of UpdatePositions below the placeholder limits
+const menuPositionBatchSize = 500
+
+// UpdatePositions sets parent_id, parent_path and sequence of the menus
+// with one CASE update per batch instead of one update per menu
+func (m MenuRepository) UpdatePositions(menus models.Menus) error {
+	for start := 0; start < len(menus); start += menuPositionBatchSize {
+		end := start + menuPositionBatchSize
+		if end > len(menus) {
+			end = len(menus)
+		}
+
+		batch := menus[start:end]
+		ids := batch.ToIDs()
+
+		// sequences are written as literals, PostgreSQL would type a THEN placeholder as text
+		var parentIDArgs, parentPathArgs, sequenceArgs []interface{}
+		sequenceWhen := "CASE id"
+		for _, menu := range batch {
+			parentIDArgs = append(parentIDArgs, menu.ID, menu.ParentID)
+			parentPathArgs = append(parentPathArgs, menu.ID, menu.ParentPath)
+			sequenceArgs = append(sequenceArgs, menu.ID)
+			sequenceWhen += " WHEN ? THEN " + strconv.Itoa(menu.Sequence)
+		}
+
+		when := "CASE id" + strings.Repeat(" WHEN ? THEN ?", len(batch)) + " END"
+		result := m.Model().Where("id IN (?)", ids).Updates(map[string]interface{}{
+			"parent_id":   gorm.Expr(when, parentIDArgs...),
+			"parent_path": gorm.Expr(when, parentPathArgs...),
+			"sequence":    gorm.Expr(sequenceWhen+" END", sequenceArgs...),
+			"version":     gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+		}
 	}
 
 	return nil
@@ -139,7 +123,6 @@ func (m MenuRepository) UpdateParentPath(id string, parentPath string) error {
 // NewMenuRepository creates a new menu repository
 func NewMenuRepository(db lib.Database, logger lib.Logger) MenuRepository {
 	return MenuRepository{
-		db:     db,
-		logger: logger,
+		Repository: NewRepository[models.Menu](db, logger),
 	}
 }
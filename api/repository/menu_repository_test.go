@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/internal/testutil"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"testing"
+)
+
+func newTestMenuRepository(t *testing.T) MenuRepository {
+	t.Helper()
+
+	logger := testutil.NewLogger()
+	db := testutil.NewDatabase(t, logger)
+
+	return NewMenuRepository(db, logger)
+}
+
+// createTestMenus creates the menus "menu 1" to "menu n" in this order, with sequence i*10
+func createTestMenus(t *testing.T, r MenuRepository, n int) models.Menus {
+	t.Helper()
+
+	menus := make(models.Menus, n)
+	for i := range menus {
+		menus[i] = &models.Menu{
+			ID:        fmt.Sprintf("menu-%d", i+1),
+			Name:      fmt.Sprintf("menu %d", i+1),
+			Sequence:  (i + 1) * 10,
+			Hidden:    -1,
+			Status:    1,
+			Remark:    fmt.Sprintf("Remark of menu %d", i+1),
+			CreatedBy: "test",
+		}
+
+		if err := r.Create(menus[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return menus
+}
+
+func TestMenuRepositoryQueryLike(t *testing.T) {
+	r := newTestMenuRepository(t)
+	createTestMenus(t, r, 3)
+
+	tests := []struct {
+		value string
+		ids   []string
+	}{
+		{value: "MENU 2", ids: []string{"menu-2"}},
+		{value: "remark of", ids: []string{"menu-3", "menu-2", "menu-1"}},
+		{value: "nothing", ids: nil},
+	}
+
+	for _, test := range tests {
+		qr, err := r.Query(&models.MenuQueryParam{QueryValue: test.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ids := qr.List.ToIDs(); fmt.Sprint(ids) != fmt.Sprint(test.ids) {
+			t.Errorf("query %q: menus %v, want %v", test.value, ids, test.ids)
+		}
+	}
+}
+
+func TestMenuRepositoryUpdatePositions(t *testing.T) {
+	r := newTestMenuRepository(t)
+	menus := createTestMenus(t, r, 3)
+
+	// menu 3 moves under menu 1, menu 2 moves to the top
+	menus[1].Sequence = 5
+	menus[2].ParentID, menus[2].ParentPath, menus[2].Sequence = "menu-1", "menu-1", 1
+	if err := r.UpdatePositions(menus[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id         string
+		parentID   string
+		parentPath string
+		sequence   int
+		version    int
+	}{
+		{id: "menu-1", sequence: 10, version: 1},
+		{id: "menu-2", sequence: 5, version: 2},
+		{id: "menu-3", parentID: "menu-1", parentPath: "menu-1", sequence: 1, version: 2},
+	}
+
+	for _, test := range tests {
+		menu, err := r.Get(test.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if menu.ParentID != test.parentID || menu.ParentPath != test.parentPath ||
+			menu.Sequence != test.sequence || menu.Version != test.version {
+			t.Errorf("menu %s: parent %q, path %q, sequence %d, version %d, want %q, %q, %d, %d",
+				test.id, menu.ParentID, menu.ParentPath, menu.Sequence, menu.Version,
+				test.parentID, test.parentPath, test.sequence, test.version)
+		}
+	}
+}
+
+func TestMenuRepositoryQueryTotal(t *testing.T) {
+	r := newTestMenuRepository(t)
+	createTestMenus(t, r, 3)
+
+	tests := []struct {
+		param *models.MenuQueryParam
+		json  string
+	}{
+		{
+			param: &models.MenuQueryParam{Name: "nothing"},
+			json:  `{"total":0,"current":0,"pageSize":15}`,
+		},
+		{
+			param: &models.MenuQueryParam{PaginationParam: dto.PaginationParam{Current: 2, PageSize: 2}},
+			json:  `{"total":3,"current":2,"pageSize":2}`,
+		},
+		{
+			param: &models.MenuQueryParam{PaginationParam: dto.PaginationParam{Current: 1, PageSize: 2, SkipTotal: true}},
+			json:  `{"current":1,"pageSize":2}`,
+		},
+	}
+
+	for _, test := range tests {
+		qr, err := r.Query(test.param)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := json.Marshal(qr.Pagination)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(data) != test.json {
+			t.Errorf("pagination %s, want %s", data, test.json)
+		}
+	}
+}
+
+func TestMenuRepositoryQueryCursor(t *testing.T) {
+	r := newTestMenuRepository(t)
+	createTestMenus(t, r, 5)
+
+	// sequence of menu 4 equals menu 3, the pages keep record_id as the tie breaker
+	if err := r.UpdateFields("menu-4", map[string]interface{}{"sequence": 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(cursor string) (*models.MenuQueryResult, []string) {
+		t.Helper()
+
+		qr, err := r.Query(&models.MenuQueryParam{
+			PaginationParam: dto.PaginationParam{PageSize: 2, Mode: dto.PaginationCursor, Cursor: cursor},
+			OrderParam:      dto.OrderParam{Sort: "sequence"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return qr, qr.List.ToIDs()
+	}
+
+	first, ids := query("")
+	if fmt.Sprint(ids) != "[menu-1 menu-2]" || first.Pagination.NextCursor == "" || first.Pagination.PrevCursor != "" {
+		t.Fatalf("first page %v, next %q, prev %q", ids, first.Pagination.NextCursor, first.Pagination.PrevCursor)
+	}
+
+	if !first.Pagination.SkipTotal {
+		t.Error("cursor page counted the rows")
+	}
+
+	second, ids := query(first.Pagination.NextCursor)
+	if fmt.Sprint(ids) != "[menu-4 menu-3]" || second.Pagination.NextCursor == "" || second.Pagination.PrevCursor == "" {
+		t.Fatalf("second page %v, next %q, prev %q", ids, second.Pagination.NextCursor, second.Pagination.PrevCursor)
+	}
+
+	last, ids := query(second.Pagination.NextCursor)
+	if fmt.Sprint(ids) != "[menu-5]" || last.Pagination.NextCursor != "" {
+		t.Fatalf("last page %v, next %q", ids, last.Pagination.NextCursor)
+	}
+
+	back, ids := query(second.Pagination.PrevCursor)
+	if fmt.Sprint(ids) != "[menu-1 menu-2]" || back.Pagination.PrevCursor != "" {
+		t.Errorf("previous page %v, prev %q", ids, back.Pagination.PrevCursor)
+	}
+
+	if _, err := r.Query(&models.MenuQueryParam{
+		PaginationParam: dto.PaginationParam{Cursor: second.Pagination.NextCursor},
+		OrderParam:      dto.OrderParam{Sort: "-sequence"},
+	}); err == nil {
+		t.Error("cursor of another sort was accepted")
+	}
+}
+
+func TestMenuRepositoryUpdateVersioned(t *testing.T) {
+	r := newTestMenuRepository(t)
+	menu := createTestMenus(t, r, 1)[0]
+
+	// a stale version conflicts and the menu keeps it
+	menu.Name, menu.Version = "stale", 5
+	if err := r.UpdateVersioned(menu.ID, menu); !errors.Is(err, errors.DatabaseVersionConflict) {
+		t.Fatalf("update of a stale version: %v, want %v", err, errors.DatabaseVersionConflict)
+	} else if menu.Version != 5 {
+		t.Errorf("version %d after the conflict, want 5", menu.Version)
+	}
+
+	menu.Name, menu.Version = "updated", 1
+	if err := r.UpdateVersioned(menu.ID, menu); err != nil {
+		t.Fatal(err)
+	} else if menu.Version != 2 {
+		t.Errorf("version %d after the update, want 2", menu.Version)
+	}
+
+	stored, err := r.Get(menu.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if stored.Name != "updated" || stored.Version != 2 {
+		t.Errorf("stored menu %q at version %d, want %q at 2", stored.Name, stored.Version, "updated")
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"time"
+)
+
+// OutboxRepository stores the domain events until the relay has published them
+type OutboxRepository struct {
+	db     lib.Database
+	logger lib.Logger
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r OutboxRepository) WithContext(ctx context.Context) OutboxRepository {
+	r.db.ORM = r.db.WithContext(ctx)
+	return r
+}
+
+// Create writes the event, due right away
+func (r OutboxRepository) Create(event *models.OutboxEvent) error {
+	if event.NextAttemptAt == nil {
+		now := r.db.ORM.NowFunc()
+		event.NextAttemptAt = &now
+	}
+
+	result := r.db.ORM.Model(event).Create(event)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// QueryPending lists the first pending events due at now in the order they were written, read on
+// the primary so that the relay never misses an event a replica has not received yet
+func (r OutboxRepository) QueryPending(now time.Time, limit int) (models.OutboxEvents, error) {
+	var list models.OutboxEvents
+
+	result := r.db.Primary().Model(&models.OutboxEvent{}).
+		Where("published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&list)
+	if result.Error != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return list, nil
+}
+
+// QueryWaiting returns the first pending event not due at now of every aggregate, by aggregate key.
+// The later events of these aggregates wait for it.
+func (r OutboxRepository) QueryWaiting(now time.Time) (map[string]uint64, error) {
+	var rows []struct {
+		AggregateType string
+		AggregateID   string
+		ID            uint64
+	}
+
+	result := r.db.Primary().Model(&models.OutboxEvent{}).
+		Select("aggregate_type, aggregate_id, MIN(id) AS id").
+		Where("published_at IS NULL AND dead_at IS NULL AND next_attempt_at > ?", now).
+		Group("aggregate_type, aggregate_id").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	waiting := make(map[string]uint64, len(rows))
+	for _, row := range rows {
+		event := &models.OutboxEvent{AggregateType: row.AggregateType, AggregateID: row.AggregateID}
+		waiting[event.AggregateKey()] = row.ID
+	}
+
+	return waiting, nil
+}
+
+// MarkPublished writes the event published by every sink at event.PublishedAt
+func (r OutboxRepository) MarkPublished(event *models.OutboxEvent) error {
+	return r.updateAttempt(event, "published_at", "attempts", "last_error", "next_attempt_at", "published_sinks")
+}
+
+// MarkFailed writes a failed attempt of the event, with the sinks that published it,
+// its next attempt or its death
+func (r OutboxRepository) MarkFailed(event *models.OutboxEvent) error {
+	return r.updateAttempt(event, "attempts", "last_error", "next_attempt_at", "dead_at", "published_sinks")
+}
+
+func (r OutboxRepository) updateAttempt(event *models.OutboxEvent, columns ...string) error {
+	result := r.db.ORM.Model(event).Select(columns).Updates(event)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// DeletePublished removes the events published before the time
+func (r OutboxRepository) DeletePublished(before time.Time) (int64, error) {
+	result := r.db.ORM.Where("published_at < ?", before).Delete(&models.OutboxEvent{})
+	if result.Error != nil {
+		return 0, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return result.RowsAffected, nil
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db lib.Database, logger lib.Logger) OutboxRepository {
+	return OutboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
@@ -1,9 +1,15 @@
 package repository
 
 import (
-	"errors"
+	"encoding/json"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models/dto"
+	"reflect"
+	"strings"
+	"sync"
 )
 
 func QueryCount(db *gorm.DB) (n int64, err error) {
@@ -23,6 +29,12 @@ func QueryPage(db *gorm.DB, pp dto.PaginationParam, out interface{}) (n int64, e
 		return
 	}
 
+	err = queryOffset(db, pp, out)
+
+	return
+}
+
+func queryOffset(db *gorm.DB, pp dto.PaginationParam, out interface{}) error {
 	current, pageSize := pp.GetCurrent(), pp.GetPageSize()
 	if current > 0 && pageSize > 0 {
 		db = db.Offset((current - 1) * pageSize).Limit(pageSize)
@@ -30,26 +42,195 @@ func QueryPage(db *gorm.DB, pp dto.PaginationParam, out interface{}) (n int64, e
 		db = db.Limit(pageSize)
 	}
 
-	err = db.Find(out).Error
-
-	return
+	return db.Find(out).Error
 }
 
-func QueryPagination(db *gorm.DB, pp dto.PaginationParam, out interface{}) (*dto.Pagination, error) {
-	pagination := new(dto.Pagination)
+// QueryPagination reads a page of db sorted by order into out, by offset or by cursor.
+// Database errors are wrapped as DatabaseInternalError.
+func QueryPagination(db *gorm.DB, pp dto.PaginationParam, order string, out interface{}) (*dto.Pagination, error) {
+	if pp.IsCursor() {
+		return QueryCursor(db, pp, order, out)
+	}
+
+	db = db.Order(order)
+	pagination := &dto.Pagination{Current: pp.GetCurrent(), PageSize: pp.GetPageSize()}
+
+	if pp.SkipTotal {
+		if err := queryOffset(db, pp, out); err != nil {
+			return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+		}
+		pagination.SkipTotal = true
+
+		return pagination, nil
+	}
 
 	total, err := QueryPage(db, pp, out)
 	if err != nil {
-		return pagination, err
+		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
 	}
-
-	pagination.Current = pp.GetCurrent()
-	pagination.PageSize = pp.GetPageSize()
 	pagination.Total = total
 
 	return pagination, nil
 }
 
+type orderKey struct {
+	column string
+	desc   bool
+}
+
+// parseOrderKeys splits an ORDER BY clause made by dto.ParseOrder, record_id is
+// appended when missing so that the keys identify a row
+func parseOrderKeys(order string) []orderKey {
+	var keys []orderKey
+	for _, item := range strings.Split(order, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+
+		keys = append(keys, orderKey{column: fields[0], desc: len(fields) > 1 && strings.EqualFold(fields[1], "DESC")})
+		if fields[0] == "record_id" {
+			return keys
+		}
+	}
+
+	return append(keys, orderKey{column: "record_id", desc: true})
+}
+
+// QueryCursor reads the page of out after, or before, the row of the cursor of pp.
+// Rows are compared on their sort keys instead of being skipped by OFFSET, and are not
+// counted, so the cost of a page does not grow with its position. The sort columns must
+// not be null.
+func QueryCursor(db *gorm.DB, pp dto.PaginationParam, order string, out interface{}) (*dto.Pagination, error) {
+	pagination := &dto.Pagination{PageSize: pp.GetPageSize(), SkipTotal: true}
+
+	outSchema, err := schema.Parse(out, cursorSchemas, db.NamingStrategy)
+	if err != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+	}
+
+	keys := parseOrderKeys(order)
+	fields := make([]*schema.Field, len(keys))
+	for i, key := range keys {
+		if fields[i] = outSchema.LookUpField(key.column); fields[i] == nil {
+			return nil, errors.Wrapf(errors.DatabaseInternalError, "cursor sort column %s not found", key.column)
+		}
+	}
+
+	var cursor *dto.Cursor
+	if pp.Cursor != "" {
+		if cursor, err = dto.ParseCursor(pp.Cursor); err != nil {
+			return nil, err
+		} else if cursor.Order != order || len(cursor.Values) != len(keys) {
+			return nil, errors.Wrap(errors.CursorInvalid, "cursor does not match the sort")
+		}
+
+		values := make([]interface{}, len(keys))
+		for i, field := range fields {
+			value := reflect.New(field.FieldType)
+			if err := json.Unmarshal(cursor.Values[i], value.Interface()); err != nil {
+				return nil, errors.Wrap(errors.CursorInvalid, err.Error())
+			}
+			values[i] = value.Elem().Interface()
+		}
+
+		db = queryKeyset(db, keys, values, cursor.Prev)
+	}
+
+	// a previous page is read backwards from the cursor and reversed afterwards
+	backward := cursor != nil && cursor.Prev
+	for _, key := range keys {
+		direction := "ASC"
+		if key.desc != backward {
+			direction = "DESC"
+		}
+		db = db.Order(key.column + " " + direction)
+	}
+
+	// one more row than the page tells whether there is a page after it
+	if err := db.Limit(pagination.PageSize + 1).Find(out).Error; err != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+	}
+
+	list := reflect.ValueOf(out).Elem()
+	hasMore := list.Len() > pagination.PageSize
+	if hasMore {
+		list.Set(list.Slice(0, pagination.PageSize))
+	}
+
+	if backward {
+		swap := reflect.Swapper(list.Interface())
+		for i, j := 0, list.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if list.Len() == 0 {
+		return pagination, nil
+	}
+
+	if hasMore || backward {
+		if pagination.NextCursor, err = encodeCursor(db, order, fields, list.Index(list.Len()-1), false); err != nil {
+			return nil, err
+		}
+	}
+
+	if (backward && hasMore) || (!backward && cursor != nil) {
+		if pagination.PrevCursor, err = encodeCursor(db, order, fields, list.Index(0), true); err != nil {
+			return nil, err
+		}
+	}
+
+	return pagination, nil
+}
+
+var cursorSchemas = new(sync.Map)
+
+// queryKeyset keeps the rows after the values in the order of keys, or before when prev:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for the descending keys
+func queryKeyset(db *gorm.DB, keys []orderKey, values []interface{}, prev bool) *gorm.DB {
+	conditions := make([]string, len(keys))
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].column+" = ?")
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if key.desc != prev {
+			operator = "<"
+		}
+		parts = append(parts, key.column+" "+operator+" ?")
+		args = append(args, values[i])
+
+		conditions[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+
+	return db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+func encodeCursor(db *gorm.DB, order string, fields []*schema.Field, row reflect.Value, prev bool) (string, error) {
+	cursor := &dto.Cursor{Order: order, Values: make([]json.RawMessage, len(fields)), Prev: prev}
+	for i, field := range fields {
+		value, _ := field.ValueOf(db.Statement.Context, row)
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", errors.Wrap(errors.DatabaseInternalError, err.Error())
+		}
+		cursor.Values[i] = data
+	}
+
+	value, err := cursor.Encode()
+	if err != nil {
+		return "", errors.Wrap(errors.DatabaseInternalError, err.Error())
+	}
+
+	return value, nil
+}
+
 func QueryOne(db *gorm.DB, out interface{}) (bool, error) {
 	result := db.First(out)
 	if err := result.Error; err != nil {
@@ -62,3 +243,62 @@ func QueryOne(db *gorm.DB, out interface{}) (bool, error) {
 
 	return true, nil
 }
+
+// QueryLike matches value against any of the columns ignoring case,
+// PostgreSQL needs ILIKE where MySQL and SQLite compare case insensitively with LIKE
+func QueryLike(db *gorm.DB, value string, columns ...string) *gorm.DB {
+	operator := "LIKE"
+	if db.Dialector.Name() == lib.DatabaseEnginePostgres {
+		operator = "ILIKE"
+	}
+
+	conditions := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " " + operator + " ?"
+		args[i] = value
+	}
+
+	return db.Where(strings.Join(conditions, " OR "), args...)
+}
+
+// QueryFilters scope of the filters returned by the ParseFilters of a query param,
+// their fields were checked against the filterable columns of the model
+func QueryFilters(filters []*dto.Filter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, filter := range filters {
+			column, args := filter.Field, filter.Args
+
+			switch filter.Operator {
+			case dto.FilterEq:
+				db = db.Where(column+" = ?", args[0])
+			case dto.FilterNe:
+				db = db.Where(column+" <> ?", args[0])
+			case dto.FilterGt:
+				db = db.Where(column+" > ?", args[0])
+			case dto.FilterGte:
+				db = db.Where(column+" >= ?", args[0])
+			case dto.FilterLt:
+				db = db.Where(column+" < ?", args[0])
+			case dto.FilterLte:
+				db = db.Where(column+" <= ?", args[0])
+			case dto.FilterIn:
+				db = db.Where(column+" IN (?)", args)
+			case dto.FilterNin:
+				db = db.Where(column+" NOT IN (?)", args)
+			case dto.FilterPrefix:
+				db = QueryLike(db, args[0].(string)+"%", column)
+			case dto.FilterContains:
+				db = QueryLike(db, "%"+args[0].(string)+"%", column)
+			case dto.FilterNull:
+				if args[0].(bool) {
+					db = db.Where(column + " IS NULL")
+				} else {
+					db = db.Where(column + " IS NOT NULL")
+				}
+			}
+		}
+
+		return db
+	}
+}
@@ -11,4 +11,11 @@ var Module = fx.Options(
 	fx.Provide(NewMenuRepository),
 	fx.Provide(NewMenuActionRepository),
 	fx.Provide(NewMenuActionResourceRepository),
+	fx.Provide(NewMenuI18nRepository),
+	fx.Provide(NewTrashRepository),
+	fx.Provide(NewOutboxRepository),
+	fx.Provide(NewWebhookRepository),
+	fx.Provide(NewWebhookDeliveryRepository),
+	fx.Provide(NewAuditLogRepository),
+	fx.Provide(NewLoginLogRepository),
 )
@@ -1,59 +1,50 @@
 package repository
 
 import (
+	"context"
 	"gorm.io/gorm"
-	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
 )
 
 // RoleRepository database structure
 type RoleRepository struct {
-	db     lib.Database
-	logger lib.Logger
+	Repository[models.Role]
 }
 
-// WithTrx enables repository with transaction
-func (r RoleRepository) WithTrx(trxHandle *gorm.DB) RoleRepository {
-	if trxHandle == nil {
-		r.logger.Zap.Error("Transaction Database not found in echo context.")
-		return r
-	}
-
-	r.db.ORM = trxHandle
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r RoleRepository) WithContext(ctx context.Context) RoleRepository {
+	r.Repository = r.Repository.WithContext(ctx)
 	return r
 }
 
 func (r RoleRepository) Query(param *models.RoleQueryParam) (*models.RoleQueryResult, error) {
-	db := r.db.ORM.Model(&models.Role{})
-
-	if v := param.IDs; len(v) > 0 {
-		db = db.Where("id IN (?)", v)
-	}
-
-	if v := param.Name; v != "" {
-		db = db.Where("name = ?", v)
-	}
-
-	if v := param.UserID; v != "" {
-		subQuery := r.db.ORM.Model(&models.UserRole{}).
-			Where("user_id = ?", v).
-			Select("role_id")
-
-		db = db.Where("id IN (?)", subQuery)
-	}
-
-	if v := param.QueryValue; v != "" {
-		v = "%" + v + "%"
-		db = db.Where("name LIKE ? OR remark LIKE ?", v, v)
-	}
-
-	db = db.Order(param.OrderParam.ParseOrder())
-
-	list := make(models.Roles, 0)
-	pagination, err := QueryPagination(db, param.PaginationParam, &list)
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.IDs; len(v) > 0 {
+			db = db.Where("id IN (?)", v)
+		}
+
+		if v := param.Name; v != "" {
+			db = db.Where("name = ?", v)
+		}
+
+		if v := param.UserID; v != "" {
+			subQuery := r.db.ORM.Model(&models.UserRole{}).
+				Where("user_id = ?", v).
+				Select("role_id")
+
+			db = db.Where("id IN (?)", subQuery)
+		}
+
+		if v := param.QueryValue; v != "" {
+			v = "%" + v + "%"
+			db = QueryLike(db, v, "name", "remark")
+		}
+
+		return db
+	})
 	if err != nil {
-		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+		return nil, err
 	}
 
 	qr := &models.RoleQueryResult{
@@ -64,62 +55,14 @@ func (r RoleRepository) Query(param *models.RoleQueryParam) (*models.RoleQueryRe
 	return qr, nil
 }
 
-func (r RoleRepository) Get(id string) (*models.Role, error) {
-	role := new(models.Role)
-
-	if ok, err := QueryOne(r.db.ORM.Model(role).Where("id = ?", id), role); err != nil {
-		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
-	} else if !ok {
-		return nil, errors.DatabaseRecordNotFound
-	}
-
-	return role, nil
-}
-
-func (r RoleRepository) Create(role *models.Role) error {
-	result := r.db.ORM.Model(role).Create(role)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
+// Update writes the role only if its version is still role.Version and increments the version
 func (r RoleRepository) Update(id string, role *models.Role) error {
-	result := r.db.ORM.Model(role).Where("id = ?", id).Updates(role)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
-func (r RoleRepository) Delete(id string) error {
-	role := new(models.Role)
-
-	result := r.db.ORM.Model(role).Where("id = ?", id).Delete(role)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
-func (r RoleRepository) UpdateStatus(id string, status int) error {
-	role := new(models.Role)
-
-	result := r.db.ORM.Model(role).Where("id = ?", id).Update("status", status)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
+	return r.UpdateVersioned(id, role)
 }
 
 // NewRoleRepository creates a new role repository
 func NewRoleRepository(db lib.Database, logger lib.Logger) RoleRepository {
 	return RoleRepository{
-		db:     db,
-		logger: logger,
+		Repository: NewRepository[models.Role](db, logger),
 	}
 }
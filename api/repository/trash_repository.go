@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models/dto"
+	"time"
+)
+
+// TrashRepository reads and restores the soft deleted rows of any model
+type TrashRepository struct {
+	db     lib.Database
+	logger lib.Logger
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r TrashRepository) WithContext(ctx context.Context) TrashRepository {
+	r.db.ORM = r.db.WithContext(ctx)
+	return r
+}
+
+// Query lists the soft deleted rows of model into out, the most recently deleted first.
+// A non empty queryValue filters on the name column.
+func (r TrashRepository) Query(model interface{}, nameColumn, queryValue string, pp dto.PaginationParam, out interface{}) (*dto.Pagination, error) {
+	db := r.db.ORM.Unscoped().Model(model).Where("deleted_at IS NOT NULL")
+
+	if queryValue != "" {
+		db = QueryLike(db, "%"+queryValue+"%", nameColumn)
+	}
+
+	pagination, err := QueryPagination(db, pp, "deleted_at DESC", out)
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination, nil
+}
+
+// Get reads the soft deleted row of model by id into out
+func (r TrashRepository) Get(model interface{}, id string, out interface{}) error {
+	db := r.db.ORM.Unscoped().Model(model).Where("id = ? AND deleted_at IS NOT NULL", id)
+
+	if ok, err := QueryOne(db, out); errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !ok) {
+		return errors.DatabaseRecordNotFound
+	} else if err != nil {
+		return errors.Wrap(errors.DatabaseInternalError, err.Error())
+	}
+
+	return nil
+}
+
+// QueryDeleted reads the soft deleted rows of model whose column is in values,
+// only those deleted at deletedAt unless it is nil
+func (r TrashRepository) QueryDeleted(model interface{}, column string, values interface{}, deletedAt *time.Time, out interface{}) error {
+	db := r.db.ORM.Unscoped().Model(model).Where(column+" IN (?) AND deleted_at IS NOT NULL", values)
+
+	if deletedAt != nil {
+		db = db.Where("deleted_at = ?", *deletedAt)
+	}
+
+	if result := db.Find(out); result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// Restore clears deleted_at of the rows of model whose column is in values and that were deleted at deletedAt
+func (r TrashRepository) Restore(model interface{}, column string, values interface{}, deletedAt time.Time) error {
+	result := r.db.ORM.Unscoped().Model(model).
+		Where(column+" IN (?) AND deleted_at = ?", values, deletedAt).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// Purge permanently deletes the soft deleted rows of model whose column is in values
+func (r TrashRepository) Purge(model interface{}, column string, values interface{}) error {
+	result := r.db.ORM.Unscoped().
+		Where(column+" IN (?) AND deleted_at IS NOT NULL", values).
+		Delete(model)
+	if result.Error != nil {
+		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return nil
+}
+
+// NewTrashRepository creates a new trash repository
+func NewTrashRepository(db lib.Database, logger lib.Logger) TrashRepository {
+	return TrashRepository{
+		db:     db,
+		logger: logger,
+	}
+}
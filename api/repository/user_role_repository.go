@@ -1,112 +1,60 @@
 package repository
 
 import (
+	"context"
 	"gorm.io/gorm"
-	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
 )
 
 // UserRoleRepository database structure
 type UserRoleRepository struct {
-	db     lib.Database
-	logger lib.Logger
+	Repository[models.UserRole]
 }
 
-// WithTrx enables repository with transaction
-func (r UserRoleRepository) WithTrx(trxHandle *gorm.DB) UserRoleRepository {
-	if trxHandle == nil {
-		r.logger.Zap.Error("Transaction Database not found in echo context.")
-		return r
-	}
-
-	r.db.ORM = trxHandle
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r UserRoleRepository) WithContext(ctx context.Context) UserRoleRepository {
+	r.Repository = r.Repository.WithContext(ctx)
 	return r
 }
 
 func (r UserRoleRepository) Query(param *models.UserRoleQueryParam) (*models.UserRoleQueryResult, error) {
-	db := r.db.ORM.Model(models.UserRole{})
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.UserID; v != "" {
+			db = db.Where("user_id = ?", v)
+		}
 
-	if v := param.UserID; v != "" {
-		db = db.Where("user_id = ?", v)
-	}
+		if v := param.UserIDs; len(v) > 0 {
+			db = db.Where("user_id IN (?)", v)
+		}
 
-	if v := param.UserIDs; len(v) > 0 {
-		db = db.Where("user_id IN (?)", v)
+		return db
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	db = db.Order(param.OrderParam.ParseOrder())
-
-	list := make(models.UserRoles, 0)
-	pagination, err := QueryPagination(db, param.PaginationParam, &list)
-	if err != nil {
-		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
+	// user roles are listed by value
+	userRoles := make(models.UserRoles, len(list))
+	for i, item := range list {
+		userRoles[i] = *item
 	}
 
 	qr := &models.UserRoleQueryResult{
 		Pagination: pagination,
-		List:       list,
+		List:       userRoles,
 	}
 
 	return qr, nil
 }
 
-func (r UserRoleRepository) Get(id string) (*models.UserRole, error) {
-	userRole := new(models.UserRole)
-
-	if ok, err := QueryOne(r.db.ORM.Model(userRole).Where("id = ?", id), userRole); err != nil {
-		return nil, errors.Wrap(errors.DatabaseInternalError, err.Error())
-	} else if !ok {
-		return nil, errors.DatabaseRecordNotFound
-	}
-
-	return userRole, nil
-}
-
-func (r UserRoleRepository) Create(userRole *models.UserRole) error {
-	result := r.db.ORM.Model(userRole).Create(userRole)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
-func (r UserRoleRepository) Update(id string, userRole *models.UserRole) error {
-	result := r.db.ORM.Model(userRole).Where("id = ?", id).Updates(userRole)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
-func (r UserRoleRepository) Delete(id string) error {
-	userRole := new(models.UserRole)
-
-	result := r.db.ORM.Model(userRole).Where("id = ?", id).Delete(userRole)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
-}
-
 func (r UserRoleRepository) DeleteByUserID(userID string) error {
-	userRole := new(models.UserRole)
-
-	result := r.db.ORM.Model(userRole).Where("user_id = ?", userID).Delete(userRole)
-	if result.Error != nil {
-		return errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
-	}
-
-	return nil
+	return r.DeleteWhere("user_id = ?", userID)
 }
 
 // NewUserRoleRepository creates a new user role repository
 func NewUserRoleRepository(db lib.Database, logger lib.Logger) UserRoleRepository {
 	return UserRoleRepository{
-		db:     db,
-		logger: logger,
+		Repository: NewRepository[models.UserRole](db, logger),
 	}
 }
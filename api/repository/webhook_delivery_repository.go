@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"time"
+)
+
+// WebhookDeliveryRepository database structure
+type WebhookDeliveryRepository struct {
+	Repository[models.WebhookDelivery]
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r WebhookDeliveryRepository) WithContext(ctx context.Context) WebhookDeliveryRepository {
+	r.Repository = r.Repository.WithContext(ctx)
+	return r
+}
+
+func (r WebhookDeliveryRepository) Query(param *models.WebhookDeliveryQueryParam) (*models.WebhookDeliveryQueryResult, error) {
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.WebhookID; v != "" {
+			db = db.Where("webhook_id = ?", v)
+		}
+
+		if v := param.EventType; v != "" {
+			db = db.Where("event_type = ?", v)
+		}
+
+		if v := param.Status; v != "" {
+			db = db.Where("status = ?", v)
+		}
+
+		return db
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	qr := &models.WebhookDeliveryQueryResult{
+		Pagination: pagination,
+		List:       list,
+	}
+
+	return qr, nil
+}
+
+// Exists reports whether the event already has a delivery to the webhook
+func (r WebhookDeliveryRepository) Exists(webhookID, eventID string) (bool, error) {
+	var count int64
+
+	result := r.db.Primary().Model(&models.WebhookDelivery{}).
+		Where("webhook_id = ? AND event_id = ?", webhookID, eventID).
+		Count(&count)
+	if result.Error != nil {
+		return false, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return count > 0, nil
+}
+
+// QueryDue lists the pending deliveries whose next attempt is due at now, the earliest first
+func (r WebhookDeliveryRepository) QueryDue(now time.Time, limit int) (models.WebhookDeliveries, error) {
+	var list models.WebhookDeliveries
+
+	result := r.db.Primary().Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at ASC, record_id ASC").
+		Limit(limit).
+		Find(&list)
+	if result.Error != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return list, nil
+}
+
+// Claim moves the next attempt of the delivery to until when it is pending and due at now,
+// so that it is sent by one sender only. It reports whether the delivery was claimed.
+func (r WebhookDeliveryRepository) Claim(id string, now, until time.Time) (bool, error) {
+	result := r.db.Primary().Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, models.WebhookDeliveryPending, now).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateAttempt writes the status and the outcome of the last attempt of the delivery
+func (r WebhookDeliveryRepository) UpdateAttempt(delivery *models.WebhookDelivery) error {
+	return r.UpdateColumns(delivery.ID, delivery, "status", "attempts", "next_attempt_at", "last_attempt_at",
+		"response_code", "response_body", "last_error", "delivered_at")
+}
+
+func (r WebhookDeliveryRepository) DeleteByWebhookID(webhookID string) error {
+	return r.DeleteWhere("webhook_id = ?", webhookID)
+}
+
+// NewWebhookDeliveryRepository creates a new webhook delivery repository
+func NewWebhookDeliveryRepository(db lib.Database, logger lib.Logger) WebhookDeliveryRepository {
+	return WebhookDeliveryRepository{
+		Repository: NewRepository[models.WebhookDelivery](db, logger),
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+)
+
+// WebhookRepository database structure
+type WebhookRepository struct {
+	Repository[models.Webhook]
+}
+
+// WithContext binds the repository to ctx, its queries join the transaction carried by ctx
+func (r WebhookRepository) WithContext(ctx context.Context) WebhookRepository {
+	r.Repository = r.Repository.WithContext(ctx)
+	return r
+}
+
+func (r WebhookRepository) Query(param *models.WebhookQueryParam) (*models.WebhookQueryResult, error) {
+	list, pagination, err := r.Repository.Query(param, func(db *gorm.DB) *gorm.DB {
+		if v := param.Name; v != "" {
+			db = db.Where("name = ?", v)
+		}
+
+		if v := param.Status; v != 0 {
+			db = db.Where("status = ?", v)
+		}
+
+		if v := param.QueryValue; v != "" {
+			v = "%" + v + "%"
+			db = QueryLike(db, v, "name", "url", "remark")
+		}
+
+		return db
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	qr := &models.WebhookQueryResult{
+		Pagination: pagination,
+		List:       list,
+	}
+
+	return qr, nil
+}
+
+// QueryEnabled lists every enabled webhook, read on the primary
+func (r WebhookRepository) QueryEnabled() (models.Webhooks, error) {
+	var list models.Webhooks
+
+	result := r.db.Primary().Model(&models.Webhook{}).Where("status = ?", 1).Find(&list)
+	if result.Error != nil {
+		return nil, errors.Wrap(errors.DatabaseInternalError, result.Error.Error())
+	}
+
+	return list, nil
+}
+
+// Update writes the webhook only if its version is still webhook.Version and increments the version
+func (r WebhookRepository) Update(id string, webhook *models.Webhook) error {
+	return r.UpdateVersioned(id, webhook)
+}
+
+// UpdateSecret replaces the secret of the webhook and increments the version
+func (r WebhookRepository) UpdateSecret(id, secret string) error {
+	return r.UpdateFields(id, map[string]interface{}{"secret": secret})
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db lib.Database, logger lib.Logger) WebhookRepository {
+	return WebhookRepository{
+		Repository: NewRepository[models.Webhook](db, logger),
+	}
+}
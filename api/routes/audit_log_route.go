@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/lib"
+)
+
+type AuditLogRoutes struct {
+	logger             lib.Logger
+	handler            lib.HttpHandler
+	auditLogController controllers.AuditLogController
+}
+
+// Setup audit log routes
+func (r AuditLogRoutes) Setup() {
+	r.logger.Zap.Info("Setting up audit log routes")
+
+	api := r.handler.RouterV1.Group("/audit-logs")
+	{
+		api.GET("", r.auditLogController.Query)
+		api.GET("/export", r.auditLogController.Export)
+	}
+}
+
+// NewAuditLogRoutes creates new audit log routes
+func NewAuditLogRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	auditLogController controllers.AuditLogController,
+) AuditLogRoutes {
+	return AuditLogRoutes{
+		handler:            handler,
+		logger:             logger,
+		auditLogController: auditLogController,
+	}
+}
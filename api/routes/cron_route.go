@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/lib"
+)
+
+type CronRoutes struct {
+	logger         lib.Logger
+	handler        lib.HttpHandler
+	cronController controllers.CronController
+}
+
+// Setup cron routes
+func (r CronRoutes) Setup() {
+	r.logger.Zap.Info("Setting up cron routes")
+
+	api := r.handler.RouterV1.Group("/cron")
+	{
+		api.GET("/jobs", r.cronController.Query)
+	}
+}
+
+// NewCronRoutes creates new cron routes
+func NewCronRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	cronController controllers.CronController,
+) CronRoutes {
+	return CronRoutes{
+		handler:        handler,
+		logger:         logger,
+		cronController: cronController,
+	}
+}
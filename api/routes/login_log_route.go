@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/lib"
+)
+
+type LoginLogRoutes struct {
+	logger             lib.Logger
+	handler            lib.HttpHandler
+	loginLogController controllers.LoginLogController
+}
+
+// Setup login log routes
+func (r LoginLogRoutes) Setup() {
+	r.logger.Zap.Info("Setting up login log routes")
+
+	api := r.handler.RouterV1.Group("/login-logs")
+	{
+		api.GET("", r.loginLogController.Query)
+	}
+}
+
+// NewLoginLogRoutes creates new login log routes
+func NewLoginLogRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	loginLogController controllers.LoginLogController,
+) LoginLogRoutes {
+	return LoginLogRoutes{
+		handler:            handler,
+		logger:             logger,
+		loginLogController: loginLogController,
+	}
+}
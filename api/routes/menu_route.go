@@ -2,13 +2,15 @@ package routes
 
 import (
 	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/api/middlewares"
 	"manuel71sj/go-api-template/lib"
 )
 
 type MenuRoutes struct {
-	logger         lib.Logger
-	handler        lib.HttpHandler
-	menuController controllers.MenuController
+	logger                lib.Logger
+	handler               lib.HttpHandler
+	menuController        controllers.MenuController
+	transactionMiddleware middlewares.TransactionMiddleware
 }
 
 // Setup menu routes
@@ -16,18 +18,21 @@ func (r MenuRoutes) Setup() {
 	r.logger.Zap.Info("Setting up menu routes")
 
 	api := r.handler.RouterV1.Group("/menus")
+	tx := r.transactionMiddleware.Handle()
 	{
 		api.GET("", r.menuController.Query)
+		api.GET("/export", r.menuController.Export)
+		api.PUT("/tree", r.menuController.MoveTree, tx)
 
-		api.POST("", r.menuController.Create)
+		api.POST("", r.menuController.Create, tx)
 		api.GET("/:id", r.menuController.Get)
-		api.PUT("/:id", r.menuController.Update)
-		api.DELETE("/:id", r.menuController.Delete)
-		api.PATCH("/:id/enable", r.menuController.Enable)
-		api.PATCH("/:id/disable", r.menuController.Disable)
+		api.PUT("/:id", r.menuController.Update, tx)
+		api.DELETE("/:id", r.menuController.Delete, tx)
+		api.PATCH("/:id/enable", r.menuController.Enable, tx)
+		api.PATCH("/:id/disable", r.menuController.Disable, tx)
 
 		api.GET("/:id/actions", r.menuController.GetActions)
-		api.PUT("/:id/actions", r.menuController.UpdateActions)
+		api.PUT("/:id/actions", r.menuController.UpdateActions, tx)
 	}
 }
 
@@ -36,10 +41,12 @@ func NewMenuRoutes(
 	logger lib.Logger,
 	handler lib.HttpHandler,
 	menuController controllers.MenuController,
+	transactionMiddleware middlewares.TransactionMiddleware,
 ) MenuRoutes {
 	return MenuRoutes{
-		handler:        handler,
-		logger:         logger,
-		menuController: menuController,
+		handler:               handler,
+		logger:                logger,
+		menuController:        menuController,
+		transactionMiddleware: transactionMiddleware,
 	}
 }
@@ -21,7 +21,9 @@ func (r PublicRoutes) Setup() {
 		api.GET("/user", r.publicController.UserInfo)
 		api.POST("/user/login", r.publicController.UserLogin)
 		api.POST("/user/logout", r.publicController.UserLogout)
+		api.POST("/user/refresh", r.publicController.UserRefresh)
 		api.GET("/user/menutree", r.publicController.MenuTree)
+		api.GET("/user/permissions", r.publicController.UserPermissions)
 
 		// sys routes
 		api.GET("/sys/routes", r.publicController.SysRoutes)
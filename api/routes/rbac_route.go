@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/api/middlewares"
+	"manuel71sj/go-api-template/lib"
+)
+
+type RbacRoutes struct {
+	logger                lib.Logger
+	handler               lib.HttpHandler
+	rbacController        controllers.RbacController
+	transactionMiddleware middlewares.TransactionMiddleware
+}
+
+// Setup rbac routes
+func (r RbacRoutes) Setup() {
+	r.logger.Zap.Info("Setting up rbac routes")
+
+	api := r.handler.RouterV1.Group("/rbac")
+	tx := r.transactionMiddleware.Handle()
+	{
+		api.GET("/export", r.rbacController.Export)
+		api.POST("/import", r.rbacController.Import, tx)
+	}
+}
+
+// NewRbacRoutes creates new rbac routes
+func NewRbacRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	rbacController controllers.RbacController,
+	transactionMiddleware middlewares.TransactionMiddleware,
+) RbacRoutes {
+	return RbacRoutes{
+		handler:               handler,
+		logger:                logger,
+		rbacController:        rbacController,
+		transactionMiddleware: transactionMiddleware,
+	}
+}
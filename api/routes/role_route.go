@@ -2,13 +2,15 @@ package routes
 
 import (
 	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/api/middlewares"
 	"manuel71sj/go-api-template/lib"
 )
 
 type RoleRoutes struct {
-	logger         lib.Logger
-	handler        lib.HttpHandler
-	roleController controllers.RoleController
+	logger                lib.Logger
+	handler               lib.HttpHandler
+	roleController        controllers.RoleController
+	transactionMiddleware middlewares.TransactionMiddleware
 }
 
 // Setup role routes
@@ -16,16 +18,17 @@ func (r RoleRoutes) Setup() {
 	r.logger.Zap.Info("Setting up role routes")
 
 	api := r.handler.RouterV1.Group("/roles")
+	tx := r.transactionMiddleware.Handle()
 	{
 		api.GET("", r.roleController.Query)
 		api.GET(".all", r.roleController.GetAll)
 
-		api.POST("", r.roleController.Create)
+		api.POST("", r.roleController.Create, tx)
 		api.GET("/:id", r.roleController.Get)
-		api.PUT("/:id", r.roleController.Update)
-		api.DELETE("/:id", r.roleController.Delete)
-		api.PATCH("/:id/enable", r.roleController.Enable)
-		api.PATCH("/:id/disable", r.roleController.Disable)
+		api.PUT("/:id", r.roleController.Update, tx)
+		api.DELETE("/:id", r.roleController.Delete, tx)
+		api.PATCH("/:id/enable", r.roleController.Enable, tx)
+		api.PATCH("/:id/disable", r.roleController.Disable, tx)
 	}
 }
 
@@ -34,10 +37,12 @@ func NewRoleRoutes(
 	logger lib.Logger,
 	handler lib.HttpHandler,
 	roleController controllers.RoleController,
+	transactionMiddleware middlewares.TransactionMiddleware,
 ) RoleRoutes {
 	return RoleRoutes{
-		handler:        handler,
-		logger:         logger,
-		roleController: roleController,
+		handler:               handler,
+		logger:                logger,
+		roleController:        roleController,
+		transactionMiddleware: transactionMiddleware,
 	}
 }
@@ -10,6 +10,13 @@ var Module = fx.Options(
 	fx.Provide(NewUserRoutes),
 	fx.Provide(NewRoleRoutes),
 	fx.Provide(NewMenuRoutes),
+	fx.Provide(NewRbacRoutes),
+	fx.Provide(NewTrashRoutes),
+	fx.Provide(NewWebhookRoutes),
+	fx.Provide(NewCronRoutes),
+	fx.Provide(NewAuditLogRoutes),
+	fx.Provide(NewLoginLogRoutes),
+	fx.Provide(NewSysRoutes),
 	fx.Provide(NewRoutes),
 )
 
@@ -36,6 +43,13 @@ func NewRoutes(
 	userRoutes UserRoutes,
 	roleRoutes RoleRoutes,
 	menuRoutes MenuRoutes,
+	rbacRoutes RbacRoutes,
+	trashRoutes TrashRoutes,
+	webhookRoutes WebhookRoutes,
+	cronRoutes CronRoutes,
+	auditLogRoutes AuditLogRoutes,
+	loginLogRoutes LoginLogRoutes,
+	sysRoutes SysRoutes,
 ) Routes {
 	return Routes{
 		pprofRoutes,
@@ -44,5 +58,12 @@ func NewRoutes(
 		userRoutes,
 		roleRoutes,
 		menuRoutes,
+		rbacRoutes,
+		trashRoutes,
+		webhookRoutes,
+		cronRoutes,
+		auditLogRoutes,
+		loginLogRoutes,
+		sysRoutes,
 	}
 }
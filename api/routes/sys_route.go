@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/lib"
+)
+
+type SysRoutes struct {
+	logger        lib.Logger
+	handler       lib.HttpHandler
+	sysController controllers.SysController
+}
+
+// Setup sys routes
+func (r SysRoutes) Setup() {
+	r.logger.Zap.Info("Setting up sys routes")
+
+	api := r.handler.RouterV1.Group("/sys")
+	{
+		api.GET("/cache", r.sysController.CacheStats)
+		api.GET("/database", r.sysController.DatabaseStats)
+	}
+}
+
+// NewSysRoutes creates new sys routes
+func NewSysRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	sysController controllers.SysController,
+) SysRoutes {
+	return SysRoutes{
+		handler:       handler,
+		logger:        logger,
+		sysController: sysController,
+	}
+}
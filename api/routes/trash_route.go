@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/api/middlewares"
+	"manuel71sj/go-api-template/lib"
+)
+
+type TrashRoutes struct {
+	logger                lib.Logger
+	handler               lib.HttpHandler
+	trashController       controllers.TrashController
+	transactionMiddleware middlewares.TransactionMiddleware
+}
+
+// Setup trash routes
+func (r TrashRoutes) Setup() {
+	r.logger.Zap.Info("Setting up trash routes")
+
+	api := r.handler.RouterV1.Group("/trash")
+	tx := r.transactionMiddleware.Handle()
+	{
+		api.GET("/:resource", r.trashController.Query)
+		api.POST("/:resource/:id/restore", r.trashController.Restore, tx)
+		api.DELETE("/:resource/:id", r.trashController.Purge, tx)
+	}
+}
+
+// NewTrashRoutes creates new trash routes
+func NewTrashRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	trashController controllers.TrashController,
+	transactionMiddleware middlewares.TransactionMiddleware,
+) TrashRoutes {
+	return TrashRoutes{
+		handler:               handler,
+		logger:                logger,
+		trashController:       trashController,
+		transactionMiddleware: transactionMiddleware,
+	}
+}
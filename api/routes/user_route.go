@@ -2,27 +2,31 @@ package routes
 
 import (
 	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/api/middlewares"
 	"manuel71sj/go-api-template/lib"
 )
 
 type UserRoutes struct {
-	logger         lib.Logger
-	handler        lib.HttpHandler
-	userController controllers.UserController
+	logger                lib.Logger
+	handler               lib.HttpHandler
+	userController        controllers.UserController
+	transactionMiddleware middlewares.TransactionMiddleware
 }
 
 // Setup user routes
 func (r UserRoutes) Setup() {
 	r.logger.Zap.Info("Setting up user routes")
 	api := r.handler.RouterV1.Group("/users")
+	tx := r.transactionMiddleware.Handle()
 	{
 		api.GET("", r.userController.Query)
-		api.POST("", r.userController.Create)
+		api.POST("", r.userController.Create, tx)
 		api.GET("/:id", r.userController.Get)
-		api.PUT("/:id", r.userController.Update)
-		api.DELETE("/:id", r.userController.Delete)
-		api.POST("/:id/enable", r.userController.Enable)
-		api.POST("/:id/disable", r.userController.Disable)
+		api.GET("/:id/login-logs", r.userController.QueryLoginLogs)
+		api.PUT("/:id", r.userController.Update, tx)
+		api.DELETE("/:id", r.userController.Delete, tx)
+		api.POST("/:id/enable", r.userController.Enable, tx)
+		api.POST("/:id/disable", r.userController.Disable, tx)
 	}
 }
 
@@ -31,10 +35,12 @@ func NewUserRoutes(
 	logger lib.Logger,
 	handler lib.HttpHandler,
 	userController controllers.UserController,
+	transactionMiddleware middlewares.TransactionMiddleware,
 ) UserRoutes {
 	return UserRoutes{
-		handler:        handler,
-		logger:         logger,
-		userController: userController,
+		handler:               handler,
+		logger:                logger,
+		userController:        userController,
+		transactionMiddleware: transactionMiddleware,
 	}
 }
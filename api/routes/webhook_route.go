@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"manuel71sj/go-api-template/api/controllers"
+	"manuel71sj/go-api-template/api/middlewares"
+	"manuel71sj/go-api-template/lib"
+)
+
+type WebhookRoutes struct {
+	logger                lib.Logger
+	handler               lib.HttpHandler
+	webhookController     controllers.WebhookController
+	transactionMiddleware middlewares.TransactionMiddleware
+}
+
+// Setup webhook routes
+func (r WebhookRoutes) Setup() {
+	r.logger.Zap.Info("Setting up webhook routes")
+
+	api := r.handler.RouterV1.Group("/webhooks")
+	tx := r.transactionMiddleware.Handle()
+	{
+		api.GET("", r.webhookController.Query)
+		api.POST("", r.webhookController.Create, tx)
+		api.GET("/:id", r.webhookController.Get)
+		api.PUT("/:id", r.webhookController.Update, tx)
+		api.DELETE("/:id", r.webhookController.Delete, tx)
+		api.PATCH("/:id/enable", r.webhookController.Enable, tx)
+		api.PATCH("/:id/disable", r.webhookController.Disable, tx)
+		api.POST("/:id/ping", r.webhookController.Ping)
+		api.POST("/:id/secret", r.webhookController.RotateSecret, tx)
+
+		api.GET("/:id/deliveries", r.webhookController.QueryDeliveries)
+		api.POST("/:id/deliveries/:delivery_id/redeliver", r.webhookController.Redeliver)
+	}
+}
+
+// NewWebhookRoutes creates new webhook routes
+func NewWebhookRoutes(
+	logger lib.Logger,
+	handler lib.HttpHandler,
+	webhookController controllers.WebhookController,
+	transactionMiddleware middlewares.TransactionMiddleware,
+) WebhookRoutes {
+	return WebhookRoutes{
+		handler:               handler,
+		logger:                logger,
+		webhookController:     webhookController,
+		transactionMiddleware: transactionMiddleware,
+	}
+}
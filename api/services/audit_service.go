@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"sync"
+)
+
+const (
+	// auditExportPageSize the audit logs read at once by Export
+	auditExportPageSize = 500
+	// auditExportLimit the audit logs exported at most
+	auditExportLimit = 100000
+)
+
+type auditActorKey struct{}
+
+type auditBufferKey struct{}
+
+// auditBuffer the failures recorded within the transaction of a request, written after the rollback
+type auditBuffer struct {
+	mu   sync.Mutex
+	logs models.AuditLogs
+}
+
+// AuditService records the changes of the services in the audit log. The log of a success joins
+// the transaction of the change, the log of a failure outlives the rollback of the transaction.
+type AuditService struct {
+	logger             lib.Logger
+	auditLogRepository repository.AuditLogRepository
+	ctx                context.Context
+}
+
+// WithContext binds the service to ctx, the logs name the actor of ctx and join its transaction
+func (s AuditService) WithContext(ctx context.Context) AuditService {
+	s.logger = s.logger.WithContext(ctx)
+	s.auditLogRepository = s.auditLogRepository.WithContext(ctx)
+	s.ctx = ctx
+	return s
+}
+
+// Begin returns a copy of ctx carrying the actor of a request, the failures recorded within
+// a transaction of the returned ctx are kept until Flush
+func (s AuditService) Begin(ctx context.Context, actor *dto.AuditActor) context.Context {
+	ctx = context.WithValue(ctx, auditActorKey{}, actor)
+	return context.WithValue(ctx, auditBufferKey{}, new(auditBuffer))
+}
+
+// Flush writes the failures kept by the ctx returned by Begin, once its transactions are done
+func (s AuditService) Flush(ctx context.Context) {
+	buffer, ok := ctx.Value(auditBufferKey{}).(*auditBuffer)
+	if !ok {
+		return
+	}
+
+	buffer.mu.Lock()
+	logs := buffer.logs
+	buffer.logs = nil
+	buffer.mu.Unlock()
+
+	logger := s.logger.WithContext(ctx)
+	auditLogRepository := s.auditLogRepository.WithContext(ctx)
+	for _, log := range logs {
+		if err := auditLogRepository.Create(log); err != nil {
+			logger.Zap.Errorf("Audit log %s %s[%s] write error: %v", log.Action, log.Resource, log.ResourceID, err)
+		}
+	}
+}
+
+// Record writes the audit log of the action on the resource, with the changes from before to
+// after, either may be nil. It returns err, the error of the action, or the error writing the
+// audit log of a success, so that the change is not committed without its audit log.
+func (s AuditService) Record(action, resource, resourceID string, before, after interface{}, err error) error {
+	log := models.NewAuditLog(s.actor(), action, resource, resourceID, models.NewAuditChanges(before, after), err)
+	if err == nil {
+		return s.auditLogRepository.Create(log)
+	}
+
+	// within a transaction the log would be rolled back with the failed change
+	if _, ok := lib.TransactionFromContext(s.ctx); ok {
+		if buffer, ok := s.ctx.Value(auditBufferKey{}).(*auditBuffer); ok {
+			buffer.mu.Lock()
+			buffer.logs = append(buffer.logs, log)
+			buffer.mu.Unlock()
+			return err
+		}
+	}
+
+	if werr := s.auditLogRepository.Create(log); werr != nil {
+		s.logger.Zap.Errorf("Audit log %s %s[%s] write error: %v", action, resource, resourceID, werr)
+	}
+
+	return err
+}
+
+// actor returns the actor of the bound context, the system outside of a request
+func (s AuditService) actor() *dto.AuditActor {
+	if actor, ok := s.ctx.Value(auditActorKey{}).(*dto.AuditActor); ok {
+		return actor
+	}
+
+	return &dto.AuditActor{Username: models.AuditActorSystem}
+}
+
+func (s AuditService) Query(param *models.AuditLogQueryParam) (*models.AuditLogQueryResult, error) {
+	return s.auditLogRepository.Query(param)
+}
+
+// Export calls fn with the audit logs of the query in its order, page after page by cursor,
+// at most auditExportLimit logs
+func (s AuditService) Export(param *models.AuditLogQueryParam, fn func(log *models.AuditLog) error) error {
+	param.PaginationParam = dto.PaginationParam{
+		PageSize:  auditExportPageSize,
+		Mode:      dto.PaginationCursor,
+		SkipTotal: true,
+	}
+
+	for count := 0; count < auditExportLimit; {
+		qr, err := s.auditLogRepository.Query(param)
+		if err != nil {
+			return err
+		}
+
+		for _, log := range qr.List {
+			if count == auditExportLimit {
+				return nil
+			}
+			if err := fn(log); err != nil {
+				return err
+			}
+			count++
+		}
+
+		if qr.Pagination.NextCursor == "" {
+			return nil
+		}
+		param.Cursor = qr.Pagination.NextCursor
+	}
+
+	return nil
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(logger lib.Logger, auditLogRepository repository.AuditLogRepository) AuditService {
+	return AuditService{
+		logger:             logger,
+		auditLogRepository: auditLogRepository,
+		ctx:                context.Background(),
+	}
+}
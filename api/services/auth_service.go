@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"github.com/golang-jwt/jwt"
 	"manuel71sj/go-api-template/errors"
@@ -24,6 +25,12 @@ type AuthService struct {
 	redis lib.Redis
 }
 
+// WithContext binds the token store to ctx
+func (s AuthService) WithContext(ctx context.Context) AuthService {
+	s.redis = s.redis.WithContext(ctx)
+	return s
+}
+
 func (s AuthService) GenerateToken(user *models.User) (string, error) {
 	now := time.Now()
 	claims := &dto.JwtClaims{
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/uuid"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// cronLockMargin the lock of a run outlives its timeout by this margin
+	cronLockMargin = time.Minute
+	// cronRunRetention the last run of a job is kept for this long
+	cronRunRetention = 30 * 24 * time.Hour
+)
+
+// CronJob runs on the schedule, a cron expression such as "0 * * * *" or a descriptor such
+// as "@daily". Every instance schedules the job, the instance claiming a run executes it.
+type CronJob interface {
+	Name() string
+	Schedule() string
+	Run(ctx context.Context) error
+}
+
+// AsCronJob annotates the constructor of a CronJob so that its job is added to the jobs
+// of the cron scheduler, e.g. fx.Provide(AsCronJob(NewXCronJob))
+func AsCronJob(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(CronJob)),
+		fx.ResultTags(`group:"cron_jobs"`),
+	)
+}
+
+type funcCronJob struct {
+	name     string
+	schedule string
+	fn       func(ctx context.Context) error
+}
+
+func (j funcCronJob) Name() string {
+	return j.name
+}
+
+func (j funcCronJob) Schedule() string {
+	return j.schedule
+}
+
+func (j funcCronJob) Run(ctx context.Context) error {
+	return j.fn(ctx)
+}
+
+// NewCronJob creates a cron job calling fn on the schedule
+func NewCronJob(name, schedule string, fn func(ctx context.Context) error) CronJob {
+	return funcCronJob{name: name, schedule: schedule, fn: fn}
+}
+
+// cronEntry a job with its parsed schedule, a nil schedule is a disabled job
+type cronEntry struct {
+	job      CronJob
+	spec     string
+	schedule cron.Schedule
+}
+
+// CronScheduler runs the cron jobs. The runs of the instances are claimed with a redis lock
+// per job and the scheduled time of the run, so a run happens on one instance only. The
+// schedules shall not depend on the start of the instance, "@every" only fits jobs that
+// may run once per instance.
+type CronScheduler struct {
+	config  *lib.CronConfig
+	logger  lib.Logger
+	redis   lib.Redis
+	owner   string
+	entries []*cronEntry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+}
+
+// CronSchedulerParams the dependencies of the scheduler, Jobs are those provided with AsCronJob
+type CronSchedulerParams struct {
+	fx.In
+
+	Config lib.Config
+	Logger lib.Logger
+	Redis  lib.Redis
+	Jobs   []CronJob `group:"cron_jobs"`
+}
+
+func cronLockKey(name string) string {
+	return "cron:" + name
+}
+
+func cronRunKey(name string) string {
+	return "cron:" + name + ":last-run"
+}
+
+// Start schedules the jobs in the background until Stop
+func (s CronScheduler) Start() {
+	if !s.config.Enable {
+		return
+	}
+
+	for _, entry := range s.entries {
+		if entry.schedule == nil {
+			s.logger.Zap.Infof("Cron job %s is disabled", entry.job.Name())
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.schedule(entry)
+	}
+}
+
+// Stop ends the scheduler, waiting for the running jobs at most until ctx is done
+func (s CronScheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Jobs returns the jobs with their next and last run
+func (s CronScheduler) Jobs() (models.CronJobs, error) {
+	now := time.Now()
+
+	jobs := make(models.CronJobs, 0, len(s.entries))
+	for _, entry := range s.entries {
+		job := &models.CronJob{
+			Name:     entry.job.Name(),
+			Schedule: entry.spec,
+			Disabled: !s.config.Enable || entry.schedule == nil,
+		}
+
+		if !job.Disabled {
+			next := entry.schedule.Next(now)
+			job.NextRunAt = &next
+		}
+
+		running, err := s.redis.Check(cronLockKey(job.Name))
+		if err != nil {
+			return nil, err
+		}
+		job.Running = running
+
+		run := new(models.CronRun)
+		if err := s.redis.Get(cronRunKey(job.Name), run); err == nil {
+			job.LastRun = run
+		} else if !errors.Is(err, errors.RedisKeyNoExist) {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// schedule runs the job at every scheduled time until the scheduler stops
+func (s CronScheduler) schedule(entry *cronEntry) {
+	defer s.wg.Done()
+
+	for {
+		next := entry.schedule.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(entry.job, next)
+	}
+}
+
+// run executes the job scheduled at slot if this instance claims the run
+func (s CronScheduler) run(job CronJob, slot time.Time) {
+	timeout := time.Duration(s.config.Timeout) * time.Second
+	key := cronLockKey(job.Name())
+
+	claimed, err := s.redis.Claim(key, s.owner, slot.Unix(), timeout+cronLockMargin)
+	if err != nil {
+		s.logger.Zap.Warnf("Cron job %s claim error: %v", job.Name(), err)
+		return
+	} else if !claimed {
+		return
+	}
+	defer func() {
+		if err := s.redis.ReleaseLease(key, s.owner); err != nil {
+			s.logger.Zap.Warnf("Cron job %s release error: %v", job.Name(), err)
+		}
+	}()
+
+	run := &models.CronRun{Instance: s.owner, StartedAt: time.Now()}
+	if err := s.execute(job, timeout); err != nil {
+		run.Error = err.Error()
+		s.logger.Zap.Errorf("Cron job %s error: %v", job.Name(), err)
+	}
+	run.Duration = time.Since(run.StartedAt).Milliseconds()
+
+	s.logger.Zap.Debugf("Cron job %s done in %dms", job.Name(), run.Duration)
+	if err := s.redis.Set(cronRunKey(job.Name()), run, cronRunRetention); err != nil {
+		s.logger.Zap.Warnf("Cron job %s record error: %v", job.Name(), err)
+	}
+}
+
+// execute runs the job with the timeout, a panic is an error of the run
+func (s CronScheduler) execute(job CronJob, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cron job panic: %v", r)
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+// NewCronScheduler creates a new cron scheduler of the jobs provided with AsCronJob, with
+// the schedules of the config taking precedence over those of the jobs
+func NewCronScheduler(params CronSchedulerParams) CronScheduler {
+	config := params.Config.Cron
+
+	entries := make([]*cronEntry, 0, len(params.Jobs))
+	names := make(map[string]struct{}, len(params.Jobs))
+	for _, job := range params.Jobs {
+		if _, ok := names[job.Name()]; ok {
+			params.Logger.Zap.Fatalf("Cron job %s is provided more than once", job.Name())
+		}
+		names[job.Name()] = struct{}{}
+
+		entry := &cronEntry{job: job, spec: job.Schedule()}
+		if spec, ok := config.Schedules[job.Name()]; ok {
+			entry.spec = spec
+		}
+
+		if entry.spec != lib.CronDisabled {
+			schedule, err := cron.ParseStandard(entry.spec)
+			if err != nil {
+				params.Logger.Zap.Fatalf("Cron job %s schedule %q is invalid: %v", job.Name(), entry.spec, err)
+			}
+			entry.schedule = schedule
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].job.Name() < entries[j].job.Name()
+	})
+
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return CronScheduler{
+		config:  config,
+		logger:  params.Logger,
+		redis:   params.Redis,
+		owner:   fmt.Sprintf("%s-%s", hostname, uuid.MustString()[:8]),
+		entries: entries,
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      new(sync.WaitGroup),
+	}
+}
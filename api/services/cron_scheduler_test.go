@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/internal/testutil"
+	"manuel71sj/go-api-template/lib"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCronJobName the name of the jobs of the test schedulers
+const testCronJobName = "test"
+
+// newTestCronSchedulers creates n schedulers of job sharing one redis, as n instances would
+func newTestCronSchedulers(t *testing.T, n int, timeout int, job CronJob) []CronScheduler {
+	t.Helper()
+
+	logger := testutil.NewLogger()
+	_, redisConfig := testutil.NewRedis(t)
+	config := lib.Config{Redis: redisConfig, Cron: &lib.CronConfig{Enable: true, Timeout: timeout}}
+
+	schedulers := make([]CronScheduler, n)
+	for i := range schedulers {
+		redis := lib.NewRedis(config, logger)
+		t.Cleanup(func() { _ = redis.Close() })
+
+		schedulers[i] = NewCronScheduler(CronSchedulerParams{
+			Config: config,
+			Logger: logger,
+			Redis:  redis,
+			Jobs:   []CronJob{job},
+		})
+	}
+
+	return schedulers
+}
+
+func TestCronSchedulerRunsASlotOnce(t *testing.T) {
+	var runs int32
+	job := NewCronJob(testCronJobName, "* * * * *", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+
+	schedulers := newTestCronSchedulers(t, 2, 60, job)
+	slot := time.Now().Truncate(time.Minute)
+
+	// both instances wake up for the slot at once
+	var wg sync.WaitGroup
+	for _, s := range schedulers {
+		wg.Add(1)
+		go func(s CronScheduler) {
+			defer wg.Done()
+			s.run(job, slot)
+		}(s)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("slot ran %d times, want 1", n)
+	}
+
+	// a late instance skips the slot once the lock of the run is released
+	for _, s := range schedulers {
+		s.run(job, slot)
+	}
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("slot ran %d times after the release, want 1", n)
+	}
+
+	for _, s := range schedulers {
+		s.run(job, slot.Add(time.Minute))
+	}
+
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("next slot ran %d times in total, want 2", n)
+	}
+
+	jobs, err := schedulers[1].Jobs()
+	if err != nil {
+		t.Fatal(err)
+	} else if jobs[0].Running || jobs[0].LastRun == nil || jobs[0].LastRun.Instance != schedulers[0].owner {
+		t.Errorf("job running %v, last run %+v, want the run of %s released", jobs[0].Running, jobs[0].LastRun, schedulers[0].owner)
+	}
+}
+
+func TestCronSchedulerTimesOutRuns(t *testing.T) {
+	job := NewCronJob(testCronJobName, "* * * * *", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	s := newTestCronSchedulers(t, 1, 1, job)[0]
+
+	start := time.Now()
+	s.run(job, start.Truncate(time.Minute))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("run took %s past its timeout of 1s", elapsed)
+	}
+
+	jobs, err := s.Jobs()
+	if err != nil {
+		t.Fatal(err)
+	} else if jobs[0].Running || jobs[0].LastRun == nil || jobs[0].LastRun.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("job running %v, last run %+v, want the timed out run released", jobs[0].Running, jobs[0].LastRun)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"net/http"
+	"time"
+)
+
+// EventSink publishes the domain events relayed from the outbox. The relay publishes an
+// event again until every sink accepted it, so a sink may receive an event more than once.
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, event *models.DomainEvent) error
+}
+
+// AsEventSink annotates the constructor of an EventSink so that its sink is added to
+// the sinks of the outbox relay, e.g. fx.Provide(AsEventSink(NewXEventSink))
+func AsEventSink(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(EventSink)),
+		fx.ResultTags(`group:"event_sinks"`),
+	)
+}
+
+// LogEventSink writes the events to the application log
+type LogEventSink struct {
+	logger lib.Logger
+}
+
+func (s LogEventSink) Name() string {
+	return lib.OutboxSinkLog
+}
+
+func (s LogEventSink) Publish(_ context.Context, event *models.DomainEvent) error {
+	s.logger.Zap.Infof("Event %s %s %s[%s]: %s", event.ID, event.Type, event.AggregateType, event.AggregateID, event.Data)
+	return nil
+}
+
+// NewLogEventSink creates a new log event sink
+func NewLogEventSink(logger lib.Logger) LogEventSink {
+	return LogEventSink{logger: logger}
+}
+
+// RedisStreamEventSink appends the events to a redis stream, the fields of an entry
+// are those of models.DomainEvent
+type RedisStreamEventSink struct {
+	redis  lib.Redis
+	stream string
+	maxLen int64
+}
+
+func (s RedisStreamEventSink) Name() string {
+	return lib.OutboxSinkRedis
+}
+
+func (s RedisStreamEventSink) Publish(ctx context.Context, event *models.DomainEvent) error {
+	_, err := s.redis.XAdd(ctx, s.stream, s.maxLen, map[string]interface{}{
+		"id":             event.ID,
+		"type":           event.Type,
+		"aggregate_type": event.AggregateType,
+		"aggregate_id":   event.AggregateID,
+		"occurred_at":    event.OccurredAt.Format(time.RFC3339Nano),
+		"data":           string(event.Data),
+	})
+
+	return err
+}
+
+// NewRedisStreamEventSink creates a new redis stream event sink
+func NewRedisStreamEventSink(config lib.Config, redis lib.Redis) RedisStreamEventSink {
+	return RedisStreamEventSink{
+		redis:  redis,
+		stream: config.Outbox.RedisStream,
+		maxLen: config.Outbox.RedisMaxLen,
+	}
+}
+
+// HttpEventSink POSTs every event as JSON to an url, any status but 2xx is a failure
+type HttpEventSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s HttpEventSink) Name() string {
+	return lib.OutboxSinkHttp
+}
+
+func (s HttpEventSink) Publish(ctx context.Context, event *models.DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s", s.url, resp.Status)
+	}
+
+	return nil
+}
+
+// NewHttpEventSink creates a new http event sink
+func NewHttpEventSink(config lib.Config) HttpEventSink {
+	return HttpEventSink{
+		url:    config.Outbox.HttpURL,
+		client: &http.Client{Timeout: time.Duration(config.Outbox.HttpTimeout) * time.Second},
+	}
+}
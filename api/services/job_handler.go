@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/lib"
+)
+
+// JobHandler runs the jobs of a type. A job is run again after an error or a visibility
+// timeout, so a handler may run a job more than once.
+type JobHandler interface {
+	Type() string
+	Handle(ctx context.Context, job *lib.Job) error
+}
+
+// AsJobHandler annotates the constructor of a JobHandler so that its handler is added to
+// the handlers of the job worker, e.g. fx.Provide(AsJobHandler(NewXJobHandler))
+func AsJobHandler(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(JobHandler)),
+		fx.ResultTags(`group:"job_handlers"`),
+	)
+}
+
+// typedJobHandler decodes the payload of the job into T before calling fn
+type typedJobHandler[T any] struct {
+	jobType string
+	fn      func(ctx context.Context, payload T) error
+}
+
+func (h typedJobHandler[T]) Type() string {
+	return h.jobType
+}
+
+func (h typedJobHandler[T]) Handle(ctx context.Context, job *lib.Job) error {
+	var payload T
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+
+	return h.fn(ctx, payload)
+}
+
+// NewJobHandler creates a handler of the jobs of jobType whose payload is a T
+func NewJobHandler[T any](jobType string, fn func(ctx context.Context, payload T) error) JobHandler {
+	return typedJobHandler[T]{jobType: jobType, fn: fn}
+}
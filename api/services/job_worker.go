@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"sync"
+	"time"
+)
+
+const (
+	// jobPromoteLimit the jobs of a queue made ready by a promote at most
+	jobPromoteLimit = 1000
+	// jobFinishTimeout the time to record the outcome of a job, even while draining
+	jobFinishTimeout = 5 * time.Second
+)
+
+// JobWorker runs the jobs of the configured queues with the handlers of their types,
+// with Concurrency jobs of a queue at a time
+type JobWorker struct {
+	config   *lib.JobsConfig
+	logger   lib.Logger
+	jobQueue lib.JobQueue
+	handlers map[string]JobHandler
+	// ctx is cancelled when the drain of Stop times out, it cancels the running jobs
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	stopOnce *sync.Once
+	wg       *sync.WaitGroup
+}
+
+// JobWorkerParams the dependencies of the worker, Handlers are those provided with AsJobHandler
+type JobWorkerParams struct {
+	fx.In
+
+	Config   lib.Config
+	Logger   lib.Logger
+	JobQueue lib.JobQueue
+	Handlers []JobHandler `group:"job_handlers"`
+}
+
+// Start runs the jobs in the background until Stop
+func (w JobWorker) Start() {
+	for _, queue := range w.config.Queues {
+		for i := 0; i < queue.Concurrency; i++ {
+			w.wg.Add(1)
+			go w.fetch(queue)
+		}
+	}
+
+	w.wg.Add(1)
+	go w.promote()
+
+	w.logger.Zap.Infof("Job worker started with %d handlers", len(w.handlers))
+}
+
+// Stop stops taking jobs and waits for the running jobs at most until ctx is done,
+// then cancels them. A cancelled job is retried like a failed one, or after its
+// visibility timeout when the process exits first. Stop may be called more than once.
+func (w JobWorker) Stop(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// fetch runs the jobs of queue one at a time, polling while the queue is empty
+func (w JobWorker) fetch(queue *lib.JobQueueConfig) {
+	defer w.wg.Done()
+
+	visibility := time.Duration(queue.Visibility) * time.Second
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		job, err := w.jobQueue.Dequeue(w.ctx, queue.Name, visibility)
+		if err != nil {
+			w.logger.Zap.Errorf("Job queue %s read error: %v", queue.Name, err)
+		}
+
+		if job == nil {
+			if !w.wait() {
+				return
+			}
+			continue
+		}
+
+		w.run(job, visibility)
+	}
+}
+
+// promote makes the scheduled and the timed out jobs of every queue ready
+func (w JobWorker) promote() {
+	defer w.wg.Done()
+
+	for {
+		for _, queue := range w.config.Queues {
+			if _, err := w.jobQueue.Promote(w.ctx, queue.Name, jobPromoteLimit); err != nil {
+				w.logger.Zap.Errorf("Job queue %s promote error: %v", queue.Name, err)
+			}
+		}
+
+		if !w.wait() {
+			return
+		}
+	}
+}
+
+// wait sleeps a poll interval, it reports false when the worker stops meanwhile
+func (w JobWorker) wait() bool {
+	timer := time.NewTimer(time.Duration(w.config.PollInterval) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-w.stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// run handles the job within its visibility, then records its outcome
+func (w JobWorker) run(job *lib.Job, visibility time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobFinishTimeout)
+	defer cancel()
+
+	if job.Attempts > job.MaxAttempts {
+		// the last attempt did not end within the visibility
+		w.kill(ctx, job, errors.New("job was not done within its visibility timeout"))
+		return
+	}
+
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		w.kill(ctx, job, errors.Wrapf(errors.JobHandlerUnknown, "type %s", job.Type))
+		return
+	}
+
+	start := time.Now()
+	if err := w.handle(handler, job, visibility); err == nil {
+		if err := w.jobQueue.Ack(ctx, job); err != nil {
+			w.logger.Zap.Warnf("Job %s %s done in %s, ack error: %v", job.Type, job.ID, time.Since(start), err)
+		} else {
+			w.logger.Zap.Debugf("Job %s %s done in %s", job.Type, job.ID, time.Since(start))
+		}
+	} else if job.Attempts >= job.MaxAttempts {
+		w.kill(ctx, job, err)
+	} else {
+		delay := w.config.Backoff(job.Attempts)
+		w.logger.Zap.Warnf("Job %s %s attempt %d error, retry in %s: %v", job.Type, job.ID, job.Attempts, delay, err)
+		if err := w.jobQueue.Retry(ctx, job, delay, err); err != nil {
+			w.logger.Zap.Errorf("Job %s %s retry error: %v", job.Type, job.ID, err)
+		}
+	}
+}
+
+// handle calls the handler with the visibility as timeout, a panic is an error of the job
+func (w JobWorker) handle(handler JobHandler, job *lib.Job, visibility time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(w.ctx, visibility)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panic: %v", r)
+		}
+	}()
+
+	return handler.Handle(ctx, job)
+}
+
+func (w JobWorker) kill(ctx context.Context, job *lib.Job, cause error) {
+	w.logger.Zap.Errorf("Job %s %s dead after %d attempts: %v", job.Type, job.ID, job.Attempts, cause)
+	if err := w.jobQueue.Kill(ctx, job, cause); err != nil {
+		w.logger.Zap.Errorf("Job %s %s kill error: %v", job.Type, job.ID, err)
+	}
+}
+
+// NewJobWorker creates a new job worker
+func NewJobWorker(params JobWorkerParams) JobWorker {
+	handlers := make(map[string]JobHandler, len(params.Handlers))
+	for _, handler := range params.Handlers {
+		if _, ok := handlers[handler.Type()]; ok {
+			params.Logger.Zap.Fatalf("Job type %s has more than one handler", handler.Type())
+		}
+		handlers[handler.Type()] = handler
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return JobWorker{
+		config:   params.Config.Jobs,
+		logger:   params.Logger,
+		jobQueue: params.JobQueue,
+		handlers: handlers,
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+		stopOnce: new(sync.Once),
+		wg:       new(sync.WaitGroup),
+	}
+}
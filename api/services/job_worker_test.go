@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/internal/testutil"
+	"manuel71sj/go-api-template/lib"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testJobType the type of the jobs of the test handlers
+const testJobType = "test.job"
+
+// testJobVisibility the visibility of the jobs run by the tests
+const testJobVisibility = 30 * time.Second
+
+func newTestJobWorker(t *testing.T, config *lib.JobsConfig, handlers ...JobHandler) (JobWorker, *miniredis.RedisDB) {
+	t.Helper()
+
+	logger := testutil.NewLogger()
+	server, redisConfig := testutil.NewRedis(t)
+
+	config.Queues = []*lib.JobQueueConfig{{Name: lib.JobQueueDefault, Concurrency: 1, Visibility: 30}}
+	if config.PollInterval == 0 {
+		config.PollInterval = 10
+	}
+
+	jobQueue := lib.NewJobQueue(lib.Config{Redis: redisConfig, Jobs: config}, logger)
+	t.Cleanup(func() { _ = jobQueue.Close() })
+
+	worker := NewJobWorker(JobWorkerParams{
+		Config:   lib.Config{Jobs: config},
+		Logger:   logger,
+		JobQueue: jobQueue,
+		Handlers: handlers,
+	})
+
+	return worker, server.DB(constants.RedisTaskDB)
+}
+
+// testJobKey the key of the part name of the default queue
+func testJobKey(name string) string {
+	return "test:jobs:" + lib.JobQueueDefault + ":" + name
+}
+
+// takeTestJob makes the due jobs ready and takes the next one
+func takeTestJob(t *testing.T, w JobWorker) *lib.Job {
+	t.Helper()
+
+	if _, err := w.jobQueue.Promote(context.Background(), lib.JobQueueDefault, jobPromoteLimit); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := w.jobQueue.Dequeue(context.Background(), lib.JobQueueDefault, testJobVisibility)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return job
+}
+
+func TestJobQueuePromotesDelayedJobs(t *testing.T) {
+	w, _ := newTestJobWorker(t, &lib.JobsConfig{MaxAttempts: 3})
+	ctx := context.Background()
+
+	id, err := w.jobQueue.Enqueue(ctx, testJobType, "payload", lib.JobDelay(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job := takeTestJob(t, w); job != nil {
+		t.Fatalf("delayed job %s taken before its run time", job.ID)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	job := takeTestJob(t, w)
+	if job == nil || job.ID != id || job.Attempts != 1 {
+		t.Fatalf("job %+v taken after its run time, want %s at attempt 1", job, id)
+	}
+}
+
+func TestJobWorkerRetriesWithBackoff(t *testing.T) {
+	var runs int32
+	handler := NewJobHandler(testJobType, func(ctx context.Context, payload string) error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("unavailable")
+	})
+
+	w, db := newTestJobWorker(t, &lib.JobsConfig{MaxAttempts: 3, BackoffBase: 60, BackoffMax: 600}, handler)
+	id, err := w.jobQueue.Enqueue(context.Background(), testJobType, "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt, backoff := range []time.Duration{time.Minute, 2 * time.Minute} {
+		job := takeTestJob(t, w)
+		if job == nil || job.Attempts != attempt+1 {
+			t.Fatalf("job %+v taken, want attempt %d", job, attempt+1)
+		}
+
+		w.run(job, testJobVisibility)
+
+		score, err := db.ZScore(testJobKey("scheduled"), id)
+		if err != nil {
+			t.Fatalf("failed job not scheduled: %v", err)
+		}
+
+		runAt := time.UnixMilli(int64(score))
+		if delay := time.Until(runAt); delay < backoff-5*time.Second || delay > backoff {
+			t.Errorf("attempt %d retried in %s, want %s", attempt+1, delay, backoff)
+		}
+
+		if job := takeTestJob(t, w); job != nil {
+			t.Fatalf("job retried before its backoff")
+		}
+
+		// the backoff has passed
+		if _, err := db.ZAdd(testJobKey("scheduled"), 0, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if job := takeTestJob(t, w); job == nil || job.Attempts != 3 || job.LastError != "unavailable" {
+		t.Errorf("job %+v taken, want attempt 3 after the error", job)
+	}
+
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestJobWorkerKillsAfterMaxAttempts(t *testing.T) {
+	var runs int32
+	handler := NewJobHandler(testJobType, func(ctx context.Context, payload string) error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("unavailable")
+	})
+
+	// no backoff, a failed job is due again right away
+	w, db := newTestJobWorker(t, &lib.JobsConfig{MaxAttempts: 2, DeadRetention: 1}, handler)
+	id, err := w.jobQueue.Enqueue(context.Background(), testJobType, "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if job := takeTestJob(t, w); job != nil {
+			w.run(job, testJobVisibility)
+		}
+	}
+
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+
+	dead, err := w.jobQueue.Dead(context.Background(), lib.JobQueueDefault, 10)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dead) != 1 || dead[0].ID != id || dead[0].Attempts != 2 || dead[0].LastError != "unavailable" {
+		t.Fatalf("dead jobs %+v, want %s after 2 attempts", dead, id)
+	}
+
+	if ids, _ := db.HKeys(testJobKey("attempts")); len(ids) != 0 {
+		t.Errorf("attempts of %v kept after the kill", ids)
+	}
+}
+
+func TestJobWorkerStopDrainsRunningJobs(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var done int32
+	handler := NewJobHandler(testJobType, func(ctx context.Context, payload string) error {
+		started <- struct{}{}
+
+		select {
+		case <-time.After(200 * time.Millisecond):
+			atomic.AddInt32(&done, 1)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	w, db := newTestJobWorker(t, &lib.JobsConfig{MaxAttempts: 3}, handler)
+	ctx := context.Background()
+
+	first, err := w.jobQueue.Enqueue(ctx, testJobType, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Start()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job not started")
+	}
+
+	// the worker takes no new job once stopping
+	second, err := w.jobQueue.Enqueue(ctx, testJobType, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := w.Stop(stopCtx); err != nil {
+		t.Fatal(err)
+	} else if err := w.Stop(stopCtx); err != nil {
+		t.Errorf("second stop: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&done); n != 1 {
+		t.Fatalf("%d jobs done before the stop returned, want 1", n)
+	}
+
+	if db.Exists("test:jobs:data:" + first) {
+		t.Errorf("drained job %s not acked", first)
+	}
+
+	if ids, _ := db.List(testJobKey("ready")); len(ids) != 1 || ids[0] != second {
+		t.Errorf("ready jobs %v after the stop, want %s", ids, second)
+	}
+}
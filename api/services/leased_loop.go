@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/pkg/uuid"
+	"time"
+)
+
+// leasedLoopTTL a lease not extended for this long is taken by another instance
+const leasedLoopTTL = 30 * time.Second
+
+// leasedLoop runs a background job on the one instance holding the redis lease of the job
+type leasedLoop struct {
+	key      string
+	owner    string
+	interval time.Duration
+	redis    lib.Redis
+	logger   lib.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// start calls fn every interval while holding the lease, and again right away as long as
+// fn reports more work. fn stops when the ctx it is given is done.
+func (l leasedLoop) start(fn func(ctx context.Context) bool) {
+	go func() {
+		defer close(l.done)
+
+		ticker := time.NewTicker(l.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.ctx.Done():
+				_ = l.redis.ReleaseLease(l.key, l.owner)
+				return
+			case <-ticker.C:
+			}
+
+			for l.lease() && fn(l.ctx) && l.ctx.Err() == nil {
+			}
+		}
+	}()
+}
+
+// skip marks the loop as never started, for stop
+func (l leasedLoop) skip() {
+	close(l.done)
+}
+
+// stop ends the loop, waiting for fn at most until ctx is done
+func (l leasedLoop) stop(ctx context.Context) error {
+	l.cancel()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lease reports whether this instance holds the lease, taking or extending it
+func (l leasedLoop) lease() bool {
+	ok, err := l.redis.Lease(l.key, l.owner, leasedLoopTTL)
+	if err != nil {
+		l.logger.Zap.Warnf("Lease %s error: %v", l.key, err)
+	}
+
+	return ok
+}
+
+func newLeasedLoop(redis lib.Redis, logger lib.Logger, key string, interval time.Duration) leasedLoop {
+	ctx, cancel := context.WithCancel(context.Background())
+	return leasedLoop{
+		key:      key,
+		owner:    uuid.MustString(),
+		interval: interval,
+		redis:    redis,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
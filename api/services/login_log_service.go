@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"time"
+)
+
+// LoginAlertHook is told of the successful logins from a new device or a new location,
+// e.g. to notify the user. A hook error is logged, it never fails the login.
+type LoginAlertHook interface {
+	Alert(ctx context.Context, log *models.LoginLog) error
+}
+
+// AsLoginAlertHook annotates the constructor of a LoginAlertHook so that its hook is added to
+// the hooks of the login log service, e.g. fx.Provide(AsLoginAlertHook(NewXLoginAlertHook))
+func AsLoginAlertHook(constructor interface{}) interface{} {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(LoginAlertHook)),
+		fx.ResultTags(`group:"login_alert_hooks"`),
+	)
+}
+
+// LoginAlertEventHook emits user.new_device_login, published by the outbox relay to the
+// sinks and the webhooks subscribing to it
+type LoginAlertEventHook struct {
+	outboxService OutboxService
+}
+
+func (h LoginAlertEventHook) Alert(ctx context.Context, log *models.LoginLog) error {
+	data := &models.EventNewDeviceLoginData{
+		ID:          log.UserID,
+		Username:    log.Username,
+		IP:          log.IP,
+		UserAgent:   log.UserAgent,
+		NewDevice:   log.NewDevice,
+		NewLocation: log.NewLocation,
+		LoggedInAt:  log.CreatedAt,
+	}
+
+	return h.outboxService.WithContext(ctx).Emit(models.EventUserNewDeviceLogin, models.AggregateUser, log.UserID, data)
+}
+
+// NewLoginAlertEventHook creates a new login alert hook emitting a domain event
+func NewLoginAlertEventHook(outboxService OutboxService) LoginAlertEventHook {
+	return LoginAlertEventHook{outboxService: outboxService}
+}
+
+// LoginLogService records the logins, logouts and token refreshes of the users
+type LoginLogService struct {
+	config             *lib.LoginLogConfig
+	logger             lib.Logger
+	loginLogRepository repository.LoginLogRepository
+	hooks              []LoginAlertHook
+}
+
+// LoginLogServiceParams the dependencies of the service, Hooks are those provided with AsLoginAlertHook
+type LoginLogServiceParams struct {
+	fx.In
+
+	Config             lib.Config
+	Logger             lib.Logger
+	LoginLogRepository repository.LoginLogRepository
+	Hooks              []LoginAlertHook `group:"login_alert_hooks"`
+}
+
+// WithContext binds the service to ctx
+func (s LoginLogService) WithContext(ctx context.Context) LoginLogService {
+	s.logger = s.logger.WithContext(ctx)
+	s.loginLogRepository = s.loginLogRepository.WithContext(ctx)
+	return s
+}
+
+func (s LoginLogService) Query(param *models.LoginLogQueryParam) (*models.LoginLogQueryResult, error) {
+	return s.loginLogRepository.Query(param)
+}
+
+// Record writes the login log. A successful login of a user with earlier logins in the device
+// window is checked against them, when its device or its network is new the hooks are alerted.
+// Errors are logged only, the login log never fails the request.
+func (s LoginLogService) Record(ctx context.Context, log *models.LoginLog) {
+	s = s.WithContext(ctx)
+
+	if log.Event == models.LoginEventLogin && log.Outcome == models.LoginOutcomeSuccess && log.UserID != "" {
+		if err := s.detect(log); err != nil {
+			s.logger.Zap.Warnf("Login of %s device check error: %v", log.Username, err)
+		}
+	}
+
+	if err := s.loginLogRepository.Create(log); err != nil {
+		s.logger.Zap.Errorf("Login log %s of %s write error: %v", log.Event, log.Username, err)
+		return
+	}
+
+	if !log.Unfamiliar() {
+		return
+	}
+
+	for _, hook := range s.hooks {
+		if err := hook.Alert(ctx, log); err != nil {
+			s.logger.Zap.Errorf("Login alert of %s error: %v", log.Username, err)
+		}
+	}
+}
+
+// detect flags the login when the user logged in within the device window, but neither
+// with its user agent nor from its network
+func (s LoginLogService) detect(log *models.LoginLog) error {
+	since := time.Now().AddDate(0, 0, -s.config.DeviceWindow)
+
+	if ok, err := s.loginLogRepository.LoggedIn(log.UserID, since, "", ""); err != nil || !ok {
+		// the first login of a user is not compared
+		return err
+	}
+
+	known, err := s.loginLogRepository.LoggedIn(log.UserID, since, "user_agent", log.UserAgent)
+	if err != nil {
+		return err
+	}
+	log.NewDevice = !known
+
+	known, err = s.loginLogRepository.LoggedIn(log.UserID, since, "network", log.Network)
+	if err != nil {
+		return err
+	}
+	log.NewLocation = !known
+
+	return nil
+}
+
+// NewLoginLogService creates a new login log service
+func NewLoginLogService(params LoginLogServiceParams) LoginLogService {
+	return LoginLogService{
+		config:             params.Config.LoginLog,
+		logger:             params.Logger,
+		loginLogRepository: params.LoginLogRepository,
+		hooks:              params.Hooks,
+	}
+}
+
+// NewLoginLogCleanupCronJob creates the cron job deleting the login logs older than the retention
+func NewLoginLogCleanupCronJob(
+	config lib.Config,
+	logger lib.Logger,
+	loginLogRepository repository.LoginLogRepository,
+) CronJob {
+	return NewCronJob("login-log-cleanup", "30 3 * * *", func(ctx context.Context) error {
+		if config.LoginLog.Retention <= 0 {
+			return nil
+		}
+
+		before := time.Now().AddDate(0, 0, -config.LoginLog.Retention)
+		count, err := loginLogRepository.WithContext(ctx).DeleteBefore(before)
+		if err != nil {
+			return err
+		} else if count > 0 {
+			logger.Zap.Infof("Login log cleanup deleted %d logs", count)
+		}
+
+		return nil
+	})
+}
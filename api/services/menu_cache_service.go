@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/hash"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MenuCacheService caches the menu trees and permissions computed for a role set.
+// Keys embed the versions of the roles, and of the user for the subject entries,
+// so invalidating is bumping a version and stale entries, local ones included, are never read again.
+type MenuCacheService struct {
+	ctx    context.Context
+	logger lib.Logger
+	redis  lib.Redis
+	stats  map[string]*menuCacheCounter
+}
+
+type menuCacheCounter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// WithContext binds the cache to ctx
+func (s MenuCacheService) WithContext(ctx context.Context) MenuCacheService {
+	s.ctx = ctx
+	s.logger = s.logger.WithContext(ctx)
+	s.redis = s.redis.WithContext(ctx)
+	return s
+}
+
+// Subject returns the role set and locale of the user, loading them on a cache miss
+func (s MenuCacheService) Subject(userID string, load func() (*models.MenuCacheSubject, error)) (*models.MenuCacheSubject, error) {
+	versions, err := s.redis.GetInts(s.versionKey(), s.versionKey("user", userID))
+	if err != nil {
+		s.logger.Zap.Warnf("Menu cache version read error: %v", err)
+		return load()
+	}
+
+	key := fmt.Sprintf("%s:%s:%s:%d.%d",
+		constants.MenuCacheKeyPrefix, models.MenuCacheKindSubject, userID, versions[0], versions[1])
+
+	var subject *models.MenuCacheSubject
+	err = s.load(models.MenuCacheKindSubject, key, &subject, func() (err error) {
+		subject, err = load()
+		return
+	})
+
+	return subject, err
+}
+
+// MenuTrees returns the menu trees of the subject in locale, loading them on a cache miss
+func (s MenuCacheService) MenuTrees(
+	subject *models.MenuCacheSubject,
+	locale string,
+	load func() (models.MenuTrees, error),
+) (models.MenuTrees, error) {
+	key, err := s.subjectKey(models.MenuCacheKindTree, subject)
+	if err != nil {
+		s.logger.Zap.Warnf("Menu cache version read error: %v", err)
+		return load()
+	}
+
+	menuTrees := make(models.MenuTrees, 0)
+	err = s.load(models.MenuCacheKindTree, key+":"+locale, &menuTrees, func() (err error) {
+		menuTrees, err = load()
+		return
+	})
+
+	return menuTrees, err
+}
+
+// Permissions returns the permissions of the subject, loading them on a cache miss
+func (s MenuCacheService) Permissions(
+	subject *models.MenuCacheSubject,
+	load func() (models.UserPermissions, error),
+) (models.UserPermissions, error) {
+	key, err := s.subjectKey(models.MenuCacheKindPermissions, subject)
+	if err != nil {
+		s.logger.Zap.Warnf("Menu cache version read error: %v", err)
+		return load()
+	}
+
+	permissions := make(models.UserPermissions)
+	err = s.load(models.MenuCacheKindPermissions, key, &permissions, func() (err error) {
+		permissions, err = load()
+		return
+	})
+
+	return permissions, err
+}
+
+// The invalidations wait for the commit of the transaction of the bound context, if any,
+// so that a concurrent miss cannot cache the rows of before the change under the new versions.
+
+// InvalidateAll drops every entry, it is used for menu changes since menus are part of all of them
+func (s MenuCacheService) InvalidateAll() {
+	s.invalidate(s.versionKey())
+}
+
+// InvalidateUsers drops the subject entries of the users, after their roles or locale changed
+func (s MenuCacheService) InvalidateUsers(ids ...string) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.versionKey("user", id)
+	}
+
+	s.invalidate(keys...)
+}
+
+// InvalidateRoles drops the entries of the role sets containing the roles, after their menus or status changed
+func (s MenuCacheService) InvalidateRoles(ids ...string) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.versionKey("role", id)
+	}
+
+	s.invalidate(keys...)
+}
+
+func (s MenuCacheService) invalidate(keys ...string) {
+	lib.AfterCommit(s.ctx, func() {
+		for _, key := range keys {
+			if _, err := s.redis.Incr(key); err != nil {
+				s.logger.Zap.Warnf("Menu cache invalidation error: %v", err)
+			}
+		}
+	})
+}
+
+// Stats returns the hit and miss counters of this process
+func (s MenuCacheService) Stats() []*dto.CacheStat {
+	kinds := []string{models.MenuCacheKindSubject, models.MenuCacheKindTree, models.MenuCacheKindPermissions}
+
+	stats := make([]*dto.CacheStat, len(kinds))
+	for i, kind := range kinds {
+		stats[i] = &dto.CacheStat{
+			Kind:   kind,
+			Hits:   s.stats[kind].hits.Load(),
+			Misses: s.stats[kind].misses.Load(),
+		}
+	}
+
+	return stats
+}
+
+func (s MenuCacheService) load(kind, key string, value interface{}, load func() error) error {
+	err := s.redis.Get(key, value)
+	if err == nil {
+		s.stats[kind].hits.Add(1)
+		return nil
+	}
+
+	s.stats[kind].misses.Add(1)
+	if !errors.Is(err, errors.RedisKeyNoExist) {
+		s.logger.Zap.Warnf("Menu cache read error: %v", err)
+	}
+
+	if err := load(); err != nil {
+		return err
+	}
+
+	// entries never change once written, see versionKey, so they can live in the local cache
+	if err := s.redis.SetLocal(key, value, time.Second*constants.MenuCacheExpireTimes); err != nil {
+		s.logger.Zap.Warnf("Menu cache write error: %v", err)
+	}
+
+	return nil
+}
+
+// subjectKey identifies the role set of the subject at the current versions
+func (s MenuCacheService) subjectKey(kind string, subject *models.MenuCacheSubject) (string, error) {
+	roleIDs := append([]string(nil), subject.RoleIDs...)
+	sort.Strings(roleIDs)
+
+	keys := []string{s.versionKey()}
+	for _, id := range roleIDs {
+		keys = append(keys, s.versionKey("role", id))
+	}
+
+	versions, err := s.redis.GetInts(keys...)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d", versions[0])
+	if subject.SuperAdmin {
+		b.WriteString("|super-admin")
+	}
+
+	for i, id := range roleIDs {
+		fmt.Fprintf(&b, "|%s@%d", id, versions[i+1])
+	}
+
+	return fmt.Sprintf("%s:%s:%s", constants.MenuCacheKeyPrefix, kind, hash.SHA1(b.String())), nil
+}
+
+func (s MenuCacheService) versionKey(parts ...string) string {
+	return strings.Join(append([]string{constants.MenuCacheKeyPrefix, "version"}, parts...), ":")
+}
+
+// NewMenuCacheService creates a new menu cache service
+func NewMenuCacheService(logger lib.Logger, redis lib.Redis) MenuCacheService {
+	return MenuCacheService{
+		ctx:    context.Background(),
+		logger: logger,
+		redis:  redis,
+		stats: map[string]*menuCacheCounter{
+			models.MenuCacheKindSubject:     {},
+			models.MenuCacheKindTree:        {},
+			models.MenuCacheKindPermissions: {},
+		},
+	}
+}
@@ -1,13 +1,18 @@
 package services
 
 import (
-	"gorm.io/gorm"
+	"bytes"
+	"context"
+	"gopkg.in/yaml.v3"
 	"manuel71sj/go-api-template/api/repository"
 	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
 	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/file"
 	"manuel71sj/go-api-template/pkg/uuid"
+	"os"
+	"sort"
 )
 
 // MenuService Service layer
@@ -16,13 +21,24 @@ type MenuService struct {
 	menuRepository               repository.MenuRepository
 	menuActionRepository         repository.MenuActionRepository
 	menuActionResourceRepository repository.MenuActionResourceRepository
+	roleMenuRepository           repository.RoleMenuRepository
+	menuI18nRepository           repository.MenuI18nRepository
+	menuCacheService             MenuCacheService
+	outboxService                OutboxService
+	auditService                 AuditService
 }
 
-// WithTrx delegates transaction to repository database
-func (s MenuService) WithTrx(trxHandle *gorm.DB) MenuService {
-	s.menuRepository = s.menuRepository.WithTrx(trxHandle)
-	s.menuActionRepository = s.menuActionRepository.WithTrx(trxHandle)
-	s.menuActionResourceRepository = s.menuActionResourceRepository.WithTrx(trxHandle)
+// WithContext binds the repositories to ctx, so that they join the transaction carried by ctx
+func (s MenuService) WithContext(ctx context.Context) MenuService {
+	s.logger = s.logger.WithContext(ctx)
+	s.menuCacheService = s.menuCacheService.WithContext(ctx)
+	s.menuRepository = s.menuRepository.WithContext(ctx)
+	s.menuActionRepository = s.menuActionRepository.WithContext(ctx)
+	s.menuActionResourceRepository = s.menuActionResourceRepository.WithContext(ctx)
+	s.roleMenuRepository = s.roleMenuRepository.WithContext(ctx)
+	s.menuI18nRepository = s.menuI18nRepository.WithContext(ctx)
+	s.outboxService = s.outboxService.WithContext(ctx)
+	s.auditService = s.auditService.WithContext(ctx)
 
 	return s
 }
@@ -42,6 +58,26 @@ func (s MenuService) Check(item *models.Menu) error {
 	return nil
 }
 
+// CheckCode returns MenuCodeDuplicate when another menu has the code of item
+func (s MenuService) CheckCode(item *models.Menu) error {
+	if item.Code == "" {
+		return nil
+	}
+
+	result, err := s.menuRepository.Query(&models.MenuQueryParam{Code: item.Code})
+	if err != nil {
+		return err
+	}
+
+	for _, menu := range result.List {
+		if menu.ID != item.ID {
+			return errors.MenuCodeDuplicate
+		}
+	}
+
+	return nil
+}
+
 func (s MenuService) Query(param *models.MenuQueryParam) (*models.MenuQueryResult, error) {
 	menuQR, err := s.menuRepository.Query(param)
 	if err != nil {
@@ -66,11 +102,52 @@ func (s MenuService) Query(param *models.MenuQueryParam) (*models.MenuQueryResul
 		return nil, err
 	}
 
+	mNames, err := s.GetI18nNames(menuQR.List.ToIDs())
+	if err != nil {
+		return nil, err
+	}
+
 	menuQR.List.FillMenuAction(menuActionQR.List.ToMenuIDMap(), menuResourceQR.List.ToActionIDMap())
+	menuQR.List.FillI18n(mNames)
 
 	return menuQR, nil
 }
 
+// GetI18nNames returns the translated names of the menus and their actions, see MenuI18ns.ToNamesMap
+func (s MenuService) GetI18nNames(menuIDs []string) (map[string]map[string]string, error) {
+	menuI18nQR, err := s.menuI18nRepository.Query(&models.MenuI18nQueryParam{
+		MenuIDs: menuIDs, PaginationParam: dto.PaginationParam{PageSize: 9999, Current: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return menuI18nQR.List.ToNamesMap(), nil
+}
+
+// SaveI18n replaces the translated names of a menu, or of one of its actions when actionID is set
+func (s MenuService) SaveI18n(menuID, actionID string, names map[string]string) error {
+	if err := s.menuI18nRepository.DeleteByTarget(menuID, actionID); err != nil {
+		return err
+	}
+
+	for locale, name := range names {
+		menuI18n := &models.MenuI18n{
+			ID:       uuid.MustString(),
+			MenuID:   menuID,
+			ActionID: actionID,
+			Locale:   locale,
+			Name:     name,
+		}
+
+		if err := s.menuI18nRepository.Create(menuI18n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s MenuService) GetMenuActions(id string) (models.MenuActions, error) {
 	paginationParam := dto.PaginationParam{PageSize: 999, Current: 1}
 
@@ -104,10 +181,18 @@ func (s MenuService) Get(id string) (*models.Menu, error) {
 }
 
 func (s MenuService) Create(menu *models.Menu) (id string, err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditCreate, models.AggregateMenu, menu.ID, nil, menu, err)
+	}()
+
 	if err = s.Check(menu); err != nil {
 		return
 	}
 
+	if err = s.CheckCode(menu); err != nil {
+		return
+	}
+
 	if menu.ParentPath, err = s.GetParentPath(menu.ParentID); err != nil {
 		return
 	}
@@ -117,20 +202,122 @@ func (s MenuService) Create(menu *models.Menu) (id string, err error) {
 		return
 	}
 
+	if err = s.SaveI18n(menu.ID, "", menu.I18n); err != nil {
+		return
+	}
+
+	if err = s.outboxService.Emit(models.EventMenuCreated, models.AggregateMenu, menu.ID, menu); err != nil {
+		return
+	}
+
+	s.menuCacheService.InvalidateAll()
 	return menu.ID, nil
 }
 
+// ReadMenuFile decodes the menu trees of a menu file (see config/menu.yaml)
+func (s MenuService) ReadMenuFile(path string) (models.MenuTrees, error) {
+	if !file.IsFile(path) {
+		return nil, errors.MenuFileNotExist
+	}
+
+	fs, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(fs *os.File) {
+		_ = fs.Close()
+	}(fs)
+
+	var menuTrees models.MenuTrees
+	if err := yaml.NewDecoder(fs).Decode(&menuTrees); err != nil {
+		return nil, errors.Wrap(errors.MenuFileDecodeError, err.Error())
+	}
+
+	return menuTrees, nil
+}
+
+// ExportMenuTrees returns all menus with their actions and resources in the menu file layout
+func (s MenuService) ExportMenuTrees() (models.MenuTrees, error) {
+	paginationParam := dto.PaginationParam{PageSize: 9999, Current: 1}
+	orderParam := dto.OrderParam{Sort: "record_id"}
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{
+		PaginationParam: paginationParam,
+		OrderParam:      dto.OrderParam{Sort: "sequence"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	menuActionQR, err := s.menuActionRepository.Query(&models.MenuActionQueryParam{
+		PaginationParam: paginationParam, OrderParam: orderParam,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	menuResourceQR, err := s.menuActionResourceRepository.Query(&models.MenuActionResourceQueryParam{
+		PaginationParam: paginationParam, OrderParam: orderParam,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, menu := range menuQR.List {
+		// -1 is the default, leave it out of the file like CreateMenus expects
+		if menu.Hidden == -1 {
+			menu.Hidden = 0
+		}
+	}
+
+	mNames, err := s.GetI18nNames(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	menuQR.List.FillMenuAction(menuActionQR.List.ToMenuIDMap(), menuResourceQR.List.ToActionIDMap())
+	menuQR.List.FillI18n(mNames)
+
+	return menuQR.List.ToMenuTrees(), nil
+}
+
+// ExportMenuFile encodes ExportMenuTrees in the format of config/menu.yaml
+func (s MenuService) ExportMenuFile() ([]byte, error) {
+	menuTrees, err := s.ExportMenuTrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(menuTrees); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
 func (s MenuService) CreateMenus(parentID string, mTrees models.MenuTrees) error {
 	for _, mTree := range mTrees {
 		menu := &models.Menu{
+			Code:      mTree.Code,
 			Name:      mTree.Name,
 			Sequence:  mTree.Sequence,
 			Icon:      mTree.Icon,
 			Router:    mTree.Router,
 			Component: mTree.Component,
-			ParentID:  mTree.ParentID,
+			ParentID:  parentID, // the parent id of a menu tree is not read from the menu file
 			Status:    1,
 			Hidden:    -1,
+			I18n:      mTree.I18n,
 		}
 
 		if v := mTree.Hidden; v != 0 {
@@ -157,6 +344,82 @@ func (s MenuService) CreateMenus(parentID string, mTrees models.MenuTrees) error
 	return nil
 }
 
+// SyncMenus makes the stored menus, actions and resources match menuTrees.
+// Menus are matched by their code, so a menu renamed or moved in the file keeps its id and
+// the role menus referring to it. A stored menu without a code is matched once by its name
+// path and given the code of the file. Actions are matched by code and resources by method
+// and path. Rows missing from menuTrees are only deleted when prune is set, and nothing is
+// written when dryRun is set.
+func (s MenuService) SyncMenus(menuTrees models.MenuTrees, prune, dryRun bool) (changes models.MenuSyncChanges, err error) {
+	if !dryRun {
+		defer func() {
+			err = s.auditService.Record(models.AuditSync, models.AggregateMenu, "", nil, changes, err)
+		}()
+	}
+
+	paginationParam := dto.PaginationParam{PageSize: 9999, Current: 1}
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	menuActionQR, err := s.menuActionRepository.Query(&models.MenuActionQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	menuResourceQR, err := s.menuActionResourceRepository.Query(
+		&models.MenuActionResourceQueryParam{PaginationParam: paginationParam},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mNames, err := s.GetI18nNames(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := &menuSync{
+		service:       s,
+		dryRun:        dryRun,
+		changes:       make(models.MenuSyncChanges, 0),
+		keys:          menuQR.List.ToNamePathMap(),
+		menus:         make(map[string]*models.Menu),
+		codes:         make(map[string]*models.Menu),
+		actions:       menuActionQR.List.ToMenuIDMap(),
+		resources:     menuResourceQR.List.ToActionIDMap(),
+		names:         mNames,
+		seenMenus:     make(map[string]struct{}),
+		seenActions:   make(map[string]struct{}),
+		seenResources: make(map[string]struct{}),
+	}
+
+	for _, menu := range menuQR.List {
+		ms.menus[ms.keys[menu.ID]] = menu
+		if menu.Code != "" {
+			ms.codes[menu.Code] = menu
+		}
+	}
+
+	if err := ms.syncMenus("", nil, menuTrees); err != nil {
+		return nil, err
+	}
+
+	if prune {
+		if err := ms.prune(menuQR.List, menuActionQR.List, menuResourceQR.List); err != nil {
+			return nil, err
+		}
+	}
+
+	if !dryRun && len(ms.changes) > 0 {
+		s.menuCacheService.InvalidateAll()
+	}
+
+	return ms.changes, nil
+}
+
 func (s MenuService) CreateActions(menuID string, menuActions models.MenuActions) error {
 	for _, menuAction := range menuActions {
 		menuAction.ID = uuid.MustString()
@@ -166,6 +429,10 @@ func (s MenuService) CreateActions(menuID string, menuActions models.MenuActions
 			return err
 		}
 
+		if err := s.SaveI18n(menuID, menuAction.ID, menuAction.I18n); err != nil {
+			return err
+		}
+
 		for _, resource := range menuAction.Resources {
 			resource.ID = uuid.MustString()
 			resource.ActionID = menuAction.ID
@@ -179,22 +446,44 @@ func (s MenuService) CreateActions(menuID string, menuActions models.MenuActions
 	return nil
 }
 
-func (s MenuService) Update(id string, menu *models.Menu) error {
+func (s MenuService) Update(id string, menu *models.Menu) (err error) {
+	var oMenu *models.Menu
+	defer func() {
+		err = s.auditService.Record(models.AuditUpdate, models.AggregateMenu, id, oMenu, menu, err)
+	}()
+
 	if id == menu.ParentID {
 		return errors.MenuInvalidParent
 	}
 
 	// get old menu
-	oMenu, err := s.Get(id)
+	oMenu, err = s.Get(id)
 	if err != nil {
 		return err
-	} else if oMenu.Name != menu.Name {
+	} else if menu.I18n != nil {
+		mNames, err := s.GetI18nNames([]string{id})
+		if err != nil {
+			return err
+		}
+		oMenu.I18n = mNames[id]
+	}
+
+	if oMenu.Name != menu.Name {
 		if err = s.Check(menu); err != nil {
 			return err
 		}
 	}
 
+	// a zero version updates whatever the current version is
+	if menu.Version == 0 {
+		menu.Version = oMenu.Version
+	} else if menu.Version != oMenu.Version {
+		return errors.DatabaseVersionConflict
+	}
+
 	menu.ID = oMenu.ID
+	// the code keys the menu in the menu file, it is changed there only
+	menu.Code = oMenu.Code
 	menu.CreatedBy = oMenu.CreatedBy
 	menu.CreatedAt = oMenu.CreatedAt
 
@@ -217,11 +506,125 @@ func (s MenuService) Update(id string, menu *models.Menu) error {
 		return err
 	}
 
+	// nil leaves the translated names untouched, an empty map removes them
+	if menu.I18n != nil {
+		if err = s.SaveI18n(id, "", menu.I18n); err != nil {
+			return err
+		}
+	}
+
+	if err = s.outboxService.Emit(models.EventMenuUpdated, models.AggregateMenu, id, menu); err != nil {
+		return err
+	}
+
+	s.menuCacheService.InvalidateAll()
 	return nil
 }
 
-func (s MenuService) UpdateActions(menuId string, actions models.MenuActions) error {
-	oActions, err := s.GetMenuActions(menuId)
+// MoveMenus moves and reorders menus in one pass. Every menu below a moved menu
+// gets its parent_path recomputed, and all changed rows are written with batched updates.
+func (s MenuService) MoveMenus(moves models.MenuMoves) (err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditMove, models.AggregateMenu, "", nil, moves, err)
+	}()
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{
+		PaginationParam: dto.PaginationParam{PageSize: 9999, Current: 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	mMenus := menuQR.List.ToMap()
+	mParents := make(map[string]string)
+	for _, menu := range menuQR.List {
+		mParents[menu.ID] = menu.ParentID
+	}
+
+	mSequences := make(map[string]int)
+	for _, move := range moves.Flatten("") {
+		if _, ok := mMenus[move.ID]; !ok {
+			return errors.Wrap(errors.MenuRecordNotFound, move.ID)
+		} else if _, ok := mSequences[move.ID]; ok {
+			return errors.Wrap(errors.MenuMoveDuplicate, move.ID)
+		}
+
+		if _, ok := mMenus[move.ParentID]; move.ParentID != "" && !ok {
+			return errors.Wrap(errors.MenuInvalidParent, move.ParentID)
+		}
+
+		mParents[move.ID] = move.ParentID
+		mSequences[move.ID] = move.Sequence
+	}
+
+	// Walking up from every moved menu must reach the root
+	for id := range mSequences {
+		seen := map[string]struct{}{id: {}}
+		for pid := mParents[id]; pid != ""; pid = mParents[pid] {
+			if _, ok := seen[pid]; ok {
+				return errors.Wrap(errors.MenuMoveCycle, id)
+			}
+			seen[pid] = struct{}{}
+		}
+	}
+
+	mPaths := make(map[string]string)
+	var parentPath func(id string) string
+	parentPath = func(id string) string {
+		if path, ok := mPaths[id]; ok {
+			return path
+		}
+
+		path := ""
+		if pid := mParents[id]; pid != "" {
+			path = s.JoinParentPath(parentPath(pid), pid)
+		}
+		mPaths[id] = path
+
+		return path
+	}
+
+	var changed models.Menus
+	for _, menu := range menuQR.List {
+		nMenu := &models.Menu{
+			ID:         menu.ID,
+			ParentID:   mParents[menu.ID],
+			ParentPath: parentPath(menu.ID),
+			Sequence:   menu.Sequence,
+		}
+
+		if v := mSequences[menu.ID]; v != 0 {
+			nMenu.Sequence = v
+		}
+
+		if nMenu.ParentID != menu.ParentID || nMenu.ParentPath != menu.ParentPath || nMenu.Sequence != menu.Sequence {
+			changed = append(changed, nMenu)
+		}
+	}
+
+	if err := s.menuRepository.UpdatePositions(changed); err != nil {
+		return err
+	}
+
+	for _, menu := range changed {
+		data := &models.MenuMove{ID: menu.ID, ParentID: menu.ParentID, Sequence: menu.Sequence}
+		if err := s.outboxService.Emit(models.EventMenuMoved, models.AggregateMenu, menu.ID, data); err != nil {
+			return err
+		}
+	}
+
+	s.menuCacheService.InvalidateAll()
+	return nil
+}
+
+func (s MenuService) UpdateActions(menuId string, actions models.MenuActions) (err error) {
+	var oActions models.MenuActions
+	defer func() {
+		before, after := oActions.Audited(), actions.Audited()
+		err = s.auditService.Record(models.AuditUpdateActions, models.AggregateMenu, menuId, before, after, err)
+	}()
+
+	oActions, err = s.GetMenuActions(menuId)
 	if err != nil {
 		return err
 	}
@@ -241,6 +644,10 @@ func (s MenuService) UpdateActions(menuId string, actions models.MenuActions) er
 		if err = s.menuActionResourceRepository.DeleteByActionID(dAction.ID); err != nil {
 			return err
 		}
+
+		if err = s.menuI18nRepository.DeleteByTarget(menuId, dAction.ID); err != nil {
+			return err
+		}
 	}
 
 	oMap := oActions.ToMap()
@@ -256,6 +663,12 @@ func (s MenuService) UpdateActions(menuId string, actions models.MenuActions) er
 			}
 		}
 
+		if uAction.I18n != nil {
+			if err = s.SaveI18n(menuId, oAction.ID, uAction.I18n); err != nil {
+				return err
+			}
+		}
+
 		// compare resources to update
 		aResources, dResources := s.CompareResources(oAction.Resources, uAction.Resources)
 		for _, aResource := range aResources {
@@ -276,11 +689,22 @@ func (s MenuService) UpdateActions(menuId string, actions models.MenuActions) er
 		}
 	}
 
+	data := &models.EventMenuActionsData{ID: menuId, Actions: actions}
+	if err = s.outboxService.Emit(models.EventMenuActionsUpdated, models.AggregateMenu, menuId, data); err != nil {
+		return err
+	}
+
+	s.menuCacheService.InvalidateAll()
 	return nil
 }
 
-func (s MenuService) Delete(id string) error {
-	_, err := s.menuRepository.Get(id)
+func (s MenuService) Delete(id string) (err error) {
+	var menu *models.Menu
+	defer func() {
+		err = s.auditService.Record(models.AuditDelete, models.AggregateMenu, id, menu, nil, err)
+	}()
+
+	menu, err = s.menuRepository.Get(id)
 	if err != nil {
 		return err
 	}
@@ -290,7 +714,7 @@ func (s MenuService) Delete(id string) error {
 	})
 	if err != nil {
 		return err
-	} else if menuQR.Pagination.Total > 0 {
+	} else if len(menuQR.List) > 0 {
 		return errors.MenuNotAllowDeleteWithChild
 	}
 
@@ -302,21 +726,46 @@ func (s MenuService) Delete(id string) error {
 		return err
 	}
 
+	if err = s.menuI18nRepository.DeleteByMenuID(id); err != nil {
+		return err
+	}
+
 	if err = s.menuRepository.Delete(id); err != nil {
 		return err
 	}
 
+	if err = s.outboxService.Emit(models.EventMenuDeleted, models.AggregateMenu, id, &models.EventDeletedData{ID: id}); err != nil {
+		return err
+	}
+
+	s.menuCacheService.InvalidateAll()
 	return nil
 }
 
-func (s MenuService) UpdateStatus(id string, status int) error {
-	_, err := s.menuRepository.Get(id)
+func (s MenuService) UpdateStatus(id string, status int) (err error) {
+	var before *models.EventStatusData
+	defer func() {
+		after := &models.EventStatusData{ID: id, Status: status}
+		err = s.auditService.Record(models.AuditUpdateStatus, models.AggregateMenu, id, before, after, err)
+	}()
+
+	menu, err := s.menuRepository.Get(id)
 	if err != nil {
 		return err
 	}
+	before = &models.EventStatusData{ID: id, Status: menu.Status}
+
+	if err = s.menuRepository.UpdateStatus(id, status); err != nil {
+		return err
+	}
 
-	return s.menuRepository.UpdateStatus(id, status)
+	data := &models.EventStatusData{ID: id, Status: status}
+	if err = s.outboxService.Emit(models.EventMenuStatusChanged, models.AggregateMenu, id, data); err != nil {
+		return err
+	}
 
+	s.menuCacheService.InvalidateAll()
+	return nil
 }
 
 func (s MenuService) GetParentPath(parentID string) (string, error) {
@@ -407,17 +856,411 @@ func (s MenuService) UpdateChildParentPath(oMenu, nMenu *models.Menu) error {
 	return nil
 }
 
+// menuSync state of a single SyncMenus run
+type menuSync struct {
+	service MenuService
+	dryRun  bool
+	changes models.MenuSyncChanges
+
+	keys      map[string]string       // menu id -> name path
+	menus     map[string]*models.Menu // name path -> menu
+	codes     map[string]*models.Menu // code -> menu
+	actions   map[string]models.MenuActions
+	resources map[string]models.MenuActionResources
+	names     map[string]map[string]string // menu or action id -> locale -> name
+
+	seenMenus     map[string]struct{}
+	seenActions   map[string]struct{}
+	seenResources map[string]struct{}
+}
+
+func (ms *menuSync) record(op, kind, key string, fields ...string) {
+	ms.changes = append(ms.changes, &models.MenuSyncChange{Op: op, Kind: kind, Key: key, Fields: fields})
+}
+
+// match returns the stored menu of the menu tree at the name path key: the menu of its code,
+// or without a code the menu at key that has no code yet
+func (ms *menuSync) match(key string, mTree *models.MenuTree) (*models.Menu, bool) {
+	if mTree.Code != "" {
+		if menu, ok := ms.codes[mTree.Code]; ok {
+			return menu, true
+		}
+	}
+
+	menu, ok := ms.menus[key]
+	if !ok || menu.Code != "" {
+		return nil, false
+	}
+
+	// a menu renamed in the file may have taken the name path of another menu
+	if ms.seen(menu) {
+		return nil, false
+	}
+
+	return menu, true
+}
+
+func (ms *menuSync) seen(menu *models.Menu) bool {
+	_, ok := ms.seenMenus[menu.ID]
+	return ok
+}
+
+func (ms *menuSync) syncMenus(parentKey string, parent *models.Menu, menuTrees models.MenuTrees) error {
+	for _, mTree := range menuTrees {
+		key := mTree.Name
+		if parentKey != "" {
+			key = parentKey + "/" + key
+		}
+
+		hidden := -1
+		if v := mTree.Hidden; v != 0 {
+			hidden = v
+		}
+
+		var parentID, parentPath string
+		if parent != nil {
+			parentID = parent.ID
+			parentPath = ms.service.JoinParentPath(parent.ParentPath, parent.ID)
+		}
+
+		menu, ok := ms.match(key, mTree)
+		if ok && ms.seen(menu) {
+			return errors.Wrapf(errors.MenuCodeDuplicate, "code %s of %s", mTree.Code, key)
+		}
+
+		if !ok {
+			menu = &models.Menu{
+				ID:         uuid.MustString(),
+				Code:       mTree.Code,
+				Name:       mTree.Name,
+				Sequence:   mTree.Sequence,
+				Icon:       mTree.Icon,
+				Router:     mTree.Router,
+				Component:  mTree.Component,
+				ParentID:   parentID,
+				ParentPath: parentPath,
+				Status:     1,
+				Hidden:     hidden,
+			}
+
+			ms.record(models.MenuSyncCreate, models.MenuSyncKindMenu, key)
+			if !ms.dryRun {
+				if err := ms.service.menuRepository.Create(menu); err != nil {
+					return err
+				}
+
+				if err := ms.service.SaveI18n(menu.ID, "", mTree.I18n); err != nil {
+					return err
+				}
+
+				if err := ms.service.outboxService.Emit(models.EventMenuCreated, models.AggregateMenu, menu.ID, menu); err != nil {
+					return err
+				}
+			}
+		} else {
+			var fields []string
+			if menu.Code != mTree.Code {
+				menu.Code = mTree.Code
+				fields = append(fields, "code")
+			}
+			if menu.Name != mTree.Name {
+				menu.Name = mTree.Name
+				fields = append(fields, "name")
+			}
+			if menu.ParentID != parentID {
+				menu.ParentID = parentID
+				fields = append(fields, "parent_id")
+			}
+			if menu.ParentPath != parentPath {
+				menu.ParentPath = parentPath
+				fields = append(fields, "parent_path")
+			}
+			if menu.Icon != mTree.Icon {
+				menu.Icon = mTree.Icon
+				fields = append(fields, "icon")
+			}
+			if menu.Router != mTree.Router {
+				menu.Router = mTree.Router
+				fields = append(fields, "router")
+			}
+			if menu.Component != mTree.Component {
+				menu.Component = mTree.Component
+				fields = append(fields, "component")
+			}
+			if menu.Sequence != mTree.Sequence {
+				menu.Sequence = mTree.Sequence
+				fields = append(fields, "sequence")
+			}
+			if menu.Hidden != hidden {
+				menu.Hidden = hidden
+				fields = append(fields, "hidden")
+			}
+
+			i18nChanged := !models.EqualI18n(ms.names[menu.ID], mTree.I18n)
+			if len(fields) > 0 || i18nChanged {
+				changed := fields
+				if i18nChanged {
+					changed = append(changed, "i18n")
+				}
+
+				ms.record(models.MenuSyncUpdate, models.MenuSyncKindMenu, key, changed...)
+			}
+
+			if len(fields) > 0 && !ms.dryRun {
+				if err := ms.service.menuRepository.UpdateColumns(menu.ID, menu, fields...); err != nil {
+					return err
+				}
+			}
+
+			if i18nChanged && !ms.dryRun {
+				if err := ms.service.SaveI18n(menu.ID, "", mTree.I18n); err != nil {
+					return err
+				}
+			}
+
+			if (len(fields) > 0 || i18nChanged) && !ms.dryRun {
+				if err := ms.service.outboxService.Emit(models.EventMenuUpdated, models.AggregateMenu, menu.ID, menu); err != nil {
+					return err
+				}
+			}
+		}
+
+		ms.seenMenus[menu.ID] = struct{}{}
+
+		if err := ms.syncActions(key, menu, mTree.Actions); err != nil {
+			return err
+		}
+
+		if err := ms.syncMenus(key, menu, mTree.Children); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ms *menuSync) syncActions(menuKey string, menu *models.Menu, actions models.MenuActions) error {
+	oMap := ms.actions[menu.ID].ToMap()
+
+	for _, action := range actions {
+		key := menuKey + "#" + action.Code
+
+		oAction, ok := oMap[action.Code]
+		if !ok {
+			oAction = &models.MenuAction{
+				ID:     uuid.MustString(),
+				MenuID: menu.ID,
+				Code:   action.Code,
+				Name:   action.Name,
+			}
+
+			ms.record(models.MenuSyncCreate, models.MenuSyncKindAction, key)
+			if !ms.dryRun {
+				if err := ms.service.menuActionRepository.Create(oAction); err != nil {
+					return err
+				}
+
+				if err := ms.service.SaveI18n(menu.ID, oAction.ID, action.I18n); err != nil {
+					return err
+				}
+			}
+		} else {
+			nameChanged := oAction.Name != action.Name
+			i18nChanged := !models.EqualI18n(ms.names[oAction.ID], action.I18n)
+
+			var fields []string
+			if nameChanged {
+				oAction.Name = action.Name
+				fields = append(fields, "name")
+			}
+			if i18nChanged {
+				fields = append(fields, "i18n")
+			}
+
+			if len(fields) > 0 {
+				ms.record(models.MenuSyncUpdate, models.MenuSyncKindAction, key, fields...)
+			}
+
+			if nameChanged && !ms.dryRun {
+				if err := ms.service.menuActionRepository.Update(oAction.ID, oAction); err != nil {
+					return err
+				}
+			}
+
+			if i18nChanged && !ms.dryRun {
+				if err := ms.service.SaveI18n(menu.ID, oAction.ID, action.I18n); err != nil {
+					return err
+				}
+			}
+		}
+
+		ms.seenActions[oAction.ID] = struct{}{}
+
+		if err := ms.syncResources(key, oAction, action.Resources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ms *menuSync) syncResources(actionKey string, action *models.MenuAction, resources models.MenuActionResources) error {
+	oMap := ms.resources[action.ID].ToMap()
+
+	for _, resource := range resources {
+		if oResource, ok := oMap[resource.Method+resource.Path]; ok {
+			ms.seenResources[oResource.ID] = struct{}{}
+			continue
+		}
+
+		nResource := &models.MenuActionResource{
+			ID:       uuid.MustString(),
+			ActionID: action.ID,
+			Method:   resource.Method,
+			Path:     resource.Path,
+		}
+
+		ms.record(models.MenuSyncCreate, models.MenuSyncKindResource, actionKey+" "+resource.Method+" "+resource.Path)
+		if !ms.dryRun {
+			if err := ms.service.menuActionResourceRepository.Create(nResource); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// prune deletes the rows that were not matched by the menu trees,
+// deleted menus take their actions, resources and role menus with them
+func (ms *menuSync) prune(
+	menus models.Menus,
+	actions models.MenuActions,
+	resources models.MenuActionResources,
+) error {
+	s := ms.service
+
+	// delete children before their parents
+	pruneMenus := make(models.Menus, 0)
+	for _, menu := range menus {
+		if _, ok := ms.seenMenus[menu.ID]; !ok {
+			pruneMenus = append(pruneMenus, menu)
+		}
+	}
+
+	sort.Slice(pruneMenus, func(i, j int) bool {
+		return len(pruneMenus[i].ParentPath) > len(pruneMenus[j].ParentPath)
+	})
+
+	for _, menu := range pruneMenus {
+		ms.record(models.MenuSyncDelete, models.MenuSyncKindMenu, ms.keys[menu.ID])
+		if ms.dryRun {
+			continue
+		}
+
+		if err := s.menuActionResourceRepository.DeleteByMenuID(menu.ID); err != nil {
+			return err
+		}
+
+		if err := s.menuActionRepository.DeleteByMenuID(menu.ID); err != nil {
+			return err
+		}
+
+		if err := s.roleMenuRepository.DeleteByMenuID(menu.ID); err != nil {
+			return err
+		}
+
+		if err := s.menuI18nRepository.DeleteByMenuID(menu.ID); err != nil {
+			return err
+		}
+
+		if err := s.menuRepository.Delete(menu.ID); err != nil {
+			return err
+		}
+
+		data := &models.EventDeletedData{ID: menu.ID}
+		if err := s.outboxService.Emit(models.EventMenuDeleted, models.AggregateMenu, menu.ID, data); err != nil {
+			return err
+		}
+	}
+
+	mActions := make(map[string]*models.MenuAction)
+	for _, action := range actions {
+		mActions[action.ID] = action
+
+		if _, ok := ms.seenMenus[action.MenuID]; !ok {
+			continue
+		} else if _, ok := ms.seenActions[action.ID]; ok {
+			continue
+		}
+
+		ms.record(models.MenuSyncDelete, models.MenuSyncKindAction, ms.keys[action.MenuID]+"#"+action.Code)
+		if ms.dryRun {
+			continue
+		}
+
+		if err := s.menuActionResourceRepository.DeleteByActionID(action.ID); err != nil {
+			return err
+		}
+
+		if err := s.roleMenuRepository.DeleteByActionID(action.ID); err != nil {
+			return err
+		}
+
+		if err := s.menuI18nRepository.DeleteByTarget(action.MenuID, action.ID); err != nil {
+			return err
+		}
+
+		if err := s.menuActionRepository.Delete(action.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, resource := range resources {
+		action, ok := mActions[resource.ActionID]
+		if !ok {
+			continue
+		} else if _, ok := ms.seenActions[action.ID]; !ok {
+			continue
+		} else if _, ok := ms.seenResources[resource.ID]; ok {
+			continue
+		}
+
+		key := ms.keys[action.MenuID] + "#" + action.Code + " " + resource.Method + " " + resource.Path
+		ms.record(models.MenuSyncDelete, models.MenuSyncKindResource, key)
+		if ms.dryRun {
+			continue
+		}
+
+		if err := s.menuActionResourceRepository.Delete(resource.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // NewMenuService creates a new menu service
 func NewMenuService(
 	logger lib.Logger,
 	menuRepository repository.MenuRepository,
 	menuActionRepository repository.MenuActionRepository,
 	menuActionResourceRepository repository.MenuActionResourceRepository,
+	roleMenuRepository repository.RoleMenuRepository,
+	menuI18nRepository repository.MenuI18nRepository,
+	menuCacheService MenuCacheService,
+	outboxService OutboxService,
+	auditService AuditService,
 ) MenuService {
 	return MenuService{
 		logger:                       logger,
 		menuRepository:               menuRepository,
 		menuActionRepository:         menuActionRepository,
 		menuActionResourceRepository: menuActionResourceRepository,
+		roleMenuRepository:           roleMenuRepository,
+		menuI18nRepository:           menuI18nRepository,
+		menuCacheService:             menuCacheService,
+		outboxService:                outboxService,
+		auditService:                 auditService,
 	}
 }
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/internal/testutil"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"testing"
+)
+
+func newTestMenuService(t *testing.T) (MenuService, repository.RoleMenuRepository) {
+	t.Helper()
+
+	logger := testutil.NewLogger()
+	db := testutil.NewDatabase(t, logger)
+
+	roleMenuRepository := repository.NewRoleMenuRepository(db, logger)
+	s := NewMenuService(
+		logger,
+		repository.NewMenuRepository(db, logger),
+		repository.NewMenuActionRepository(db, logger),
+		repository.NewMenuActionResourceRepository(db, logger),
+		roleMenuRepository,
+		repository.NewMenuI18nRepository(db, logger),
+		NewMenuCacheService(logger, lib.Redis{}),
+		NewOutboxService(logger, repository.NewOutboxRepository(db, logger)),
+		NewAuditService(logger, repository.NewAuditLogRepository(db, logger)),
+	)
+
+	// the cache invalidations wait for a commit that never comes, the test runs without redis
+	ctx, _ := lib.ContextWithCommitHooks(context.Background())
+
+	return s.WithContext(ctx), roleMenuRepository.WithContext(ctx)
+}
+
+// newTestMenuTrees the menu file of a parent menu "system" with the child menu named name
+func newTestMenuTrees(name string) models.MenuTrees {
+	return models.MenuTrees{
+		{
+			Code: "system",
+			Name: "System",
+			Children: models.MenuTrees{
+				{
+					Code:    "user",
+					Name:    name,
+					Actions: models.MenuActions{{Code: "query", Name: "Query"}},
+				},
+			},
+		},
+	}
+}
+
+func getTestMenu(t *testing.T, s MenuService, code string) *models.Menu {
+	t.Helper()
+
+	qr, err := s.menuRepository.Query(&models.MenuQueryParam{Code: code})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(qr.List) != 1 {
+		t.Fatalf("%d menus of code %s, want 1", len(qr.List), code)
+	}
+
+	return qr.List[0]
+}
+
+func TestMenuServiceSyncRenameKeepsRoleMenus(t *testing.T) {
+	s, roleMenuRepository := newTestMenuService(t)
+
+	if _, err := s.SyncMenus(newTestMenuTrees("Users"), true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	menu := getTestMenu(t, s, "user")
+	if menu.ParentID != getTestMenu(t, s, "system").ID {
+		t.Fatalf("menu user created under %q", menu.ParentID)
+	}
+
+	actionQR, err := s.menuActionRepository.Query(&models.MenuActionQueryParam{MenuID: menu.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleMenu := &models.RoleMenu{ID: "role-menu", RoleID: "role", MenuID: menu.ID, ActionID: actionQR.List[0].ID}
+	if err := roleMenuRepository.Create(roleMenu); err != nil {
+		t.Fatal(err)
+	}
+
+	// a dry run plans the rename without writing it
+	plan, err := s.SyncMenus(newTestMenuTrees("Accounts"), true, true)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(plan) != 1 || plan[0].Op != models.MenuSyncUpdate || plan[0].Kind != models.MenuSyncKindMenu {
+		t.Fatalf("plan %v, want a single menu update", plan)
+	}
+
+	if got := getTestMenu(t, s, "user").Name; got != "Users" {
+		t.Errorf("dry run renamed the menu to %q", got)
+	}
+
+	if _, err := s.SyncMenus(newTestMenuTrees("Accounts"), true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := getTestMenu(t, s, "user")
+	if renamed.ID != menu.ID || renamed.Name != "Accounts" {
+		t.Errorf("renamed menu %s %q, want %s %q", renamed.ID, renamed.Name, menu.ID, "Accounts")
+	}
+
+	roleMenuQR, err := roleMenuRepository.Query(&models.RoleMenuQueryParam{RoleID: "role"})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(roleMenuQR.List) != 1 || roleMenuQR.List[0].MenuID != menu.ID {
+		t.Errorf("role menus %d after the rename, want the grant on %s", len(roleMenuQR.List), menu.ID)
+	}
+}
+
+func TestMenuServiceSyncGivesCodesByNamePath(t *testing.T) {
+	s, _ := newTestMenuService(t)
+
+	// menus stored before the codes were added
+	trees := newTestMenuTrees("Users")
+	trees[0].Code, trees[0].Children[0].Code = "", ""
+	if _, err := s.SyncMenus(trees, true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	qr, err := s.menuRepository.Query(&models.MenuQueryParam{Name: "Users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	menu := qr.List[0]
+
+	if _, err := s.SyncMenus(newTestMenuTrees("Users"), true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := getTestMenu(t, s, "user"); got.ID != menu.ID {
+		t.Errorf("menu of code user %s, want %s", got.ID, menu.ID)
+	}
+
+	// two menus of the file cannot share a code
+	trees = newTestMenuTrees("Users")
+	trees[0].Children[0].Code = "system"
+	if _, err := s.SyncMenus(trees, true, true); err == nil {
+		t.Error("duplicate menu code was accepted")
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"time"
+)
+
+// outboxRelayLeaseKey only the instance holding the lease relays, which keeps the events in order
+const outboxRelayLeaseKey = "outbox-relay"
+
+// OutboxRelay publishes the outbox events to the event sinks, at least once and in the order
+// they were written. An event that fails is retried after a backoff by the sinks that failed,
+// and holds back the later events of its aggregate until it is published or dead.
+type OutboxRelay struct {
+	config           *lib.OutboxConfig
+	logger           lib.Logger
+	outboxRepository repository.OutboxRepository
+	sinks            []EventSink
+	loop             leasedLoop
+}
+
+// OutboxRelayParams the dependencies of the relay, Sinks are those provided with AsEventSink
+type OutboxRelayParams struct {
+	fx.In
+
+	Config           lib.Config
+	Logger           lib.Logger
+	Redis            lib.Redis
+	OutboxRepository repository.OutboxRepository
+	Sinks            []EventSink `group:"event_sinks"`
+}
+
+// Start relays the events in the background until Stop
+func (r OutboxRelay) Start() {
+	if !r.config.Enable || len(r.sinks) == 0 {
+		r.loop.skip()
+		return
+	}
+
+	// a full batch without failures is followed by the next one right away
+	r.loop.start(r.relay)
+}
+
+// Stop ends the relay, waiting for the event being published at most until ctx is done
+func (r OutboxRelay) Stop(ctx context.Context) error {
+	return r.loop.stop(ctx)
+}
+
+// relay publishes a batch of due events, it reports whether more events are waiting
+func (r OutboxRelay) relay(ctx context.Context) bool {
+	now := time.Now()
+	events, err := r.outboxRepository.QueryPending(now, r.config.BatchSize)
+	if err != nil {
+		r.logger.Zap.Errorf("Outbox read error: %v", err)
+		return false
+	}
+
+	// the aggregates of an event waiting for its next attempt, by the id of the event
+	waiting, err := r.outboxRepository.QueryWaiting(now)
+	if err != nil {
+		r.logger.Zap.Errorf("Outbox read error: %v", err)
+		return false
+	}
+
+	blocked := make(map[string]struct{})
+	for _, event := range events {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		key := event.AggregateKey()
+		if _, ok := blocked[key]; ok {
+			continue
+		} else if id, ok := waiting[key]; ok && id < event.ID {
+			continue
+		}
+
+		// the lease is extended at every event, a slow sink must not let another instance relay too
+		if !r.loop.lease() {
+			return false
+		}
+
+		event.Attempts++
+		if err := r.publish(ctx, event); err != nil {
+			blocked[key] = struct{}{}
+			r.fail(event, err)
+			continue
+		}
+
+		publishedAt := time.Now()
+		event.PublishedAt = &publishedAt
+		event.NextAttemptAt = nil
+		event.LastError = ""
+		if err := r.outboxRepository.MarkPublished(event); err != nil {
+			r.logger.Zap.Errorf("Outbox event %s update error: %v", event.EventID, err)
+			return false
+		}
+	}
+
+	return len(events) == r.config.BatchSize && len(blocked) == 0
+}
+
+// publish hands the event to the sinks that have not published it yet, recording those that do
+func (r OutboxRelay) publish(ctx context.Context, event *models.OutboxEvent) error {
+	domainEvent := event.DomainEvent()
+	for _, sink := range r.sinks {
+		if event.PublishedTo(sink.Name()) {
+			continue
+		}
+
+		if err := sink.Publish(ctx, domainEvent); err != nil {
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+		event.PublishedSinks = append(event.PublishedSinks, sink.Name())
+	}
+
+	return nil
+}
+
+// fail writes the failed attempt of the event, retried after the backoff or dead after the last attempt
+func (r OutboxRelay) fail(event *models.OutboxEvent, err error) {
+	now := time.Now()
+	event.LastError = err.Error()
+
+	if event.Attempts >= r.config.MaxAttempts {
+		event.NextAttemptAt = nil
+		event.DeadAt = &now
+		r.logger.Zap.Errorf("Outbox event %s %s is dead after %d attempts: %v", event.EventID, event.Type, event.Attempts, err)
+	} else {
+		next := now.Add(r.config.Backoff(event.Attempts))
+		event.NextAttemptAt = &next
+		r.logger.Zap.Warnf("Outbox event %s %s publish error (attempt %d): %v", event.EventID, event.Type, event.Attempts, err)
+	}
+
+	if err := r.outboxRepository.MarkFailed(event); err != nil {
+		r.logger.Zap.Errorf("Outbox event %s update error: %v", event.EventID, err)
+	}
+}
+
+// NewOutboxCleanupCronJob creates the cron job deleting the events published before the retention
+func NewOutboxCleanupCronJob(
+	config lib.Config,
+	logger lib.Logger,
+	outboxRepository repository.OutboxRepository,
+) CronJob {
+	return NewCronJob("outbox-cleanup", "0 * * * *", func(ctx context.Context) error {
+		before := time.Now().Add(-time.Duration(config.Outbox.Retention) * time.Hour)
+
+		count, err := outboxRepository.WithContext(ctx).DeletePublished(before)
+		if err != nil {
+			return err
+		} else if count > 0 {
+			logger.Zap.Infof("Outbox cleanup deleted %d events", count)
+		}
+
+		return nil
+	})
+}
+
+// NewOutboxRelay creates a new outbox relay publishing to the configured sinks and to the
+// sinks provided with AsEventSink
+func NewOutboxRelay(params OutboxRelayParams) OutboxRelay {
+	config := params.Config.Outbox
+
+	var sinks []EventSink
+	for _, name := range config.Sinks {
+		switch name {
+		case lib.OutboxSinkLog:
+			sinks = append(sinks, NewLogEventSink(params.Logger))
+		case lib.OutboxSinkRedis:
+			sinks = append(sinks, NewRedisStreamEventSink(params.Config, params.Redis))
+		case lib.OutboxSinkHttp:
+			sinks = append(sinks, NewHttpEventSink(params.Config))
+		default:
+			params.Logger.Zap.Warnf("Outbox sink %q is unknown, allowed sinks %s, %s, %s",
+				name, lib.OutboxSinkLog, lib.OutboxSinkRedis, lib.OutboxSinkHttp)
+		}
+	}
+	sinks = append(sinks, params.Sinks...)
+
+	return OutboxRelay{
+		config:           config,
+		logger:           params.Logger,
+		outboxRepository: params.OutboxRepository,
+		sinks:            sinks,
+		loop: newLeasedLoop(params.Redis, params.Logger, outboxRelayLeaseKey,
+			time.Duration(config.Interval)*time.Millisecond),
+	}
+}
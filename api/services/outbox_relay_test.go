@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/internal/testutil"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEventSink the test sink, its first publishes fail while failures is positive
+type testEventSink struct {
+	name     string
+	mu       sync.Mutex
+	failures int
+	events   []string
+}
+
+func (s *testEventSink) Name() string {
+	return s.name
+}
+
+func (s *testEventSink) Publish(ctx context.Context, event *models.DomainEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event.ID)
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+
+	return nil
+}
+
+func newTestOutboxRelay(t *testing.T, config *lib.OutboxConfig, sinks ...EventSink) (OutboxRelay, OutboxService, lib.Database, lib.Redis) {
+	t.Helper()
+
+	logger := testutil.NewLogger()
+	db := testutil.NewDatabase(t, logger)
+	_, redisConfig := testutil.NewRedis(t)
+
+	redis := lib.NewRedis(lib.Config{Redis: redisConfig}, logger)
+	t.Cleanup(func() { _ = redis.Close() })
+
+	outboxRepository := repository.NewOutboxRepository(db, logger)
+	relay := NewOutboxRelay(OutboxRelayParams{
+		Config:           lib.Config{Outbox: config},
+		Logger:           logger,
+		Redis:            redis,
+		OutboxRepository: outboxRepository,
+		Sinks:            sinks,
+	})
+
+	return relay, NewOutboxService(logger, outboxRepository), db, redis
+}
+
+func queryTestOutboxEvents(t *testing.T, db lib.Database) models.OutboxEvents {
+	t.Helper()
+
+	var events models.OutboxEvents
+	if err := db.ORM.Order("id ASC").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
+
+func TestOutboxRelayRetriesFailedSinks(t *testing.T) {
+	stable := &testEventSink{name: "stable"}
+	flaky := &testEventSink{name: "flaky", failures: 1}
+
+	// no backoff, the failed events are due again right away
+	r, s, db, _ := newTestOutboxRelay(t, &lib.OutboxConfig{Enable: true, BatchSize: 10, MaxAttempts: 3}, stable, flaky)
+	if err := s.Emit(models.EventUserStatusChanged, models.AggregateUser, "user-id", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r.relay(context.Background())
+
+	event := queryTestOutboxEvents(t, db)[0]
+	if event.PublishedAt != nil || event.Attempts != 1 || event.LastError == "" || event.NextAttemptAt == nil {
+		t.Fatalf("event published at %v after %d attempts, error %q, next attempt %v",
+			event.PublishedAt, event.Attempts, event.LastError, event.NextAttemptAt)
+	}
+
+	if !event.PublishedTo(stable.name) || event.PublishedTo(flaky.name) {
+		t.Errorf("event published to %v, want %s", event.PublishedSinks, stable.name)
+	}
+
+	r.relay(context.Background())
+
+	event = queryTestOutboxEvents(t, db)[0]
+	if event.PublishedAt == nil || event.Attempts != 2 || event.LastError != "" || event.NextAttemptAt != nil {
+		t.Errorf("event published at %v after %d attempts, error %q, next attempt %v",
+			event.PublishedAt, event.Attempts, event.LastError, event.NextAttemptAt)
+	}
+
+	// the sink that published the event is not retried
+	if len(stable.events) != 1 || len(flaky.events) != 2 {
+		t.Errorf("sinks received %d and %d events, want 1 and 2", len(stable.events), len(flaky.events))
+	}
+}
+
+func TestOutboxRelayBacksOffInAggregateOrder(t *testing.T) {
+	sink := &testEventSink{name: "test", failures: 1}
+
+	r, s, db, _ := newTestOutboxRelay(t, &lib.OutboxConfig{
+		Enable: true, BatchSize: 10, MaxAttempts: 3, BackoffBase: 60, BackoffMax: 300,
+	}, sink)
+	for _, id := range []string{"user-1", "user-1", "user-2"} {
+		if err := s.Emit(models.EventUserStatusChanged, models.AggregateUser, id, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		r.relay(context.Background())
+	}
+
+	events := queryTestOutboxEvents(t, db)
+	if events[0].NextAttemptAt == nil || time.Until(*events[0].NextAttemptAt) < 55*time.Second {
+		t.Fatalf("failed event next attempt %v, want in 60s", events[0].NextAttemptAt)
+	}
+
+	// the later event of the failed aggregate waits, the other aggregate goes on
+	if events[1].PublishedAt != nil || events[1].Attempts != 0 || events[2].PublishedAt == nil {
+		t.Errorf("events published at %v after %d attempts and at %v, want the second only waiting",
+			events[1].PublishedAt, events[1].Attempts, events[2].PublishedAt)
+	}
+
+	if len(sink.events) != 2 {
+		t.Errorf("sink received %d events, want 2", len(sink.events))
+	}
+}
+
+func TestOutboxRelayDeadAfterMaxAttempts(t *testing.T) {
+	sink := &testEventSink{name: "test", failures: 3}
+
+	r, s, db, _ := newTestOutboxRelay(t, &lib.OutboxConfig{Enable: true, BatchSize: 10, MaxAttempts: 2}, sink)
+	if err := s.Emit(models.EventUserStatusChanged, models.AggregateUser, "user-id", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		r.relay(context.Background())
+	}
+
+	event := queryTestOutboxEvents(t, db)[0]
+	if event.DeadAt == nil || event.Attempts != 2 || event.NextAttemptAt != nil || event.PublishedAt != nil {
+		t.Errorf("event dead at %v after %d attempts, next attempt %v, published at %v",
+			event.DeadAt, event.Attempts, event.NextAttemptAt, event.PublishedAt)
+	}
+
+	if len(sink.events) != 2 {
+		t.Errorf("sink received %d events, want 2", len(sink.events))
+	}
+}
+
+func TestOutboxRelayStopsWithoutTheLease(t *testing.T) {
+	sink := &testEventSink{name: "test"}
+
+	r, s, db, redis := newTestOutboxRelay(t, &lib.OutboxConfig{Enable: true, BatchSize: 10, MaxAttempts: 3}, sink)
+	if err := s.Emit(models.EventUserStatusChanged, models.AggregateUser, "user-id", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// another instance relays meanwhile
+	if ok, err := redis.Lease(outboxRelayLeaseKey, "other", time.Minute); err != nil || !ok {
+		t.Fatalf("lease taken %v: %v", ok, err)
+	}
+
+	if more := r.relay(context.Background()); more {
+		t.Error("relay without the lease reported more events")
+	}
+
+	if event := queryTestOutboxEvents(t, db)[0]; len(sink.events) != 0 || event.Attempts != 0 {
+		t.Errorf("sink received %d events, event attempted %d times, want none", len(sink.events), event.Attempts)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/uuid"
+)
+
+// OutboxService records the domain events in the outbox. Bound to the context of a
+// transaction, the events are committed or rolled back with the change they describe.
+type OutboxService struct {
+	logger           lib.Logger
+	outboxRepository repository.OutboxRepository
+}
+
+// WithContext binds the repository to ctx, so that the events join the transaction carried by ctx
+func (s OutboxService) WithContext(ctx context.Context) OutboxService {
+	s.logger = s.logger.WithContext(ctx)
+	s.outboxRepository = s.outboxRepository.WithContext(ctx)
+	return s
+}
+
+// Emit writes the event of the aggregate, data is the JSON payload of the event
+func (s OutboxService) Emit(eventType, aggregateType, aggregateID string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrapf(errors.DatabaseInternalError, "event %s payload: %v", eventType, err)
+	}
+
+	return s.outboxRepository.Create(&models.OutboxEvent{
+		EventID:       uuid.MustString(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       string(payload),
+	})
+}
+
+// NewOutboxService creates a new outbox service
+func NewOutboxService(logger lib.Logger, outboxRepository repository.OutboxRepository) OutboxService {
+	return OutboxService{
+		logger:           logger,
+		outboxRepository: outboxRepository,
+	}
+}
@@ -0,0 +1,478 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/uuid"
+	"sort"
+)
+
+// RbacService exports and imports role definitions between environments
+type RbacService struct {
+	logger               lib.Logger
+	casbinService        CasbinService
+	menuCacheService     MenuCacheService
+	userRepository       repository.UserRepository
+	userRoleRepository   repository.UserRoleRepository
+	roleRepository       repository.RoleRepository
+	roleMenuRepository   repository.RoleMenuRepository
+	menuRepository       repository.MenuRepository
+	menuActionRepository repository.MenuActionRepository
+	auditService         AuditService
+}
+
+// WithContext binds the repositories to ctx, so that they join the transaction carried by ctx
+func (s RbacService) WithContext(ctx context.Context) RbacService {
+	s.logger = s.logger.WithContext(ctx)
+	s.menuCacheService = s.menuCacheService.WithContext(ctx)
+	s.userRepository = s.userRepository.WithContext(ctx)
+	s.userRoleRepository = s.userRoleRepository.WithContext(ctx)
+	s.roleRepository = s.roleRepository.WithContext(ctx)
+	s.roleMenuRepository = s.roleMenuRepository.WithContext(ctx)
+	s.menuRepository = s.menuRepository.WithContext(ctx)
+	s.menuActionRepository = s.menuActionRepository.WithContext(ctx)
+	s.auditService = s.auditService.WithContext(ctx)
+
+	return s
+}
+
+// Export builds a bundle of all roles, and of the user roles when includeUsers is set
+func (s RbacService) Export(includeUsers bool) (*models.RbacBundle, error) {
+	paginationParam := dto.PaginationParam{PageSize: 9999, Current: 1}
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	menuActionQR, err := s.menuActionRepository.Query(&models.MenuActionQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	roleQR, err := s.roleRepository.Query(&models.RoleQueryParam{
+		PaginationParam: paginationParam,
+		OrderParam:      dto.OrderParam{Sort: "sequence"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roleMenuQR, err := s.roleMenuRepository.Query(&models.RoleMenuQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	mKeys := menuQR.List.ToNamePathMap()
+	mActions := make(map[string]*models.MenuAction)
+	for _, action := range menuActionQR.List {
+		mActions[action.ID] = action
+	}
+
+	bundle := &models.RbacBundle{
+		Version: models.RbacBundleVersion,
+		Roles:   make([]*models.RbacRole, 0, len(roleQR.List)),
+	}
+
+	mRoleMenus := roleMenuQR.List.ToRoleIDMap()
+	for _, role := range roleQR.List {
+		mMenuActions := make(map[string][]string)
+		for _, roleMenu := range mRoleMenus[role.ID] {
+			key, ok := mKeys[roleMenu.MenuID]
+			if !ok {
+				continue
+			}
+
+			action, ok := mActions[roleMenu.ActionID]
+			if !ok {
+				continue
+			}
+
+			mMenuActions[key] = append(mMenuActions[key], action.Code)
+		}
+
+		rbacRole := &models.RbacRole{
+			Name:     role.Name,
+			Remark:   role.Remark,
+			Sequence: role.Sequence,
+			Status:   role.Status,
+			Menus:    make([]*models.RbacRoleMenu, 0, len(mMenuActions)),
+		}
+
+		for key, codes := range mMenuActions {
+			sort.Strings(codes)
+			rbacRole.Menus = append(rbacRole.Menus, &models.RbacRoleMenu{Menu: key, Actions: codes})
+		}
+
+		sort.Slice(rbacRole.Menus, func(i, j int) bool {
+			return rbacRole.Menus[i].Menu < rbacRole.Menus[j].Menu
+		})
+
+		bundle.Roles = append(bundle.Roles, rbacRole)
+	}
+
+	if !includeUsers {
+		return bundle, nil
+	}
+
+	userQR, err := s.userRepository.Query(&models.UserQueryParam{
+		PaginationParam: paginationParam,
+		OrderParam:      dto.OrderParam{Sort: "username"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userRoleQR, err := s.userRoleRepository.Query(&models.UserRoleQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	mRoles := roleQR.List.ToMap()
+	mUserRoles := userRoleQR.List.ToUserIDMap()
+	for _, user := range userQR.List {
+		var names []string
+		for _, userRole := range mUserRoles[user.ID] {
+			if role, ok := mRoles[userRole.RoleID]; ok {
+				names = append(names, role.Name)
+			}
+		}
+
+		if len(names) == 0 {
+			continue
+		}
+
+		sort.Strings(names)
+		bundle.Users = append(bundle.Users, &models.RbacUser{Username: user.Username, Roles: names})
+	}
+
+	return bundle, nil
+}
+
+// Encode encodes the bundle as json or yaml
+func (s RbacService) Encode(bundle *models.RbacBundle, format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(bundle, "", "  ")
+	}
+
+	return yaml.Marshal(bundle)
+}
+
+// Decode decodes a json or yaml bundle, json being a subset of yaml
+func (s RbacService) Decode(data []byte) (*models.RbacBundle, error) {
+	bundle := new(models.RbacBundle)
+	if err := yaml.Unmarshal(data, bundle); err != nil {
+		return nil, errors.Wrap(errors.RbacBundleDecodeError, err.Error())
+	}
+
+	if bundle.Version != models.RbacBundleVersion {
+		return nil, errors.RbacBundleVersionInvalid
+	}
+
+	return bundle, nil
+}
+
+// Import creates or updates the roles and user roles of the bundle.
+// Existing roles (by name) and users holding other roles are conflicts resolved by param.Strategy,
+// nothing is written when param.DryRun is set.
+func (s RbacService) Import(bundle *models.RbacBundle, param *models.RbacImportParam) (result *models.RbacImportResult, err error) {
+	if !param.DryRun {
+		defer func() {
+			// the error of the import wins over that of its audit log
+			if rerr := s.auditService.Record(models.AuditImport, models.AuditResourceRbac, "", nil, result, err); err == nil {
+				err = rerr
+			}
+		}()
+	}
+
+	paginationParam := dto.PaginationParam{PageSize: 9999, Current: 1}
+
+	strategy := param.Strategy
+	if strategy == "" {
+		strategy = models.RbacImportSkip
+	} else if strategy != models.RbacImportSkip && strategy != models.RbacImportOverwrite && strategy != models.RbacImportFail {
+		return nil, errors.Wrap(errors.RbacImportStrategyInvalid, strategy)
+	}
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	menuActionQR, err := s.menuActionRepository.Query(&models.MenuActionQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	roleQR, err := s.roleRepository.Query(&models.RoleQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	mMenus := make(map[string]*models.Menu)
+	mMenuIDs := menuQR.List.ToMap()
+	for id, key := range menuQR.List.ToNamePathMap() {
+		mMenus[key] = mMenuIDs[id]
+	}
+
+	mMenuActions := menuActionQR.List.ToMenuIDMap()
+
+	mRoles := make(map[string]*models.Role)
+	for _, role := range roleQR.List {
+		mRoles[role.Name] = role
+	}
+
+	result = &models.RbacImportResult{DryRun: param.DryRun, Changes: make([]*models.RbacImportChange, 0)}
+	record := func(op, kind, key string) {
+		result.Changes = append(result.Changes, &models.RbacImportChange{Op: op, Kind: kind, Key: key})
+	}
+
+	for _, rbacRole := range bundle.Roles {
+		roleMenus := make(models.RoleMenus, 0)
+		for _, rbacRoleMenu := range rbacRole.Menus {
+			menu, ok := mMenus[rbacRoleMenu.Menu]
+			if !ok {
+				return nil, errors.Wrap(errors.RbacMenuNotFound, rbacRoleMenu.Menu)
+			}
+
+			mActions := mMenuActions[menu.ID].ToMap()
+			for _, code := range rbacRoleMenu.Actions {
+				action, ok := mActions[code]
+				if !ok {
+					return nil, errors.Wrap(errors.RbacActionNotFound, rbacRoleMenu.Menu+"#"+code)
+				}
+
+				roleMenus = append(roleMenus, &models.RoleMenu{MenuID: menu.ID, ActionID: action.ID})
+			}
+		}
+
+		role, ok := mRoles[rbacRole.Name]
+		if !ok {
+			role = &models.Role{
+				ID:        uuid.MustString(),
+				Name:      rbacRole.Name,
+				Remark:    rbacRole.Remark,
+				Sequence:  rbacRole.Sequence,
+				Status:    rbacRole.Status,
+				CreatedBy: param.Operator,
+			}
+			mRoles[role.Name] = role
+
+			record(models.RbacImportCreate, models.RbacKindRole, role.Name)
+			if param.DryRun {
+				continue
+			}
+
+			if err := s.roleRepository.Create(role); err != nil {
+				return nil, err
+			}
+
+			if err := s.createRoleMenus(role.ID, roleMenus); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		switch strategy {
+		case models.RbacImportFail:
+			return nil, errors.Wrap(errors.RbacRoleConflict, role.Name)
+		case models.RbacImportSkip:
+			record(models.RbacImportSkip, models.RbacKindRole, role.Name)
+			continue
+		}
+
+		record(models.RbacImportOverwrite, models.RbacKindRole, role.Name)
+		if param.DryRun {
+			continue
+		}
+
+		role.Remark = rbacRole.Remark
+		role.Sequence = rbacRole.Sequence
+		role.Status = rbacRole.Status
+		if err := s.roleRepository.Update(role.ID, role); err != nil {
+			return nil, err
+		}
+
+		if err := s.replaceRoleMenus(role.ID, roleMenus); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rbacUser := range bundle.Users {
+		if err := s.importUser(rbacUser, mRoles, strategy, param.DryRun, record); err != nil {
+			return nil, err
+		}
+	}
+
+	if !param.DryRun {
+		_ = s.casbinService.Enforcer.LoadPolicy()
+		s.menuCacheService.InvalidateAll()
+	}
+
+	return result, nil
+}
+
+func (s RbacService) importUser(
+	rbacUser *models.RbacUser,
+	mRoles map[string]*models.Role,
+	strategy string,
+	dryRun bool,
+	record func(op, kind, key string),
+) error {
+	paginationParam := dto.PaginationParam{PageSize: 9999, Current: 1}
+
+	userQR, err := s.userRepository.Query(&models.UserQueryParam{PaginationParam: paginationParam, Username: rbacUser.Username})
+	if err != nil {
+		return err
+	} else if len(userQR.List) == 0 {
+		return errors.Wrap(errors.RbacUserNotFound, rbacUser.Username)
+	}
+
+	user := userQR.List[0]
+
+	roleIDs := make(map[string]struct{})
+	for _, name := range rbacUser.Roles {
+		role, ok := mRoles[name]
+		if !ok {
+			return errors.Wrap(errors.RbacRoleNotFound, name)
+		}
+
+		roleIDs[role.ID] = struct{}{}
+	}
+
+	userRoleQR, err := s.userRoleRepository.Query(&models.UserRoleQueryParam{PaginationParam: paginationParam, UserID: user.ID})
+	if err != nil {
+		return err
+	}
+
+	oUserRoles := make(map[string]models.UserRole)
+	for _, userRole := range userRoleQR.List {
+		oUserRoles[userRole.RoleID] = userRole
+	}
+
+	same := len(oUserRoles) == len(roleIDs)
+	for roleID := range roleIDs {
+		if _, ok := oUserRoles[roleID]; !ok {
+			same = false
+		}
+	}
+
+	switch {
+	case same:
+		return nil
+	case len(oUserRoles) == 0:
+		record(models.RbacImportCreate, models.RbacKindUser, user.Username)
+	case strategy == models.RbacImportFail:
+		return errors.Wrap(errors.RbacUserConflict, user.Username)
+	case strategy == models.RbacImportSkip:
+		record(models.RbacImportSkip, models.RbacKindUser, user.Username)
+		return nil
+	default:
+		record(models.RbacImportOverwrite, models.RbacKindUser, user.Username)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	for roleID, userRole := range oUserRoles {
+		if _, ok := roleIDs[roleID]; ok {
+			continue
+		}
+
+		if err := s.userRoleRepository.Delete(userRole.ID); err != nil {
+			return err
+		}
+	}
+
+	for roleID := range roleIDs {
+		if _, ok := oUserRoles[roleID]; ok {
+			continue
+		}
+
+		userRole := &models.UserRole{ID: uuid.MustString(), UserID: user.ID, RoleID: roleID}
+		if err := s.userRoleRepository.Create(userRole); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s RbacService) createRoleMenus(roleID string, roleMenus models.RoleMenus) error {
+	for _, roleMenu := range roleMenus {
+		roleMenu.ID = uuid.MustString()
+		roleMenu.RoleID = roleID
+
+		if err := s.roleMenuRepository.Create(roleMenu); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s RbacService) replaceRoleMenus(roleID string, roleMenus models.RoleMenus) error {
+	roleMenuQR, err := s.roleMenuRepository.Query(&models.RoleMenuQueryParam{
+		PaginationParam: dto.PaginationParam{PageSize: 9999, Current: 1},
+		RoleID:          roleID,
+	})
+	if err != nil {
+		return err
+	}
+
+	oMap := roleMenuQR.List.ToMap()
+	nMap := roleMenus.ToMap()
+
+	var aList models.RoleMenus
+	for k, roleMenu := range nMap {
+		if _, ok := oMap[k]; ok {
+			delete(oMap, k)
+			continue
+		}
+
+		aList = append(aList, roleMenu)
+	}
+
+	for _, roleMenu := range oMap {
+		if err := s.roleMenuRepository.Delete(roleMenu.ID); err != nil {
+			return err
+		}
+	}
+
+	return s.createRoleMenus(roleID, aList)
+}
+
+// NewRbacService creates a new rbac service
+func NewRbacService(
+	logger lib.Logger,
+	casbinService CasbinService,
+	menuCacheService MenuCacheService,
+	userRepository repository.UserRepository,
+	userRoleRepository repository.UserRoleRepository,
+	roleRepository repository.RoleRepository,
+	roleMenuRepository repository.RoleMenuRepository,
+	menuRepository repository.MenuRepository,
+	menuActionRepository repository.MenuActionRepository,
+	auditService AuditService,
+) RbacService {
+	return RbacService{
+		logger:               logger,
+		casbinService:        casbinService,
+		menuCacheService:     menuCacheService,
+		userRepository:       userRepository,
+		userRoleRepository:   userRoleRepository,
+		roleRepository:       roleRepository,
+		roleMenuRepository:   roleMenuRepository,
+		menuRepository:       menuRepository,
+		menuActionRepository: menuActionRepository,
+		auditService:         auditService,
+	}
+}
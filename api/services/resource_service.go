@@ -0,0 +1,207 @@
+package services
+
+import (
+	"github.com/casbin/casbin/v2/util"
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ResourceService checks menu action resources against the registered routes
+type ResourceService struct {
+	logger                       lib.Logger
+	config                       lib.Config
+	handler                      lib.HttpHandler
+	menuRepository               repository.MenuRepository
+	menuActionRepository         repository.MenuActionRepository
+	menuActionResourceRepository repository.MenuActionResourceRepository
+}
+
+// Check compares the routes registered on the engine with the stored resources
+// and the resources of menuTrees (usually read from the menu file).
+func (s ResourceService) Check(menuTrees models.MenuTrees) (*dto.ResourceCheckReport, error) {
+	items, err := s.storedResources()
+	if err != nil {
+		return nil, err
+	}
+
+	items = append(items, s.treeResources("", menuTrees)...)
+
+	var routes []*echo.Route
+	for _, route := range s.handler.Engine.Routes() {
+		// Only interfaces starting with /api/ are protected by menu actions
+		if strings.HasPrefix(route.Path, "/api/") {
+			routes = append(routes, route)
+		}
+	}
+
+	report := &dto.ResourceCheckReport{
+		UnknownResources: make([]*dto.ResourceCheckItem, 0),
+		UncoveredRoutes:  make([]*dto.ResourceCheckRoute, 0),
+		ShadowedRoutes:   make([]*dto.ResourceCheckRoute, 0),
+	}
+
+	for _, item := range items {
+		known := false
+		for _, route := range routes {
+			if resourceMatchRoute(item.Method, item.Path, route) {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			report.UnknownResources = append(report.UnknownResources, item)
+		}
+	}
+
+	mRoutes := make(map[string]struct{})
+	for _, route := range routes {
+		if _, ok := mRoutes[route.Method+route.Path]; ok {
+			continue
+		}
+		mRoutes[route.Method+route.Path] = struct{}{}
+
+		covered := false
+		for _, item := range items {
+			if resourceMatchRoute(item.Method, item.Path, route) {
+				covered = true
+				break
+			}
+		}
+
+		prefix, shadowed := ignorePrefix(route.Path, s.config.Casbin.IgnorePathPrefixes)
+		switch {
+		case shadowed && covered:
+			report.ShadowedRoutes = append(report.ShadowedRoutes, &dto.ResourceCheckRoute{
+				Method: route.Method, Path: route.Path, Prefix: prefix,
+			})
+		case !shadowed && !covered:
+			report.UncoveredRoutes = append(report.UncoveredRoutes, &dto.ResourceCheckRoute{
+				Method: route.Method, Path: route.Path,
+			})
+		}
+	}
+
+	sort.Slice(report.UncoveredRoutes, func(i, j int) bool {
+		return report.UncoveredRoutes[i].Path < report.UncoveredRoutes[j].Path
+	})
+
+	return report, nil
+}
+
+func (s ResourceService) storedResources() ([]*dto.ResourceCheckItem, error) {
+	paginationParam := dto.PaginationParam{PageSize: 9999, Current: 1}
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	menuActionQR, err := s.menuActionRepository.Query(&models.MenuActionQueryParam{PaginationParam: paginationParam})
+	if err != nil {
+		return nil, err
+	}
+
+	menuResourceQR, err := s.menuActionResourceRepository.Query(
+		&models.MenuActionResourceQueryParam{PaginationParam: paginationParam},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mNames := menuQR.List.ToNamePathMap()
+	mActions := make(map[string]*models.MenuAction)
+	for _, action := range menuActionQR.List {
+		mActions[action.ID] = action
+	}
+
+	items := make([]*dto.ResourceCheckItem, 0, len(menuResourceQR.List))
+	for _, resource := range menuResourceQR.List {
+		item := &dto.ResourceCheckItem{
+			Source: dto.ResourceSourceDatabase,
+			Method: resource.Method,
+			Path:   resource.Path,
+		}
+
+		if action, ok := mActions[resource.ActionID]; ok {
+			item.Menu = mNames[action.MenuID]
+			item.Action = action.Code
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (s ResourceService) treeResources(parent string, menuTrees models.MenuTrees) []*dto.ResourceCheckItem {
+	var items []*dto.ResourceCheckItem
+
+	for _, menuTree := range menuTrees {
+		name := menuTree.Name
+		if parent != "" {
+			name = parent + "/" + name
+		}
+
+		for _, action := range menuTree.Actions {
+			for _, resource := range action.Resources {
+				items = append(items, &dto.ResourceCheckItem{
+					Source: dto.ResourceSourceYAML,
+					Menu:   name,
+					Action: action.Code,
+					Method: resource.Method,
+					Path:   resource.Path,
+				})
+			}
+		}
+
+		items = append(items, s.treeResources(name, menuTree.Children)...)
+	}
+
+	return items
+}
+
+// resourceMatchRoute reports whether casbin would grant the route to a policy
+// built from the resource, see the matchers in casbin_model.conf
+func resourceMatchRoute(method, path string, route *echo.Route) bool {
+	if ok, err := regexp.MatchString(method, route.Method); err != nil || !ok {
+		return false
+	}
+
+	return path == route.Path || util.KeyMatch2(route.Path, path)
+}
+
+func ignorePrefix(path string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// NewResourceService creates a new resource service
+func NewResourceService(
+	logger lib.Logger,
+	config lib.Config,
+	handler lib.HttpHandler,
+	menuRepository repository.MenuRepository,
+	menuActionRepository repository.MenuActionRepository,
+	menuActionResourceRepository repository.MenuActionResourceRepository,
+) ResourceService {
+	return ResourceService{
+		logger:                       logger,
+		config:                       config,
+		handler:                      handler,
+		menuRepository:               menuRepository,
+		menuActionRepository:         menuActionRepository,
+		menuActionResourceRepository: menuActionResourceRepository,
+	}
+}
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"gorm.io/gorm"
+	"context"
 	"manuel71sj/go-api-template/api/repository"
 	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
@@ -13,18 +13,27 @@ import (
 type RoleService struct {
 	logger               lib.Logger
 	casbinService        CasbinService
+	menuCacheService     MenuCacheService
 	userRepository       repository.UserRepository
 	roleRepository       repository.RoleRepository
 	roleMenuRepository   repository.RoleMenuRepository
 	menuRepository       repository.MenuRepository
 	menuActionRepository repository.MenuActionRepository
+	outboxService        OutboxService
+	auditService         AuditService
 }
 
-// WithTrx delegates transaction to repository database
-func (s RoleService) WithTrx(trxHandle *gorm.DB) RoleService {
-	s.roleRepository = s.roleRepository.WithTrx(trxHandle)
-	s.userRepository = s.userRepository.WithTrx(trxHandle)
-	s.roleMenuRepository = s.roleMenuRepository.WithTrx(trxHandle)
+// WithContext binds the repositories to ctx, so that they join the transaction carried by ctx
+func (s RoleService) WithContext(ctx context.Context) RoleService {
+	s.logger = s.logger.WithContext(ctx)
+	s.menuCacheService = s.menuCacheService.WithContext(ctx)
+	s.roleRepository = s.roleRepository.WithContext(ctx)
+	s.userRepository = s.userRepository.WithContext(ctx)
+	s.roleMenuRepository = s.roleMenuRepository.WithContext(ctx)
+	s.menuRepository = s.menuRepository.WithContext(ctx)
+	s.menuActionRepository = s.menuActionRepository.WithContext(ctx)
+	s.outboxService = s.outboxService.WithContext(ctx)
+	s.auditService = s.auditService.WithContext(ctx)
 
 	return s
 }
@@ -103,6 +112,10 @@ func (s RoleService) CompareRoleMenus(oRoleMenus, nRoleMenus models.RoleMenus) (
 }
 
 func (s RoleService) Create(role *models.Role) (id string, err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditCreate, models.AggregateRole, role.ID, nil, role.Audited(), err)
+	}()
+
 	if err = s.Check(role); err != nil {
 		return
 	}
@@ -125,12 +138,21 @@ func (s RoleService) Create(role *models.Role) (id string, err error) {
 		return
 	}
 
+	if err = s.outboxService.Emit(models.EventRoleCreated, models.AggregateRole, role.ID, role); err != nil {
+		return
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
 	return role.ID, nil
 }
 
-func (s RoleService) Update(id string, role *models.Role) error {
-	oRole, err := s.Get(id)
+func (s RoleService) Update(id string, role *models.Role) (err error) {
+	var oRole *models.Role
+	defer func() {
+		err = s.auditService.Record(models.AuditUpdate, models.AggregateRole, id, oRole.Audited(), role.Audited(), err)
+	}()
+
+	oRole, err = s.Get(id)
 	if err != nil {
 		return err
 	} else if role.Name != oRole.Name {
@@ -139,6 +161,13 @@ func (s RoleService) Update(id string, role *models.Role) error {
 		}
 	}
 
+	// a zero version updates whatever the current version is
+	if role.Version == 0 {
+		role.Version = oRole.Version
+	} else if role.Version != oRole.Version {
+		return errors.DatabaseVersionConflict
+	}
+
 	role.ID = oRole.ID
 	role.CreatedBy = oRole.CreatedBy
 	role.CreatedAt = oRole.CreatedAt
@@ -167,12 +196,29 @@ func (s RoleService) Update(id string, role *models.Role) error {
 		return err
 	}
 
+	if err := s.outboxService.Emit(models.EventRoleUpdated, models.AggregateRole, id, role); err != nil {
+		return err
+	}
+
+	if len(aRoleMenus) > 0 || len(dRoleMenus) > 0 {
+		data := &models.EventRoleMenusData{ID: id, Added: aRoleMenus, Removed: dRoleMenus}
+		if err := s.outboxService.Emit(models.EventRoleMenusUpdated, models.AggregateRole, id, data); err != nil {
+			return err
+		}
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
+	s.menuCacheService.InvalidateRoles(id)
 	return nil
 }
 
-func (s RoleService) Delete(id string) error {
-	_, err := s.roleRepository.Get(id)
+func (s RoleService) Delete(id string) (err error) {
+	var role *models.Role
+	defer func() {
+		err = s.auditService.Record(models.AuditDelete, models.AggregateRole, id, role.Audited(), nil, err)
+	}()
+
+	role, err = s.Get(id)
 	if err != nil {
 		return err
 	}
@@ -182,7 +228,7 @@ func (s RoleService) Delete(id string) error {
 	})
 	if err != nil {
 		return err
-	} else if userQR.Pagination.Total > 0 {
+	} else if len(userQR.List) > 0 {
 		return errors.RoleNotAllowDeleteWithUser
 	}
 
@@ -194,21 +240,39 @@ func (s RoleService) Delete(id string) error {
 		return err
 	}
 
+	if err := s.outboxService.Emit(models.EventRoleDeleted, models.AggregateRole, id, &models.EventDeletedData{ID: id}); err != nil {
+		return err
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
+	s.menuCacheService.InvalidateRoles(id)
 	return nil
 }
 
-func (s RoleService) UpdateStatus(id string, status int) error {
-	_, err := s.roleRepository.Get(id)
+func (s RoleService) UpdateStatus(id string, status int) (err error) {
+	var before *models.EventStatusData
+	defer func() {
+		after := &models.EventStatusData{ID: id, Status: status}
+		err = s.auditService.Record(models.AuditUpdateStatus, models.AggregateRole, id, before, after, err)
+	}()
+
+	role, err := s.roleRepository.Get(id)
 	if err != nil {
 		return err
 	}
+	before = &models.EventStatusData{ID: id, Status: role.Status}
 
 	if err := s.roleRepository.UpdateStatus(id, status); err != nil {
 		return err
 	}
 
+	data := &models.EventStatusData{ID: id, Status: status}
+	if err := s.outboxService.Emit(models.EventRoleStatusChanged, models.AggregateRole, id, data); err != nil {
+		return err
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
+	s.menuCacheService.InvalidateRoles(id)
 	return nil
 }
 
@@ -216,19 +280,25 @@ func (s RoleService) UpdateStatus(id string, status int) error {
 func NewRoleService(
 	logger lib.Logger,
 	casbinService CasbinService,
+	menuCacheService MenuCacheService,
 	userRepository repository.UserRepository,
 	roleRepository repository.RoleRepository,
 	roleMenuRepository repository.RoleMenuRepository,
 	menuRepository repository.MenuRepository,
 	menuActionRepository repository.MenuActionRepository,
+	outboxService OutboxService,
+	auditService AuditService,
 ) RoleService {
 	return RoleService{
 		logger:               logger,
 		casbinService:        casbinService,
+		menuCacheService:     menuCacheService,
 		userRepository:       userRepository,
 		roleRepository:       roleRepository,
 		roleMenuRepository:   roleMenuRepository,
 		menuRepository:       menuRepository,
 		menuActionRepository: menuActionRepository,
+		outboxService:        outboxService,
+		auditService:         auditService,
 	}
 }
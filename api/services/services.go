@@ -7,6 +7,23 @@ var Module = fx.Options(
 	fx.Provide(NewUserService),
 	fx.Provide(NewRoleService),
 	fx.Provide(NewMenuService),
+	fx.Provide(NewMenuCacheService),
 	fx.Provide(NewCasbinService),
 	fx.Provide(NewAuthService),
+	fx.Provide(NewResourceService),
+	fx.Provide(NewRbacService),
+	fx.Provide(NewTrashService),
+	fx.Provide(NewAuditService),
+	fx.Provide(NewOutboxService),
+	fx.Provide(NewOutboxRelay),
+	fx.Provide(NewWebhookService),
+	fx.Provide(AsEventSink(NewWebhookEventSink)),
+	fx.Provide(NewWebhookDispatcher),
+	fx.Provide(AsJobHandler(NewWebhookDeliverJobHandler)),
+	fx.Provide(NewJobWorker),
+	fx.Provide(NewCronScheduler),
+	fx.Provide(AsCronJob(NewOutboxCleanupCronJob)),
+	fx.Provide(NewLoginLogService),
+	fx.Provide(AsLoginAlertHook(NewLoginAlertEventHook)),
+	fx.Provide(AsCronJob(NewLoginLogCleanupCronJob)),
 )
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/models/dto"
+)
+
+// TrashService lists, restores and purges soft deleted users, roles and menus.
+// Children deleted together with their parent share its deleted_at, so restoring
+// the parent brings back exactly the rows removed by the same delete.
+type TrashService struct {
+	logger           lib.Logger
+	casbinService    CasbinService
+	menuCacheService MenuCacheService
+	trashRepository  repository.TrashRepository
+	userRepository   repository.UserRepository
+	roleRepository   repository.RoleRepository
+	menuRepository   repository.MenuRepository
+	auditService     AuditService
+}
+
+// WithContext binds the repositories to ctx, so that they join the transaction carried by ctx
+func (s TrashService) WithContext(ctx context.Context) TrashService {
+	s.logger = s.logger.WithContext(ctx)
+	s.menuCacheService = s.menuCacheService.WithContext(ctx)
+	s.trashRepository = s.trashRepository.WithContext(ctx)
+	s.userRepository = s.userRepository.WithContext(ctx)
+	s.roleRepository = s.roleRepository.WithContext(ctx)
+	s.menuRepository = s.menuRepository.WithContext(ctx)
+	s.auditService = s.auditService.WithContext(ctx)
+
+	return s
+}
+
+func (s TrashService) Query(param *models.TrashQueryParam) (*models.TrashQueryResult, error) {
+	qr := &models.TrashQueryResult{List: make(models.TrashItems, 0)}
+
+	switch param.Resource {
+	case models.TrashResourceUsers:
+		var users models.Users
+		pagination, err := s.trashRepository.Query(new(models.User), "username", param.QueryValue, param.PaginationParam, &users)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			qr.List = append(qr.List, &models.TrashItem{ID: user.ID, Name: user.Username, DeletedAt: user.DeletedAt.Time})
+		}
+		qr.Pagination = pagination
+	case models.TrashResourceRoles:
+		var roles models.Roles
+		pagination, err := s.trashRepository.Query(new(models.Role), "name", param.QueryValue, param.PaginationParam, &roles)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, role := range roles {
+			qr.List = append(qr.List, &models.TrashItem{ID: role.ID, Name: role.Name, DeletedAt: role.DeletedAt.Time})
+		}
+		qr.Pagination = pagination
+	case models.TrashResourceMenus:
+		var menus models.Menus
+		pagination, err := s.trashRepository.Query(new(models.Menu), "name", param.QueryValue, param.PaginationParam, &menus)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, menu := range menus {
+			qr.List = append(qr.List, &models.TrashItem{ID: menu.ID, Name: menu.Name, DeletedAt: menu.DeletedAt.Time})
+		}
+		qr.Pagination = pagination
+	default:
+		return nil, errors.TrashResourceInvalid
+	}
+
+	return qr, nil
+}
+
+// Restore brings back the soft deleted resource and the children deleted with it
+func (s TrashService) Restore(resource, id string) (err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditRestore, s.auditResource(resource), id, nil, nil, err)
+	}()
+
+	switch resource {
+	case models.TrashResourceUsers:
+		err = s.restoreUser(id)
+	case models.TrashResourceRoles:
+		err = s.restoreRole(id)
+	case models.TrashResourceMenus:
+		err = s.restoreMenu(id)
+	default:
+		return errors.TrashResourceInvalid
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_ = s.casbinService.Enforcer.LoadPolicy()
+	return nil
+}
+
+func (s TrashService) restoreUser(id string) error {
+	user := new(models.User)
+	if err := s.trashRepository.Get(user, id, user); err != nil {
+		return err
+	}
+
+	userQR, err := s.userRepository.Query(&models.UserQueryParam{Username: user.Username})
+	if err != nil {
+		return err
+	} else if len(userQR.List) > 0 {
+		return errors.Wrap(errors.TrashUsernameConflict, user.Username)
+	}
+
+	var userRoles models.UserRoles
+	deletedAt := user.DeletedAt.Time
+	if err := s.trashRepository.QueryDeleted(new(models.UserRole), "user_id", []string{id}, &deletedAt, &userRoles); err != nil {
+		return err
+	}
+
+	if len(userRoles) > 0 {
+		roleIDs := userRoles.ToRoleIDs()
+		roleQR, err := s.roleRepository.Query(&models.RoleQueryParam{IDs: roleIDs})
+		if err != nil {
+			return err
+		}
+
+		mRoles := roleQR.List.ToMap()
+		for _, roleID := range roleIDs {
+			if _, ok := mRoles[roleID]; !ok {
+				return errors.Wrap(errors.TrashUserRoleDeleted, roleID)
+			}
+		}
+	}
+
+	if err := s.trashRepository.Restore(new(models.UserRole), "user_id", []string{id}, deletedAt); err != nil {
+		return err
+	}
+
+	if err := s.trashRepository.Restore(new(models.User), "id", []string{id}, deletedAt); err != nil {
+		return err
+	}
+
+	s.menuCacheService.InvalidateUsers(id)
+	return nil
+}
+
+func (s TrashService) restoreRole(id string) error {
+	role := new(models.Role)
+	if err := s.trashRepository.Get(role, id, role); err != nil {
+		return err
+	}
+
+	roleQR, err := s.roleRepository.Query(&models.RoleQueryParam{Name: role.Name})
+	if err != nil {
+		return err
+	} else if len(roleQR.List) > 0 {
+		return errors.Wrap(errors.TrashRoleNameConflict, role.Name)
+	}
+
+	deletedAt := role.DeletedAt.Time
+	if err := s.trashRepository.Restore(new(models.RoleMenu), "role_id", []string{id}, deletedAt); err != nil {
+		return err
+	}
+
+	if err := s.trashRepository.Restore(new(models.Role), "id", []string{id}, deletedAt); err != nil {
+		return err
+	}
+
+	s.menuCacheService.InvalidateRoles(id)
+	return nil
+}
+
+func (s TrashService) restoreMenu(id string) error {
+	menu := new(models.Menu)
+	if err := s.trashRepository.Get(menu, id, menu); err != nil {
+		return err
+	}
+
+	if menu.ParentID != "" {
+		parentQR, err := s.menuRepository.Query(&models.MenuQueryParam{IDs: []string{menu.ParentID}})
+		if err != nil {
+			return err
+		} else if len(parentQR.List) == 0 {
+			return errors.Wrap(errors.TrashMenuParentDeleted, menu.ParentID)
+		}
+	}
+
+	menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{
+		PaginationParam: dto.PaginationParam{PageSize: 9999, Current: 1},
+		Name:            menu.Name,
+		ParentID:        menu.ParentID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range menuQR.List {
+		// a top level menu query does not filter on parent_id
+		if item.ParentID == menu.ParentID {
+			return errors.Wrap(errors.TrashMenuNameConflict, menu.Name)
+		}
+	}
+
+	var actions models.MenuActions
+	deletedAt := menu.DeletedAt.Time
+	if err := s.trashRepository.QueryDeleted(new(models.MenuAction), "menu_id", []string{id}, &deletedAt, &actions); err != nil {
+		return err
+	}
+
+	if len(actions) > 0 {
+		actionIDs := make([]string, len(actions))
+		for i, action := range actions {
+			actionIDs[i] = action.ID
+		}
+
+		if err := s.trashRepository.Restore(new(models.MenuActionResource), "action_id", actionIDs, deletedAt); err != nil {
+			return err
+		}
+
+		if err := s.trashRepository.Restore(new(models.MenuAction), "id", actionIDs, deletedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := s.trashRepository.Restore(new(models.MenuI18n), "menu_id", []string{id}, deletedAt); err != nil {
+		return err
+	}
+
+	if err := s.trashRepository.Restore(new(models.Menu), "id", []string{id}, deletedAt); err != nil {
+		return err
+	}
+
+	s.menuCacheService.InvalidateAll()
+	return nil
+}
+
+// Purge permanently deletes the soft deleted resource and its soft deleted children
+func (s TrashService) Purge(resource, id string) (err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditPurge, s.auditResource(resource), id, nil, nil, err)
+	}()
+
+	switch resource {
+	case models.TrashResourceUsers:
+		if err := s.trashRepository.Get(new(models.User), id, new(models.User)); err != nil {
+			return err
+		}
+
+		if err := s.trashRepository.Purge(new(models.UserRole), "user_id", []string{id}); err != nil {
+			return err
+		}
+
+		return s.trashRepository.Purge(new(models.User), "id", []string{id})
+	case models.TrashResourceRoles:
+		if err := s.trashRepository.Get(new(models.Role), id, new(models.Role)); err != nil {
+			return err
+		}
+
+		if err := s.trashRepository.Purge(new(models.RoleMenu), "role_id", []string{id}); err != nil {
+			return err
+		}
+
+		return s.trashRepository.Purge(new(models.Role), "id", []string{id})
+	case models.TrashResourceMenus:
+		if err := s.trashRepository.Get(new(models.Menu), id, new(models.Menu)); err != nil {
+			return err
+		}
+
+		var actions models.MenuActions
+		if err := s.trashRepository.QueryDeleted(new(models.MenuAction), "menu_id", []string{id}, nil, &actions); err != nil {
+			return err
+		}
+
+		if len(actions) > 0 {
+			actionIDs := make([]string, len(actions))
+			for i, action := range actions {
+				actionIDs[i] = action.ID
+			}
+
+			if err := s.trashRepository.Purge(new(models.MenuActionResource), "action_id", actionIDs); err != nil {
+				return err
+			}
+
+			if err := s.trashRepository.Purge(new(models.MenuAction), "id", actionIDs); err != nil {
+				return err
+			}
+		}
+
+		if err := s.trashRepository.Purge(new(models.MenuI18n), "menu_id", []string{id}); err != nil {
+			return err
+		}
+
+		return s.trashRepository.Purge(new(models.Menu), "id", []string{id})
+	default:
+		return errors.TrashResourceInvalid
+	}
+}
+
+// auditResource returns the aggregate type of the resource, as the audit log names it
+func (s TrashService) auditResource(resource string) string {
+	if aggregate, ok := models.TrashAggregates[resource]; ok {
+		return aggregate
+	}
+
+	return resource
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(
+	logger lib.Logger,
+	casbinService CasbinService,
+	menuCacheService MenuCacheService,
+	trashRepository repository.TrashRepository,
+	userRepository repository.UserRepository,
+	roleRepository repository.RoleRepository,
+	menuRepository repository.MenuRepository,
+	auditService AuditService,
+) TrashService {
+	return TrashService{
+		logger:           logger,
+		casbinService:    casbinService,
+		menuCacheService: menuCacheService,
+		trashRepository:  trashRepository,
+		userRepository:   userRepository,
+		roleRepository:   roleRepository,
+		menuRepository:   menuRepository,
+		auditService:     auditService,
+	}
+}
@@ -1,7 +1,7 @@
 package services
 
 import (
-	"gorm.io/gorm"
+	"context"
 	"manuel71sj/go-api-template/api/repository"
 	"manuel71sj/go-api-template/errors"
 	"manuel71sj/go-api-template/lib"
@@ -23,6 +23,10 @@ type UserService struct {
 	menuActionRepository repository.MenuActionRepository
 	roleRepository       repository.RoleRepository
 	roleMenuRepository   repository.RoleMenuRepository
+	menuI18nRepository   repository.MenuI18nRepository
+	menuCacheService     MenuCacheService
+	outboxService        OutboxService
+	auditService         AuditService
 }
 
 func (s UserService) GetSuperAdmin() *models.User {
@@ -35,10 +39,19 @@ func (s UserService) GetSuperAdmin() *models.User {
 	}
 }
 
-// WithTrx delegates transaction to repository database
-func (s UserService) WithTrx(trxHandle *gorm.DB) UserService {
-	s.userRepository = s.userRepository.WithTrx(trxHandle)
-	s.userRoleRepository = s.userRoleRepository.WithTrx(trxHandle)
+// WithContext binds the repositories to ctx, so that they join the transaction carried by ctx
+func (s UserService) WithContext(ctx context.Context) UserService {
+	s.logger = s.logger.WithContext(ctx)
+	s.menuCacheService = s.menuCacheService.WithContext(ctx)
+	s.userRepository = s.userRepository.WithContext(ctx)
+	s.userRoleRepository = s.userRoleRepository.WithContext(ctx)
+	s.roleRepository = s.roleRepository.WithContext(ctx)
+	s.roleMenuRepository = s.roleMenuRepository.WithContext(ctx)
+	s.menuRepository = s.menuRepository.WithContext(ctx)
+	s.menuActionRepository = s.menuActionRepository.WithContext(ctx)
+	s.menuI18nRepository = s.menuI18nRepository.WithContext(ctx)
+	s.outboxService = s.outboxService.WithContext(ctx)
+	s.auditService = s.auditService.WithContext(ctx)
 
 	return s
 }
@@ -86,6 +99,22 @@ func (s UserService) Verify(username, password string) (*models.User, error) {
 	return user, nil
 }
 
+// Reverify returns the user of a token being refreshed, as long as the user may still log in
+func (s UserService) Reverify(id string) (*models.User, error) {
+	if admin := s.GetSuperAdmin(); admin.ID == id {
+		return admin, nil
+	}
+
+	user, err := s.userRepository.Get(id)
+	if err != nil {
+		return nil, err
+	} else if user.Status != 1 {
+		return nil, errors.UserIsDisable
+	}
+
+	return user, nil
+}
+
 func (s UserService) Check(user *models.User) error {
 	if user.Username == s.GetSuperAdmin().Username {
 		return errors.UserInvalidUsername
@@ -145,36 +174,113 @@ func (s UserService) GetUserInfo(ID string) (*models.UserInfo, error) {
 	return userinfo, nil
 }
 
-func (s UserService) GetUserMenuTrees(ID string) (models.MenuTrees, error) {
-	if s.GetSuperAdmin().ID == ID {
+// GetUserMenuTrees returns the menus granted to the user, named in the user locale,
+// or else in the first supported locale of locales (usually from Accept-Language)
+func (s UserService) GetUserMenuTrees(ID string, locales ...string) (models.MenuTrees, error) {
+	subject, err := s.getMenuCacheSubject(ID)
+	if err != nil {
+		return nil, err
+	}
+
+	locale := s.config.I18n.Match(append([]string{subject.Locale}, locales...)...)
+	return s.menuCacheService.MenuTrees(subject, locale, func() (models.MenuTrees, error) {
+		menus, err := s.getSubjectMenus(subject)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.localizeMenuTrees(menus, locale)
+	})
+}
+
+// GetUserPermissions returns the action codes granted to the user by menu id
+func (s UserService) GetUserPermissions(ID string) (models.UserPermissions, error) {
+	subject, err := s.getMenuCacheSubject(ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.menuCacheService.Permissions(subject, func() (models.UserPermissions, error) {
+		param := &models.MenuActionQueryParam{PaginationParam: dto.PaginationParam{PageSize: 9999, Current: 1}}
+
+		if !subject.SuperAdmin {
+			if len(subject.RoleIDs) == 0 {
+				return nil, errors.UserNoPermission
+			}
+
+			roleMenuQR, err := s.roleMenuRepository.Query(&models.RoleMenuQueryParam{
+				RoleIDs: subject.RoleIDs,
+			})
+			if err != nil {
+				return nil, err
+			} else if len(roleMenuQR.List) == 0 {
+				return nil, errors.UserNoPermission
+			}
+
+			param.IDs = roleMenuQR.List.ToActionIDs()
+		}
+
+		menuActionQR, err := s.menuActionRepository.Query(param)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions := make(models.UserPermissions)
+		for _, action := range menuActionQR.List {
+			permissions[action.MenuID] = append(permissions[action.MenuID], action.Code)
+		}
+
+		return permissions, nil
+	})
+}
+
+func (s UserService) getMenuCacheSubject(ID string) (*models.MenuCacheSubject, error) {
+	return s.menuCacheService.Subject(ID, func() (*models.MenuCacheSubject, error) {
+		if s.GetSuperAdmin().ID == ID {
+			return &models.MenuCacheSubject{SuperAdmin: true}, nil
+		}
+
+		user, err := s.userRepository.Get(ID)
+		if err != nil {
+			return nil, err
+		}
+
+		userRoleQR, err := s.userRoleRepository.Query(&models.UserRoleQueryParam{
+			UserID: ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.MenuCacheSubject{RoleIDs: userRoleQR.List.ToRoleIDs(), Locale: user.Locale}, nil
+	})
+}
+
+func (s UserService) getSubjectMenus(subject *models.MenuCacheSubject) (models.Menus, error) {
+	if subject.SuperAdmin {
 		menuQR, err := s.menuRepository.Query(&models.MenuQueryParam{
 			Status:     1,
-			OrderParam: dto.OrderParam{Key: "sequence", Direction: dto.OrderByASC},
+			OrderParam: dto.OrderParam{Sort: "sequence"},
 		})
 		if err != nil {
 			return nil, err
 		}
 
-		return menuQR.List.ToMenuTrees(), nil
+		return menuQR.List, nil
 	}
 
 	var (
-		userRoleQR *models.UserRoleQueryResult
 		roleMenuQR *models.RoleMenuQueryResult
 		menuQR     *models.MenuQueryResult
 		err        error
 	)
 
-	if userRoleQR, err = s.userRoleRepository.Query(&models.UserRoleQueryParam{
-		UserID: ID,
-	}); err != nil {
-		return nil, err
-	} else if len(userRoleQR.List) == 0 {
+	if len(subject.RoleIDs) == 0 {
 		return nil, errors.UserNoPermission
 	}
 
 	if roleMenuQR, err = s.roleMenuRepository.Query(&models.RoleMenuQueryParam{
-		RoleIDs: userRoleQR.List.ToRoleIDs(),
+		RoleIDs: subject.RoleIDs,
 	}); err != nil {
 		return nil, err
 	} else if len(roleMenuQR.List) == 0 {
@@ -184,7 +290,7 @@ func (s UserService) GetUserMenuTrees(ID string) (models.MenuTrees, error) {
 	if menuQR, err = s.menuRepository.Query(&models.MenuQueryParam{
 		IDs:        roleMenuQR.List.ToMenuIDs(),
 		Status:     1,
-		OrderParam: dto.OrderParam{Key: "sequence", Direction: dto.OrderByASC},
+		OrderParam: dto.OrderParam{Sort: "sequence"},
 	}); err != nil {
 		return nil, err
 	} else if len(menuQR.List) == 0 {
@@ -211,7 +317,24 @@ func (s UserService) GetUserMenuTrees(ID string) (models.MenuTrees, error) {
 	}
 
 	sort.Sort(menuQR.List)
-	return menuQR.List.ToMenuTrees(), nil
+	return menuQR.List, nil
+}
+
+func (s UserService) localizeMenuTrees(menus models.Menus, locale string) (models.MenuTrees, error) {
+	if locale == s.config.I18n.DefaultLocale {
+		return menus.ToMenuTrees(), nil
+	}
+
+	menuI18nQR, err := s.menuI18nRepository.Query(&models.MenuI18nQueryParam{
+		PaginationParam: dto.PaginationParam{PageSize: 9999, Current: 1},
+		MenuIDs:         menus.ToIDs(),
+		Locale:          locale,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return menus.FillI18n(menuI18nQR.List.ToNamesMap()).Localize(locale).ToMenuTrees(), nil
 }
 
 func (s UserService) GetByUsername(username string) (*models.User, error) {
@@ -257,6 +380,10 @@ func (s UserService) Get(id string) (*models.User, error) {
 }
 
 func (s UserService) Create(user *models.User) (id string, err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditCreate, models.AggregateUser, user.ID, nil, user.Audited(), err)
+	}()
+
 	if err = s.Check(user); err != nil {
 		return
 	}
@@ -276,12 +403,21 @@ func (s UserService) Create(user *models.User) (id string, err error) {
 		return
 	}
 
+	if err = s.outboxService.Emit(models.EventUserCreated, models.AggregateUser, user.ID, s.eventUser(user)); err != nil {
+		return
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
 	return user.ID, nil
 }
 
-func (s UserService) Update(id string, user *models.User) error {
-	oUser, err := s.Get(id)
+func (s UserService) Update(id string, user *models.User) (err error) {
+	var oUser *models.User
+	defer func() {
+		err = s.auditService.Record(models.AuditUpdate, models.AggregateUser, id, oUser.Audited(), user.Audited(), err)
+	}()
+
+	oUser, err = s.Get(id)
 	if err != nil {
 		return err
 	} else if user.Username != oUser.Username {
@@ -296,6 +432,13 @@ func (s UserService) Update(id string, user *models.User) error {
 		user.Password = oUser.Password
 	}
 
+	// a zero version updates whatever the current version is
+	if user.Version == 0 {
+		user.Version = oUser.Version
+	} else if user.Version != oUser.Version {
+		return errors.DatabaseVersionConflict
+	}
+
 	user.ID = oUser.ID
 	user.CreatedAt = oUser.CreatedAt
 	user.CreatedBy = oUser.CreatedBy
@@ -319,7 +462,19 @@ func (s UserService) Update(id string, user *models.User) error {
 		return err
 	}
 
+	if err := s.outboxService.Emit(models.EventUserUpdated, models.AggregateUser, id, s.eventUser(user)); err != nil {
+		return err
+	}
+
+	if len(aUserRoles) > 0 || len(dUserRoles) > 0 {
+		data := &models.EventUserRolesData{ID: id, Added: aUserRoles.ToRoleIDs(), Removed: dUserRoles.ToRoleIDs()}
+		if err := s.outboxService.Emit(models.EventUserRolesUpdated, models.AggregateUser, id, data); err != nil {
+			return err
+		}
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
+	s.menuCacheService.InvalidateUsers(id)
 	return nil
 }
 
@@ -343,8 +498,13 @@ func (s UserService) CompareUserRoles(oUserRoles, nUserRoles models.UserRoles) (
 	return
 }
 
-func (s UserService) Delete(id string) error {
-	_, err := s.userRepository.Get(id)
+func (s UserService) Delete(id string) (err error) {
+	var user *models.User
+	defer func() {
+		err = s.auditService.Record(models.AuditDelete, models.AggregateUser, id, user.Audited(), nil, err)
+	}()
+
+	user, err = s.userRepository.Get(id)
 	if err != nil {
 		return err
 	}
@@ -353,24 +513,51 @@ func (s UserService) Delete(id string) error {
 		return err
 	}
 
+	if err := s.userRepository.Delete(id); err != nil {
+		return err
+	}
+
+	if err := s.outboxService.Emit(models.EventUserDeleted, models.AggregateUser, id, &models.EventDeletedData{ID: id}); err != nil {
+		return err
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
-	return s.userRepository.Delete(id)
+	s.menuCacheService.InvalidateUsers(id)
+	return nil
 }
 
-func (s UserService) UpdateStatus(id string, status int) error {
-	_, err := s.userRepository.Get(id)
+func (s UserService) UpdateStatus(id string, status int) (err error) {
+	var before *models.EventStatusData
+	defer func() {
+		after := &models.EventStatusData{ID: id, Status: status}
+		err = s.auditService.Record(models.AuditUpdateStatus, models.AggregateUser, id, before, after, err)
+	}()
+
+	user, err := s.userRepository.Get(id)
 	if err != nil {
 		return err
 	}
+	before = &models.EventStatusData{ID: id, Status: user.Status}
 
 	if err = s.userRepository.UpdateStatus(id, status); err != nil {
 		return err
 	}
 
+	data := &models.EventStatusData{ID: id, Status: status}
+	if err = s.outboxService.Emit(models.EventUserStatusChanged, models.AggregateUser, id, data); err != nil {
+		return err
+	}
+
 	_ = s.casbinService.Enforcer.LoadPolicy()
 	return nil
 }
 
+// eventUser is the user as written in the events, without the password
+func (s UserService) eventUser(user *models.User) *models.User {
+	eUser := *user
+	return eUser.CleanSecure()
+}
+
 // NewUserService creates a new user service
 func NewUserService(
 	logger lib.Logger,
@@ -380,7 +567,11 @@ func NewUserService(
 	roleMenuRepository repository.RoleMenuRepository,
 	menuRepository repository.MenuRepository,
 	menuActionRepository repository.MenuActionRepository,
+	menuI18nRepository repository.MenuI18nRepository,
+	menuCacheService MenuCacheService,
 	casbinService CasbinService,
+	outboxService OutboxService,
+	auditService AuditService,
 	config lib.Config,
 ) UserService {
 	return UserService{
@@ -392,6 +583,10 @@ func NewUserService(
 		roleMenuRepository:   roleMenuRepository,
 		menuRepository:       menuRepository,
 		menuActionRepository: menuActionRepository,
+		menuI18nRepository:   menuI18nRepository,
+		menuCacheService:     menuCacheService,
 		casbinService:        casbinService,
+		outboxService:        outboxService,
+		auditService:         auditService,
 	}
 }
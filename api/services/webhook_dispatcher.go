@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"time"
+)
+
+// webhookDispatcherLeaseKey only the instance holding the lease sends the deliveries
+const webhookDispatcherLeaseKey = "webhook-dispatcher"
+
+// WebhookDeliverJobType the job sending a new webhook delivery right away
+const WebhookDeliverJobType = "webhook.deliver"
+
+// WebhookDeliverJob the payload of a webhook.deliver job
+type WebhookDeliverJob struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// NewWebhookDeliverJobHandler creates the handler of the webhook.deliver jobs
+func NewWebhookDeliverJobHandler(webhookService WebhookService) JobHandler {
+	return NewJobHandler(WebhookDeliverJobType, func(ctx context.Context, payload WebhookDeliverJob) error {
+		return webhookService.WithContext(ctx).DeliverDue(ctx, payload.DeliveryID)
+	})
+}
+
+// WebhookEventSink turns the relayed events into the pending deliveries of the webhooks,
+// and enqueues a webhook.deliver job per delivery. The dispatcher sends the deliveries whose
+// job was not run, and the retries.
+type WebhookEventSink struct {
+	logger         lib.Logger
+	jobQueue       lib.JobQueue
+	webhookService WebhookService
+}
+
+func (s WebhookEventSink) Name() string {
+	return "webhook"
+}
+
+func (s WebhookEventSink) Publish(ctx context.Context, event *models.DomainEvent) error {
+	deliveries, err := s.webhookService.WithContext(ctx).Enqueue(event)
+	if err != nil {
+		return err
+	}
+
+	// the job would not find the deliveries before they are committed
+	lib.AfterCommit(ctx, func() {
+		for _, delivery := range deliveries {
+			if _, err := s.jobQueue.Enqueue(ctx, WebhookDeliverJobType, &WebhookDeliverJob{DeliveryID: delivery.ID}); err != nil {
+				s.logger.Zap.Warnf("Webhook delivery %s job enqueue error: %v", delivery.ID, err)
+			}
+		}
+	})
+
+	return nil
+}
+
+// NewWebhookEventSink creates a new webhook event sink
+func NewWebhookEventSink(logger lib.Logger, jobQueue lib.JobQueue, webhookService WebhookService) WebhookEventSink {
+	return WebhookEventSink{logger: logger, jobQueue: jobQueue, webhookService: webhookService}
+}
+
+// WebhookDispatcher sends the due webhook deliveries in the background
+type WebhookDispatcher struct {
+	config         *lib.WebhookConfig
+	webhookService WebhookService
+	loop           leasedLoop
+}
+
+// Start sends the deliveries in the background until Stop
+func (d WebhookDispatcher) Start() {
+	if !d.config.Enable {
+		d.loop.skip()
+		return
+	}
+
+	d.loop.start(d.webhookService.DispatchDue)
+}
+
+// Stop ends the dispatcher, waiting for the requests in flight at most until ctx is done
+func (d WebhookDispatcher) Stop(ctx context.Context) error {
+	return d.loop.stop(ctx)
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher
+func NewWebhookDispatcher(
+	config lib.Config,
+	logger lib.Logger,
+	redis lib.Redis,
+	webhookService WebhookService,
+) WebhookDispatcher {
+	return WebhookDispatcher{
+		config:         config.Webhook,
+		webhookService: webhookService,
+		loop: newLeasedLoop(redis, logger, webhookDispatcherLeaseKey,
+			time.Duration(config.Webhook.Interval)*time.Millisecond),
+	}
+}
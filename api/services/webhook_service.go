@@ -0,0 +1,504 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/hash"
+	"manuel71sj/go-api-template/pkg/uuid"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The headers of a webhook request. The signature is "sha256=" and the hex HMAC-SHA256,
+// keyed with the secret of the webhook, of the timestamp, a dot and the body.
+const (
+	WebhookHeaderID        = "X-Webhook-ID"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// webhookResponseBodyLimit bytes of the response body kept in the delivery log
+	webhookResponseBodyLimit = 1024
+	// webhookClaimMargin a claimed delivery is due again this long after the request timeout,
+	// in case its sender stopped before writing the outcome
+	webhookClaimMargin = time.Minute
+)
+
+// SignWebhook returns the signature of a webhook request
+func SignWebhook(secret, timestamp string, body []byte) string {
+	return "sha256=" + hash.HMACSHA256(secret, timestamp+"."+string(body))
+}
+
+// WebhookService manages the webhooks and delivers the domain events to them
+type WebhookService struct {
+	logger                    lib.Logger
+	config                    *lib.WebhookConfig
+	client                    *http.Client
+	webhookRepository         repository.WebhookRepository
+	webhookDeliveryRepository repository.WebhookDeliveryRepository
+	auditService              AuditService
+}
+
+// WithContext binds the repositories to ctx, so that they join the transaction carried by ctx
+func (s WebhookService) WithContext(ctx context.Context) WebhookService {
+	s.logger = s.logger.WithContext(ctx)
+	s.webhookRepository = s.webhookRepository.WithContext(ctx)
+	s.webhookDeliveryRepository = s.webhookDeliveryRepository.WithContext(ctx)
+	s.auditService = s.auditService.WithContext(ctx)
+
+	return s
+}
+
+func (s WebhookService) Query(param *models.WebhookQueryParam) (*models.WebhookQueryResult, error) {
+	return s.webhookRepository.Query(param)
+}
+
+func (s WebhookService) Get(id string) (*models.Webhook, error) {
+	return s.webhookRepository.Get(id)
+}
+
+func (s WebhookService) Check(webhook *models.Webhook) error {
+	for _, event := range webhook.Events {
+		if !models.ValidWebhookEvent(event) {
+			return errors.Wrap(errors.WebhookEventInvalid, event)
+		}
+	}
+
+	return nil
+}
+
+func (s WebhookService) Create(webhook *models.Webhook) (id string, err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditCreate, models.AuditResourceWebhook, webhook.ID, nil, webhook, err)
+	}()
+
+	if err = s.Check(webhook); err != nil {
+		return
+	}
+
+	webhook.ID = uuid.MustString()
+	if webhook.Secret == "" {
+		if webhook.Secret, err = s.GenerateSecret(); err != nil {
+			return
+		}
+	}
+
+	if err = s.webhookRepository.Create(webhook); err != nil {
+		return
+	}
+
+	return webhook.ID, nil
+}
+
+// Update writes the webhook, an empty secret keeps the current secret
+func (s WebhookService) Update(id string, webhook *models.Webhook) (err error) {
+	var oWebhook *models.Webhook
+	defer func() {
+		err = s.auditService.Record(models.AuditUpdate, models.AuditResourceWebhook, id, oWebhook, webhook, err)
+	}()
+
+	oWebhook, err = s.Get(id)
+	if err != nil {
+		return err
+	} else if err = s.Check(webhook); err != nil {
+		return err
+	}
+
+	// a zero version updates whatever the current version is
+	if webhook.Version == 0 {
+		webhook.Version = oWebhook.Version
+	} else if webhook.Version != oWebhook.Version {
+		return errors.DatabaseVersionConflict
+	}
+
+	webhook.ID = oWebhook.ID
+	webhook.CreatedBy = oWebhook.CreatedBy
+	webhook.CreatedAt = oWebhook.CreatedAt
+	if webhook.Secret == "" {
+		webhook.Secret = oWebhook.Secret
+	}
+
+	return s.webhookRepository.Update(id, webhook)
+}
+
+func (s WebhookService) Delete(id string) (err error) {
+	var webhook *models.Webhook
+	defer func() {
+		err = s.auditService.Record(models.AuditDelete, models.AuditResourceWebhook, id, webhook, nil, err)
+	}()
+
+	if webhook, err = s.Get(id); err != nil {
+		return err
+	}
+
+	if err := s.webhookDeliveryRepository.DeleteByWebhookID(id); err != nil {
+		return err
+	}
+
+	return s.webhookRepository.Delete(id)
+}
+
+func (s WebhookService) UpdateStatus(id string, status int) (err error) {
+	var before *models.EventStatusData
+	defer func() {
+		after := &models.EventStatusData{ID: id, Status: status}
+		err = s.auditService.Record(models.AuditUpdateStatus, models.AuditResourceWebhook, id, before, after, err)
+	}()
+
+	webhook, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	before = &models.EventStatusData{ID: id, Status: webhook.Status}
+
+	return s.webhookRepository.UpdateStatus(id, status)
+}
+
+// RotateSecret replaces the secret of the webhook with a generated one and returns it,
+// the requests signed with the former secret are no longer valid
+func (s WebhookService) RotateSecret(id string) (secret string, err error) {
+	defer func() {
+		err = s.auditService.Record(models.AuditRotateSecret, models.AuditResourceWebhook, id, nil, nil, err)
+	}()
+
+	if _, err = s.Get(id); err != nil {
+		return "", err
+	}
+
+	if secret, err = s.GenerateSecret(); err != nil {
+		return "", err
+	}
+
+	if err = s.webhookRepository.UpdateSecret(id, secret); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// GenerateSecret returns a random secret of 32 bytes in hex
+func (s WebhookService) GenerateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func (s WebhookService) QueryDeliveries(param *models.WebhookDeliveryQueryParam) (*models.WebhookDeliveryQueryResult, error) {
+	if _, err := s.Get(param.WebhookID); err != nil {
+		return nil, err
+	}
+
+	return s.webhookDeliveryRepository.Query(param)
+}
+
+// GetDelivery returns the delivery of the webhook
+func (s WebhookService) GetDelivery(webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookDeliveryRepository.Get(deliveryID)
+	if err != nil {
+		return nil, err
+	} else if delivery.WebhookID != webhookID {
+		return nil, errors.DatabaseRecordNotFound
+	}
+
+	return delivery, nil
+}
+
+// Redeliver sends the delivery again right away, whatever its status. When it fails again
+// it is retried with a new series of attempts.
+func (s WebhookService) Redeliver(webhookID, deliveryID string) (delivery *models.WebhookDelivery, err error) {
+	defer func() {
+		after := map[string]string{"delivery_id": deliveryID}
+		err = s.auditService.Record(models.AuditRedeliver, models.AuditResourceWebhook, webhookID, nil, after, err)
+	}()
+
+	webhook, err := s.Get(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err = s.GetDelivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Attempts = 0
+	if err := s.Deliver(webhook, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Ping sends a webhook.ping event to the webhook right away, disabled or not
+func (s WebhookService) Ping(id string) (delivery *models.WebhookDelivery, err error) {
+	defer func() {
+		var after interface{}
+		if delivery != nil {
+			after = map[string]string{"delivery_id": delivery.ID}
+		}
+		err = s.auditService.Record(models.AuditPing, models.AuditResourceWebhook, id, nil, after, err)
+	}()
+
+	webhook, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(map[string]string{"webhook_id": webhook.ID, "name": webhook.Name})
+	delivery, err = s.createDelivery(webhook, &models.DomainEvent{
+		ID:            uuid.MustString(),
+		Type:          models.EventWebhookPing,
+		AggregateType: "webhook",
+		AggregateID:   webhook.ID,
+		OccurredAt:    time.Now(),
+		Data:          data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Deliver(webhook, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Enqueue adds a pending delivery of the event for every enabled webhook subscribing to it,
+// it returns the added deliveries. An event relayed again is not delivered twice.
+func (s WebhookService) Enqueue(event *models.DomainEvent) (models.WebhookDeliveries, error) {
+	webhooks, err := s.webhookRepository.QueryEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries models.WebhookDeliveries
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event.Type) {
+			continue
+		}
+
+		if ok, err := s.webhookDeliveryRepository.Exists(webhook.ID, event.ID); err != nil {
+			return nil, err
+		} else if ok {
+			continue
+		}
+
+		delivery, err := s.createDelivery(webhook, event)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (s WebhookService) createDelivery(webhook *models.Webhook, event *models.DomainEvent) (*models.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrapf(errors.DatabaseInternalError, "event %s payload: %v", event.Type, err)
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:            uuid.MustString(),
+		WebhookID:     webhook.ID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       string(payload),
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+
+	if err := s.webhookDeliveryRepository.Create(delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// DispatchDue sends the due pending deliveries, it reports whether more may be due
+func (s WebhookService) DispatchDue(ctx context.Context) bool {
+	deliveries, err := s.webhookDeliveryRepository.QueryDue(time.Now(), s.config.BatchSize)
+	if err != nil {
+		s.logger.Zap.Errorf("Webhook deliveries read error: %v", err)
+		return false
+	}
+
+	webhooks := make(map[string]*models.Webhook)
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			if webhook, err = s.Get(delivery.WebhookID); err != nil && !errors.Is(err, errors.DatabaseRecordNotFound) {
+				s.logger.Zap.Errorf("Webhook %s read error: %v", delivery.WebhookID, err)
+				return false
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if webhook == nil || webhook.Status != 1 {
+			// the delivery waits in the dead letters for a redelivery once the webhook is enabled
+			delivery.Status = models.WebhookDeliveryDead
+			delivery.NextAttemptAt = nil
+			delivery.LastError = errors.WebhookIsDisable.Error()
+			err = s.webhookDeliveryRepository.UpdateAttempt(delivery)
+		} else {
+			err = s.deliverClaimed(ctx, webhook, delivery)
+		}
+
+		if err != nil {
+			s.logger.Zap.Errorf("Webhook delivery %s update error: %v", delivery.ID, err)
+			return false
+		}
+	}
+
+	return len(deliveries) == s.config.BatchSize
+}
+
+// DeliverDue sends the delivery right away when it is pending and due, e.g. as soon as it is
+// added instead of on the next round of the dispatcher. A delivery the dispatcher claimed
+// meanwhile, or whose webhook is disabled, is left to the dispatcher.
+func (s WebhookService) DeliverDue(ctx context.Context, deliveryID string) error {
+	if !s.config.Enable {
+		return nil
+	}
+
+	delivery, err := s.webhookDeliveryRepository.Get(deliveryID)
+	if errors.Is(err, errors.DatabaseRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if delivery.Status != models.WebhookDeliveryPending {
+		return nil
+	}
+
+	webhook, err := s.Get(delivery.WebhookID)
+	if errors.Is(err, errors.DatabaseRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if webhook.Status != 1 {
+		return nil
+	}
+
+	return s.deliverClaimed(ctx, webhook, delivery)
+}
+
+// deliverClaimed sends the delivery unless another sender claimed it first
+func (s WebhookService) deliverClaimed(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	now := time.Now()
+	if ok, err := s.webhookDeliveryRepository.Claim(delivery.ID, now, now.Add(s.client.Timeout+webhookClaimMargin)); err != nil || !ok {
+		return err
+	}
+
+	return s.deliver(ctx, webhook, delivery)
+}
+
+// Deliver sends the delivery to the webhook and writes the outcome: succeeded, pending
+// with the next attempt after the backoff, or dead after the last attempt
+func (s WebhookService) Deliver(webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	return s.deliver(context.Background(), webhook, delivery)
+}
+
+func (s WebhookService) deliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	code, body, err := s.send(ctx, webhook, delivery)
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	} else if delivery.Attempts >= s.config.MaxAttempts {
+		delivery.Status = models.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+	} else {
+		next := now.Add(s.config.Backoff(delivery.Attempts))
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+	}
+
+	if err != nil {
+		s.logger.Zap.Warnf("Webhook %s delivery %s of %s failed (attempt %d): %v",
+			webhook.ID, delivery.ID, delivery.EventType, delivery.Attempts, err)
+	}
+
+	return s.webhookDeliveryRepository.UpdateAttempt(delivery)
+}
+
+// send POSTs the payload of the delivery, any status but 2xx is an error
+func (s WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderID, webhook.ID)
+	req.Header.Set(WebhookHeaderDelivery, delivery.ID)
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("responded %s", resp.Status)
+	}
+
+	return resp.StatusCode, string(respBody), nil
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(
+	logger lib.Logger,
+	config lib.Config,
+	webhookRepository repository.WebhookRepository,
+	webhookDeliveryRepository repository.WebhookDeliveryRepository,
+	auditService AuditService,
+) WebhookService {
+	return WebhookService{
+		logger:                    logger,
+		config:                    config.Webhook,
+		client:                    &http.Client{Timeout: time.Duration(config.Webhook.Timeout) * time.Second},
+		webhookRepository:         webhookRepository,
+		webhookDeliveryRepository: webhookDeliveryRepository,
+		auditService:              auditService,
+	}
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/internal/testutil"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/pkg/uuid"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRequest a request received by the test webhook
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver the test webhook, answering with the next status of statuses, 200 once they ran out
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*webhookRequest
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, &webhookRequest{header: req.Header.Clone(), body: body})
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(http.StatusText(status)))
+}
+
+func newTestWebhookService(t *testing.T, config *lib.WebhookConfig) WebhookService {
+	t.Helper()
+
+	logger := testutil.NewLogger()
+	db := testutil.NewDatabase(t, logger)
+
+	return NewWebhookService(
+		logger,
+		lib.Config{Webhook: config},
+		repository.NewWebhookRepository(db, logger),
+		repository.NewWebhookDeliveryRepository(db, logger),
+		NewAuditService(logger, repository.NewAuditLogRepository(db, logger)),
+	)
+}
+
+func newTestWebhook(t *testing.T, s WebhookService, url string) *models.Webhook {
+	t.Helper()
+
+	webhook := &models.Webhook{Name: "test", URL: url, Events: []string{"user.*"}, Status: 1, CreatedBy: "test"}
+	if _, err := s.Create(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	return webhook
+}
+
+func newTestEvent(t *testing.T) *models.DomainEvent {
+	t.Helper()
+
+	data, _ := json.Marshal(&models.EventStatusData{ID: "user-id", Status: 1})
+	return &models.DomainEvent{
+		ID:            uuid.MustString(),
+		Type:          models.EventUserStatusChanged,
+		AggregateType: models.AggregateUser,
+		AggregateID:   "user-id",
+		OccurredAt:    time.Now(),
+		Data:          data,
+	}
+}
+
+func queryTestDeliveries(t *testing.T, s WebhookService, webhookID string) models.WebhookDeliveries {
+	t.Helper()
+
+	qr, err := s.QueryDeliveries(&models.WebhookDeliveryQueryParam{WebhookID: webhookID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return qr.List
+}
+
+func TestWebhookServiceSignsDeliveries(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	s := newTestWebhookService(t, &lib.WebhookConfig{BatchSize: 10, Timeout: 5, MaxAttempts: 3})
+	webhook := newTestWebhook(t, s, server.URL)
+	event := newTestEvent(t)
+
+	if _, err := s.Enqueue(event); err != nil {
+		t.Fatal(err)
+	} else if more := s.DispatchDue(context.Background()); more {
+		t.Error("DispatchDue reported more due deliveries")
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(receiver.requests))
+	}
+
+	request := receiver.requests[0]
+	timestamp := request.header.Get(WebhookHeaderTimestamp)
+	if got, want := request.header.Get(WebhookHeaderSignature), SignWebhook(webhook.Secret, timestamp, request.body); got != want {
+		t.Errorf("signature %q, want %q", got, want)
+	}
+
+	if got := request.header.Get(WebhookHeaderID); got != webhook.ID {
+		t.Errorf("webhook id header %q, want %q", got, webhook.ID)
+	}
+
+	if got := request.header.Get(WebhookHeaderEvent); got != event.Type {
+		t.Errorf("event header %q, want %q", got, event.Type)
+	}
+
+	var payload models.DomainEvent
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatal(err)
+	} else if payload.ID != event.ID {
+		t.Errorf("payload event id %q, want %q", payload.ID, event.ID)
+	}
+
+	// a relayed event is not delivered twice
+	if _, err := s.Enqueue(event); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := queryTestDeliveries(t, s, webhook.ID)
+	if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries, want 1", len(deliveries))
+	}
+
+	if got := request.header.Get(WebhookHeaderDelivery); got != deliveries[0].ID {
+		t.Errorf("delivery header %q, want %q", got, deliveries[0].ID)
+	}
+}
+
+func TestWebhookServiceRetriesServerErrors(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// no backoff, the failed deliveries are due again right away
+	s := newTestWebhookService(t, &lib.WebhookConfig{BatchSize: 10, Timeout: 5, MaxAttempts: 3})
+	webhook := newTestWebhook(t, s, server.URL)
+
+	if _, err := s.Enqueue(newTestEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	s.DispatchDue(context.Background())
+
+	delivery := queryTestDeliveries(t, s, webhook.ID)[0]
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("delivery %s after %d attempts, want pending after 1", delivery.Status, delivery.Attempts)
+	}
+
+	if delivery.ResponseCode != http.StatusInternalServerError || delivery.LastError == "" || delivery.NextAttemptAt == nil {
+		t.Errorf("delivery logged code %d, error %q, next attempt %v",
+			delivery.ResponseCode, delivery.LastError, delivery.NextAttemptAt)
+	}
+
+	s.DispatchDue(context.Background())
+	s.DispatchDue(context.Background())
+
+	delivery = queryTestDeliveries(t, s, webhook.ID)[0]
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.Attempts != 3 {
+		t.Fatalf("delivery %s after %d attempts, want succeeded after 3", delivery.Status, delivery.Attempts)
+	}
+
+	if delivery.ResponseCode != http.StatusOK || delivery.ResponseBody != "OK" || delivery.LastError != "" {
+		t.Errorf("delivery logged code %d, body %q, error %q", delivery.ResponseCode, delivery.ResponseBody, delivery.LastError)
+	}
+
+	if delivery.DeliveredAt == nil || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery logged delivered at %v, next attempt %v", delivery.DeliveredAt, delivery.NextAttemptAt)
+	}
+
+	if len(receiver.requests) != 3 {
+		t.Errorf("received %d requests, want 3", len(receiver.requests))
+	}
+}
+
+func TestWebhookServiceDeadAfterMaxAttempts(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	s := newTestWebhookService(t, &lib.WebhookConfig{BatchSize: 10, Timeout: 5, MaxAttempts: 2})
+	webhook := newTestWebhook(t, s, server.URL)
+
+	if _, err := s.Enqueue(newTestEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		s.DispatchDue(context.Background())
+	}
+
+	delivery := queryTestDeliveries(t, s, webhook.ID)[0]
+	if delivery.Status != models.WebhookDeliveryDead || delivery.Attempts != 2 {
+		t.Fatalf("delivery %s after %d attempts, want dead after 2", delivery.Status, delivery.Attempts)
+	}
+
+	if delivery.ResponseCode != http.StatusServiceUnavailable || delivery.NextAttemptAt != nil {
+		t.Errorf("delivery logged code %d, next attempt %v", delivery.ResponseCode, delivery.NextAttemptAt)
+	}
+
+	// a redelivery sends the dead delivery again
+	if _, err := s.Redeliver(webhook.ID, delivery.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery = queryTestDeliveries(t, s, webhook.ID)[0]
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.Attempts != 1 {
+		t.Errorf("redelivery %s after %d attempts, want succeeded after 1", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookSecretIsWriteOnly(t *testing.T) {
+	form := new(models.WebhookForm)
+	if err := json.Unmarshal([]byte(`{"name":"test","url":"http://localhost","secret":"s3cret"}`), form); err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := form.ToWebhook()
+	if webhook.Secret != "s3cret" {
+		t.Fatalf("secret %q read from the form, want %q", webhook.Secret, "s3cret")
+	}
+
+	data, err := json.Marshal(webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	_ = json.Unmarshal(data, &fields)
+	if _, ok := fields["secret"]; ok {
+		t.Errorf("secret written in %s", data)
+	}
+}
+
+func TestWebhookServiceDeliverDueOnce(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	s := newTestWebhookService(t, &lib.WebhookConfig{Enable: true, BatchSize: 10, Timeout: 5, MaxAttempts: 3})
+	webhook := newTestWebhook(t, s, server.URL)
+
+	deliveries, err := s.Enqueue(newTestEvent(t))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(deliveries) != 1 {
+		t.Fatalf("%d deliveries added, want 1", len(deliveries))
+	}
+
+	// the job may run more than once, the dispatcher may run meanwhile
+	for i := 0; i < 2; i++ {
+		if err := s.DeliverDue(context.Background(), deliveries[0].ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.DispatchDue(context.Background())
+
+	if len(receiver.requests) != 1 {
+		t.Errorf("received %d requests, want 1", len(receiver.requests))
+	}
+
+	delivery := queryTestDeliveries(t, s, webhook.ID)[0]
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.Attempts != 1 {
+		t.Errorf("delivery %s after %d attempts, want succeeded after 1", delivery.Status, delivery.Attempts)
+	}
+}
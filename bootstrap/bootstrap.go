@@ -2,6 +2,7 @@ package bootstrap
 
 import (
 	"context"
+	"database/sql"
 	"go.uber.org/fx"
 	"manuel71sj/go-api-template/api/controllers"
 	"manuel71sj/go-api-template/api/middlewares"
@@ -14,14 +15,19 @@ import (
 	"time"
 )
 
-// Module exported for initializing application
-var Module = fx.Options(
+// CommonModules exported for building the dependency graph without starting the server
+var CommonModules = fx.Options(
 	controllers.Module,
 	routes.Module,
 	lib.Module,
 	services.Module,
 	middlewares.Module,
 	repository.Module,
+)
+
+// Module exported for initializing application
+var Module = fx.Options(
+	CommonModules,
 	fx.Invoke(bootstrap),
 )
 
@@ -33,29 +39,37 @@ func bootstrap(
 	config lib.Config,
 	middlewares middlewares.Middlewares,
 	database lib.Database,
+	menuService services.MenuService,
+	resourceService services.ResourceService,
+	outboxRelay services.OutboxRelay,
+	webhookDispatcher services.WebhookDispatcher,
+	jobWorker services.JobWorker,
+	cronScheduler services.CronScheduler,
+	tracing lib.Tracing,
+	metrics lib.Metrics,
 ) {
-	db, err := database.ORM.DB()
-	if err != nil {
-		logger.Zap.Fatalf("Error to get database connection: %v", err)
-	}
+	db := connectionPool(logger, database)
 
 	lifecycle.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
 			logger.Zap.Info("Starting application...")
 
-			if err := db.Ping(); err != nil {
-				logger.Zap.Fatalf("Error to ping database connection: %v", err)
-			}
+			setupConnectionPool(logger, config, db)
 
-			// set conn
-			db.SetMaxOpenConns(config.Database.MaxOpenConns)
-			db.SetMaxIdleConns(config.Database.MaxIdleConns)
-			db.SetConnMaxLifetime(time.Duration(config.Database.MaxLifetime) * time.Second)
+			outboxRelay.Start()
+			webhookDispatcher.Start()
+			if config.Jobs.Embedded {
+				jobWorker.Start()
+			}
+			cronScheduler.Start()
+			metrics.Start()
 
 			go func() {
 				middlewares.Setup()
 				routes.Setup()
 
+				checkResources(logger, config, menuService, resourceService)
+
 				if err := handler.Engine.Start(config.Http.ListenAddr()); err != nil {
 					if errors.Is(err, http.ErrServerClosed) {
 						logger.Zap.Debug("Shutting down the Application")
@@ -71,9 +85,83 @@ func bootstrap(
 			logger.Zap.Info("Stopping application...")
 
 			_ = handler.Engine.Close()
-			_ = db.Close()
+			if err := metrics.Shutdown(ctx); err != nil {
+				logger.Zap.Warnf("Metrics shutdown error: %v", err)
+			}
+			// drain the running jobs, they may still use the database
+			if err := jobWorker.Stop(ctx); err != nil {
+				logger.Zap.Warnf("Job worker stop error: %v", err)
+			}
+			if err := cronScheduler.Stop(ctx); err != nil {
+				logger.Zap.Warnf("Cron scheduler stop error: %v", err)
+			}
+			if err := outboxRelay.Stop(ctx); err != nil {
+				logger.Zap.Warnf("Outbox relay stop error: %v", err)
+			}
+			if err := webhookDispatcher.Stop(ctx); err != nil {
+				logger.Zap.Warnf("Webhook dispatcher stop error: %v", err)
+			}
+			_ = database.Close()
+			// the spans of the last requests
+			if err := tracing.Shutdown(ctx); err != nil {
+				logger.Zap.Warnf("Tracing shutdown error: %v", err)
+			}
 
 			return nil
 		},
 	})
 }
+
+// connectionPool returns the connection pool of the database
+func connectionPool(logger lib.Logger, database lib.Database) *sql.DB {
+	db, err := database.ORM.DB()
+	if err != nil {
+		logger.Zap.Fatalf("Error to get database connection: %v", err)
+	}
+
+	return db
+}
+
+// setupConnectionPool checks the database connection and sets the pool limits of the config
+func setupConnectionPool(logger lib.Logger, config lib.Config, db *sql.DB) {
+	if err := db.Ping(); err != nil {
+		logger.Zap.Fatalf("Error to ping database connection: %v", err)
+	}
+
+	// set conn
+	db.SetMaxOpenConns(config.Database.MaxOpenConns)
+	db.SetMaxIdleConns(config.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(config.Database.MaxLifetime) * time.Second)
+}
+
+// checkResources warns about menu action resources that do not match the registered routes
+func checkResources(
+	logger lib.Logger,
+	config lib.Config,
+	menuService services.MenuService,
+	resourceService services.ResourceService,
+) {
+	menuTrees, err := menuService.ReadMenuFile(config.Menu.File)
+	if err != nil && !errors.Is(err, errors.MenuFileNotExist) {
+		logger.Zap.Warnf("Resource check skipped menu file[%s]: %v", config.Menu.File, err)
+	}
+
+	report, err := resourceService.Check(menuTrees)
+	if err != nil {
+		logger.Zap.Errorf("Error to check resources: %v", err)
+		return
+	}
+
+	for _, item := range report.UnknownResources {
+		logger.Zap.Warnf("Resource %s %s of %s[%s] (%s) matches no route",
+			item.Method, item.Path, item.Menu, item.Action, item.Source)
+	}
+
+	for _, route := range report.UncoveredRoutes {
+		logger.Zap.Warnf("Route %s %s is not covered by any menu action", route.Method, route.Path)
+	}
+
+	for _, route := range report.ShadowedRoutes {
+		logger.Zap.Warnf("Route %s %s is shadowed by casbin ignore prefix %s", route.Method, route.Path, route.Prefix)
+	}
+}
@@ -0,0 +1,50 @@
+package bootstrap
+
+import (
+	"context"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+)
+
+// WorkerModule exported for running the jobs and the cron jobs in a process of their own
+var WorkerModule = fx.Options(
+	CommonModules,
+	fx.Invoke(worker),
+)
+
+func worker(
+	lifecycle fx.Lifecycle,
+	logger lib.Logger,
+	config lib.Config,
+	database lib.Database,
+	jobWorker services.JobWorker,
+	cronScheduler services.CronScheduler,
+) {
+	db := connectionPool(logger, database)
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Zap.Info("Starting worker...")
+
+			setupConnectionPool(logger, config, db)
+			jobWorker.Start()
+			cronScheduler.Start()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Zap.Info("Stopping worker...")
+
+			if err := jobWorker.Stop(ctx); err != nil {
+				logger.Zap.Warnf("Job worker stop error: %v", err)
+			}
+			if err := cronScheduler.Stop(ctx); err != nil {
+				logger.Zap.Warnf("Cron scheduler stop error: %v", err)
+			}
+			_ = database.Close()
+
+			return nil
+		},
+	})
+}
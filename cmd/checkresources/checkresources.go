@@ -0,0 +1,118 @@
+package checkresources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/api/routes"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/bootstrap"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models/dto"
+	"os"
+)
+
+var (
+	configFile  string
+	casbinModel string
+	menuFile    string
+	jsonOutput  bool
+
+	StartCmd = &cobra.Command{
+		Use:          "check-resources",
+		Short:        "Check menu action resources against the registered routes",
+		Example:      "{execfile} check-resources -c config/config.yaml -m config/casbin_model.conf -f config/menu.yaml",
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			lib.SetConfigPath(configFile)
+			lib.SetConfigCasbinModelPath(casbinModel)
+			lib.SetConfigMenuPath(menuFile)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				report   *dto.ResourceCheckReport
+				database lib.Database
+				redis    lib.Redis
+				tracing  lib.Tracing
+			)
+
+			app := fx.New(
+				bootstrap.CommonModules,
+				fx.NopLogger,
+				fx.Populate(&database, &redis, &tracing),
+				fx.Invoke(func(
+					config lib.Config,
+					routes routes.Routes,
+					menuService services.MenuService,
+					resourceService services.ResourceService,
+				) error {
+					routes.Setup()
+
+					menuTrees, err := menuService.ReadMenuFile(config.Menu.File)
+					if err != nil {
+						return err
+					}
+
+					report, err = resourceService.Check(menuTrees)
+					return err
+				}),
+			)
+			if err := app.Err(); err != nil {
+				return err
+			}
+
+			defer func() {
+				_ = database.Close()
+				_ = redis.Close()
+				_ = tracing.Shutdown(context.Background())
+			}()
+
+			if jsonOutput {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				_ = enc.Encode(report)
+			} else {
+				printReport(report)
+			}
+
+			if report.HasProblems() {
+				return fmt.Errorf("%d resources are unknown, %d routes are not covered and %d are shadowed",
+					len(report.UnknownResources), len(report.UncoveredRoutes), len(report.ShadowedRoutes))
+			}
+
+			return nil
+		},
+	}
+)
+
+func init() {
+	pf := StartCmd.PersistentFlags()
+	pf.StringVarP(&configFile, "config", "c",
+		"config/config.yaml", "this parameter is used to start the service application.")
+	pf.StringVarP(&casbinModel, "casbin", "m",
+		"config/casbin_model.conf", "this parameter is used for the running configuration of casbin.")
+	pf.StringVarP(&menuFile, "menu", "f",
+		"config/menu.yaml", "this parameter is used to set the menu data to check.")
+	pf.BoolVar(&jsonOutput, "json", false, "print the report as json.")
+
+	_ = cobra.MarkFlagRequired(pf, "config")
+}
+
+func printReport(report *dto.ResourceCheckReport) {
+	fmt.Printf("Unknown resources (%d):\n", len(report.UnknownResources))
+	for _, item := range report.UnknownResources {
+		fmt.Printf("  [%s] %-7s %s  (%s -> %s)\n", item.Source, item.Method, item.Path, item.Menu, item.Action)
+	}
+
+	fmt.Printf("Routes not covered by any action (%d):\n", len(report.UncoveredRoutes))
+	for _, route := range report.UncoveredRoutes {
+		fmt.Printf("  %-7s %s\n", route.Method, route.Path)
+	}
+
+	fmt.Printf("Routes shadowed by Casbin.IgnorePathPrefixes (%d):\n", len(report.ShadowedRoutes))
+	for _, route := range report.ShadowedRoutes {
+		fmt.Printf("  %-7s %s  (prefix %s)\n", route.Method, route.Path, route.Prefix)
+	}
+}
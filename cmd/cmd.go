@@ -3,9 +3,14 @@ package cmd
 import (
 	"errors"
 	"github.com/spf13/cobra"
+	"manuel71sj/go-api-template/cmd/checkresources"
+	"manuel71sj/go-api-template/cmd/exportmenus"
 	"manuel71sj/go-api-template/cmd/migrate"
+	"manuel71sj/go-api-template/cmd/rbac"
 	"manuel71sj/go-api-template/cmd/runserver"
 	"manuel71sj/go-api-template/cmd/setup"
+	"manuel71sj/go-api-template/cmd/webhookreceiver"
+	"manuel71sj/go-api-template/cmd/worker"
 	"os"
 )
 
@@ -29,6 +34,11 @@ func init() {
 	rootCmd.AddCommand(runserver.StartCmd)
 	rootCmd.AddCommand(migrate.StartCmd)
 	rootCmd.AddCommand(setup.StartCmd)
+	rootCmd.AddCommand(checkresources.StartCmd)
+	rootCmd.AddCommand(exportmenus.StartCmd)
+	rootCmd.AddCommand(rbac.StartCmd)
+	rootCmd.AddCommand(webhookreceiver.StartCmd)
+	rootCmd.AddCommand(worker.StartCmd)
 }
 
 func Execute() {
@@ -0,0 +1,67 @@
+package exportmenus
+
+import (
+	"github.com/spf13/cobra"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+	"os"
+)
+
+var (
+	configFile string
+	outputFile string
+
+	StartCmd = &cobra.Command{
+		Use:          "export-menus",
+		Short:        "Export the menu data in the menu file format",
+		Example:      "{execfile} export-menus -c config/config.yaml -o config/menu.yaml",
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			lib.SetConfigPath(configFile)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			config := lib.NewConfig()
+			logger := lib.NewLogger(config)
+			db := lib.NewDatabase(config, logger)
+
+			menuService := services.NewMenuService(
+				logger,
+				repository.NewMenuRepository(db, logger),
+				repository.NewMenuActionRepository(db, logger),
+				repository.NewMenuActionResourceRepository(db, logger),
+				repository.NewRoleMenuRepository(db, logger),
+				repository.NewMenuI18nRepository(db, logger),
+				services.NewMenuCacheService(logger, lib.NewRedis(config, logger)),
+				services.NewOutboxService(logger, repository.NewOutboxRepository(db, logger)),
+				services.NewAuditService(logger, repository.NewAuditLogRepository(db, logger)),
+			)
+
+			data, err := menuService.ExportMenuFile()
+			if err != nil {
+				logger.Zap.Fatalf("Menu export error: %v", err)
+			}
+
+			if outputFile == "" {
+				_, _ = os.Stdout.Write(data)
+				return
+			}
+
+			if err := os.WriteFile(outputFile, data, 0644); err != nil {
+				logger.Zap.Fatalf("Menu file write error: %v", err)
+			}
+
+			logger.Zap.Infof("Menu file exported to %s", outputFile)
+		},
+	}
+)
+
+func init() {
+	pf := StartCmd.PersistentFlags()
+	pf.StringVarP(&configFile, "config", "c",
+		"config/config.yaml", "this parameter is used to start the service application.")
+	pf.StringVarP(&outputFile, "output", "o",
+		"", "this parameter is used to set the exported menu file, stdout when empty.")
+
+	_ = cobra.MarkFlagRequired(pf, "config")
+}
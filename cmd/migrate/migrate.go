@@ -1,44 +1,187 @@
 package migrate
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
 	"manuel71sj/go-api-template/lib"
-	"manuel71sj/go-api-template/models"
+	"manuel71sj/go-api-template/migrations"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var configFile string
-
-var StartCmd = &cobra.Command{
-	Use:          "migrate",
-	Short:        "Migrate database",
-	Example:      "{execfile} migrate -c config/config.yml",
-	SilenceUsage: true,
-	PreRun: func(cmd *cobra.Command, args []string) {
-		lib.SetConfigPath(configFile)
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		config := lib.NewConfig()
-		logger := lib.NewLogger(config)
-		db := lib.NewDatabase(config, logger)
-
-		if err := db.ORM.AutoMigrate(
-			&models.User{},
-			&models.UserRole{},
-			&models.Role{},
-			&models.RoleMenu{},
-			&models.Menu{},
-			&models.MenuAction{},
-			&models.MenuActionResource{},
-		); err != nil {
-			logger.Zap.Fatalf("Error to migrate database: %v", err)
-		}
-	},
+var (
+	configFile string
+	sqlDir     string
+	sqlFormat  bool
+
+	StartCmd = &cobra.Command{
+		Use:          "migrate",
+		Short:        "Migrate database, same as migrate up",
+		Example:      "{execfile} migrate -c config/config.yml",
+		SilenceUsage: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			lib.SetConfigPath(configFile)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			upCmd.Run(cmd, args)
+		},
+	}
+
+	upCmd = &cobra.Command{
+		Use:          "up",
+		Short:        "Apply all pending migrations",
+		Example:      "{execfile} migrate up -c config/config.yml",
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger, migrator := newMigrator()
+			if err := migrator.Up(); err != nil {
+				logger.Zap.Fatalf("Error to migrate database: %v", err)
+			}
+		},
+	}
+
+	downCmd = &cobra.Command{
+		Use:          "down [steps]",
+		Short:        "Revert the last applied migrations, one by default",
+		Example:      "{execfile} migrate down 2 -c config/config.yml",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			steps := 1
+			if len(args) > 0 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					_, _ = fmt.Fprintf(os.Stderr, "invalid steps %q\n", args[0])
+					os.Exit(1)
+				}
+				steps = n
+			}
+
+			logger, migrator := newMigrator()
+			if err := migrator.Down(steps); err != nil {
+				logger.Zap.Fatalf("Error to revert migrations: %v", err)
+			}
+		},
+	}
+
+	toCmd = &cobra.Command{
+		Use:          "to <version>",
+		Short:        "Migrate up or down to the version, 0 reverts all migrations",
+		Example:      "{execfile} migrate to 20231001000000 -c config/config.yml",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger, migrator := newMigrator()
+			if err := migrator.To(args[0]); err != nil {
+				logger.Zap.Fatalf("Error to migrate database: %v", err)
+			}
+		},
+	}
+
+	statusCmd = &cobra.Command{
+		Use:          "status",
+		Short:        "List the migrations and when they were applied",
+		Example:      "{execfile} migrate status -c config/config.yml",
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger, migrator := newMigrator()
+
+			list, err := migrator.Status()
+			if err != nil {
+				logger.Zap.Fatalf("Error to read migrations: %v", err)
+			}
+
+			for _, item := range list {
+				appliedAt := "pending"
+				if item.AppliedAt != nil {
+					appliedAt = item.AppliedAt.Format(time.RFC3339)
+				}
+
+				fmt.Printf("%s  %-40s  %s\n", item.Version, item.Name, appliedAt)
+			}
+		},
+	}
+
+	createCmd = &cobra.Command{
+		Use:          "create <name>",
+		Short:        "Create a Go migration file, or up and down SQL files with --sql",
+		Example:      "{execfile} migrate create add_user_nickname --sql",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			version := time.Now().Format("20060102150405")
+			name := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(args[0]), "_"), "_")
+
+			files := map[string]string{
+				filepath.Join("migrations", version+"_"+name+".go"): fmt.Sprintf(goMigrationTemplate, version, name),
+			}
+			if sqlFormat {
+				files = map[string]string{
+					filepath.Join(sqlDir, version+"_"+name+".up.sql"):   "-- {prefix} is replaced by the table prefix\n",
+					filepath.Join(sqlDir, version+"_"+name+".down.sql"): "",
+				}
+			}
+
+			for file, content := range files {
+				if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Error to create migration: %v\n", err)
+					os.Exit(1)
+				}
+
+				fmt.Println(file)
+			}
+		},
+	}
+)
+
+const goMigrationTemplate = `package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register("%[1]s", "%[2]s", up%[1]s, down%[1]s)
+}
+
+func up%[1]s(tx *gorm.DB) error {
+	return nil
+}
+
+func down%[1]s(tx *gorm.DB) error {
+	return nil
+}
+`
+
+func newMigrator() (lib.Logger, lib.Migrator) {
+	config := lib.NewConfig()
+	logger := lib.NewLogger(config)
+	db := lib.NewDatabase(config, logger)
+
+	list, err := migrations.All(sqlDir, config.Database.TablePrefix+"_")
+	if err != nil {
+		logger.Zap.Fatalf("Error to load migrations: %v", err)
+	}
+
+	migrator, err := lib.NewMigrator(db, logger, list)
+	if err != nil {
+		logger.Zap.Fatalf("Error to prepare migrations: %v", err)
+	}
+
+	return logger, migrator
 }
 
 func init() {
 	pf := StartCmd.PersistentFlags()
 	pf.StringVarP(&configFile, "config", "c",
 		"config/config.yaml", "this parameter is used to start the service application")
+	pf.StringVar(&sqlDir, "dir", migrations.SQLDir, "directory of the SQL migrations")
+
+	createCmd.Flags().BoolVar(&sqlFormat, "sql", false, "create up and down SQL files instead of a Go file")
 
-	_ = cobra.MarkFlagRequired(pf, "config")
+	StartCmd.AddCommand(upCmd, downCmd, toCmd, statusCmd, createCmd)
 }
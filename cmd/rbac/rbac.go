@@ -0,0 +1,157 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/spf13/cobra"
+	"manuel71sj/go-api-template/api/repository"
+	"manuel71sj/go-api-template/api/services"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/models"
+	"os"
+)
+
+var (
+	configFile   string
+	outputFile   string
+	inputFile    string
+	format       string
+	includeUsers bool
+	dryRun       bool
+	strategy     string
+
+	StartCmd = &cobra.Command{
+		Use:          "rbac",
+		Short:        "Export or import the roles, role menus and user roles",
+		SilenceUsage: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			lib.SetConfigPath(configFile)
+		},
+	}
+
+	exportCmd = &cobra.Command{
+		Use:          "export",
+		Short:        "Export the roles as a rbac bundle",
+		Example:      "{execfile} rbac export -c config/config.yaml -o rbac.yaml --include-users",
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger, _, rbacService := newRbacService()
+
+			bundle, err := rbacService.Export(includeUsers)
+			if err != nil {
+				logger.Zap.Fatalf("Rbac export error: %v", err)
+			}
+
+			data, err := rbacService.Encode(bundle, format)
+			if err != nil {
+				logger.Zap.Fatalf("Rbac encode error: %v", err)
+			}
+
+			if outputFile == "" {
+				_, _ = os.Stdout.Write(data)
+				return
+			}
+
+			if err := os.WriteFile(outputFile, data, 0644); err != nil {
+				logger.Zap.Fatalf("Rbac file write error: %v", err)
+			}
+
+			logger.Zap.Infof("Rbac bundle exported to %s", outputFile)
+		},
+	}
+
+	importCmd = &cobra.Command{
+		Use:          "import",
+		Short:        "Import a rbac bundle in a single transaction",
+		Example:      "{execfile} rbac import -c config/config.yaml -i rbac.yaml --strategy overwrite --dry-run",
+		SilenceUsage: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger, db, rbacService := newRbacService()
+
+			data, err := os.ReadFile(inputFile)
+			if err != nil {
+				logger.Zap.Fatalf("Rbac file read error: %v", err)
+			}
+
+			bundle, err := rbacService.Decode(data)
+			if err != nil {
+				logger.Zap.Fatalf("Rbac file decode error: %v", err)
+			}
+
+			param := &models.RbacImportParam{DryRun: dryRun, Strategy: strategy, Operator: "cli"}
+
+			var result *models.RbacImportResult
+			err = db.Transaction(context.Background(), func(ctx context.Context) error {
+				result, err = rbacService.WithContext(ctx).Import(bundle, param)
+				return err
+			})
+			if err != nil {
+				logger.Zap.Fatalf("Rbac import error: %v", err)
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			_ = encoder.Encode(result)
+		},
+	}
+)
+
+func init() {
+	pf := StartCmd.PersistentFlags()
+	pf.StringVarP(&configFile, "config", "c",
+		"config/config.yaml", "this parameter is used to start the service application.")
+
+	_ = cobra.MarkFlagRequired(pf, "config")
+
+	ef := exportCmd.Flags()
+	ef.StringVarP(&outputFile, "output", "o",
+		"", "this parameter is used to set the exported rbac file, stdout when empty.")
+	ef.StringVar(&format, "format", "yaml", "the bundle format, yaml or json.")
+	ef.BoolVar(&includeUsers, "include-users", false, "also export the user roles by username.")
+
+	imf := importCmd.Flags()
+	imf.StringVarP(&inputFile, "input", "i",
+		"", "this parameter is used to set the imported rbac file, yaml or json.")
+	imf.BoolVar(&dryRun, "dry-run", false, "print the changes without applying them.")
+	imf.StringVar(&strategy, "strategy", models.RbacImportSkip,
+		"how to handle existing roles and user roles, skip, overwrite or fail.")
+
+	_ = cobra.MarkFlagRequired(imf, "input")
+
+	StartCmd.AddCommand(exportCmd)
+	StartCmd.AddCommand(importCmd)
+}
+
+func newRbacService() (lib.Logger, lib.Database, services.RbacService) {
+	config := lib.NewConfig()
+	logger := lib.NewLogger(config)
+	db := lib.NewDatabase(config, logger)
+
+	userRepository := repository.NewUserRepository(db, logger)
+	userRoleRepository := repository.NewUserRoleRepository(db, logger)
+	roleRepository := repository.NewRoleRepository(db, logger)
+	roleMenuRepository := repository.NewRoleMenuRepository(db, logger)
+
+	casbinService := services.NewCasbinService(
+		logger,
+		config,
+		userRepository,
+		userRoleRepository,
+		roleRepository,
+		roleMenuRepository,
+		repository.NewMenuActionResourceRepository(db, logger),
+	)
+
+	return logger, db, services.NewRbacService(
+		logger,
+		casbinService,
+		services.NewMenuCacheService(logger, lib.NewRedis(config, logger)),
+		userRepository,
+		userRoleRepository,
+		roleRepository,
+		roleMenuRepository,
+		repository.NewMenuRepository(db, logger),
+		repository.NewMenuActionRepository(db, logger),
+		services.NewAuditService(logger, repository.NewAuditLogRepository(db, logger)),
+	)
+}
@@ -10,6 +10,7 @@ import (
 var (
 	configFile  string
 	casbinModel string
+	menuFile    string
 
 	StartCmd = &cobra.Command{
 		Use:          "runserver",
@@ -19,6 +20,9 @@ var (
 		PreRun: func(cmd *cobra.Command, args []string) {
 			lib.SetConfigPath(configFile)
 			lib.SetConfigCasbinModelPath(casbinModel)
+			if menuFile != "" {
+				lib.SetConfigMenuPath(menuFile)
+			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			runApplication()
@@ -32,6 +36,8 @@ func init() {
 		"config/config.yaml", "this parameter is used to start the service application.")
 	pf.StringVarP(&casbinModel, "casbin", "m",
 		"config/casbin_model.conf", "this parameter is used for the running configuration of casbin.")
+	pf.StringVarP(&menuFile, "menu", "f",
+		"", "this parameter is used to check the menu resources against the routes at startup.")
 
 	_ = cobra.MarkFlagRequired(pf, "config")
 	_ = cobra.MarkFlagRequired(pf, "casbin")
@@ -1,19 +1,21 @@
 package setup
 
 import (
+	"context"
+	"fmt"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 	"manuel71sj/go-api-template/api/repository"
 	"manuel71sj/go-api-template/api/services"
 	"manuel71sj/go-api-template/lib"
 	"manuel71sj/go-api-template/models"
-	"manuel71sj/go-api-template/pkg/file"
-	"os"
 )
 
 var (
 	configFile string
 	menuFile   string
+	sync       bool
+	prune      bool
+	dryRun     bool
 
 	StartCmd = &cobra.Command{
 		Use:          "setup",
@@ -33,25 +35,21 @@ var (
 				repository.NewMenuRepository(db, logger),
 				repository.NewMenuActionRepository(db, logger),
 				repository.NewMenuActionResourceRepository(db, logger),
+				repository.NewRoleMenuRepository(db, logger),
+				repository.NewMenuI18nRepository(db, logger),
+				services.NewMenuCacheService(logger, lib.NewRedis(config, logger)),
+				services.NewOutboxService(logger, repository.NewOutboxRepository(db, logger)),
+				services.NewAuditService(logger, repository.NewAuditLogRepository(db, logger)),
 			)
 
-			if !file.IsFile(menuFile) {
-				logger.Zap.Fatal("Menu file does not exist")
-			}
-
-			fs, err := os.Open(menuFile)
+			menuTrees, err := menuService.ReadMenuFile(menuFile)
 			if err != nil {
-				logger.Zap.Fatalf("Menu file could not be opened: %v", err)
+				logger.Zap.Fatalf("Menu file read error: %v", err)
 			}
 
-			defer func(fs *os.File) {
-				_ = fs.Close()
-			}(fs)
-
-			var menuTrees models.MenuTrees
-			yd := yaml.NewDecoder(fs)
-			if err := yd.Decode(&menuTrees); err != nil {
-				logger.Zap.Fatalf("Menu file decode error: %v", err)
+			if sync {
+				syncMenus(logger, db, menuService, menuTrees)
+				return
 			}
 
 			if err := menuService.CreateMenus("", menuTrees); err != nil {
@@ -69,8 +67,42 @@ func init() {
 		"config/config.yaml", "this parameter is used to start the service application.")
 	pf.StringVarP(&menuFile, "menu", "m",
 		"config/menu.yaml", "this parameter is used to set the initialized menu data.")
+	pf.BoolVar(&sync, "sync", false,
+		"update the existing menu data to match the menu file instead of only creating it.")
+	pf.BoolVar(&prune, "prune", false,
+		"with --sync, delete the menus, actions and resources that are not in the menu file.")
+	pf.BoolVar(&dryRun, "dry-run", false,
+		"with --sync, print the plan without applying it.")
 
 	_ = cobra.MarkFlagRequired(pf, "config")
 	_ = cobra.MarkFlagRequired(pf, "menu")
 
 }
+
+func syncMenus(logger lib.Logger, db lib.Database, menuService services.MenuService, menuTrees models.MenuTrees) {
+	plan, err := menuService.SyncMenus(menuTrees, prune, true)
+	if err != nil {
+		logger.Zap.Fatalf("Menu sync plan error: %v", err)
+	}
+
+	for _, change := range plan {
+		fmt.Println(change)
+	}
+
+	fmt.Printf("Plan: %d to create, %d to update, %d to delete.\n",
+		plan.Count(models.MenuSyncCreate), plan.Count(models.MenuSyncUpdate), plan.Count(models.MenuSyncDelete))
+
+	if dryRun || len(plan) == 0 {
+		return
+	}
+
+	err = db.Transaction(context.Background(), func(ctx context.Context) error {
+		_, err := menuService.WithContext(ctx).SyncMenus(menuTrees, prune, false)
+		return err
+	})
+	if err != nil {
+		logger.Zap.Fatalf("Menu sync error: %v", err)
+	}
+
+	logger.Zap.Info("Menu file sync successfully.")
+}
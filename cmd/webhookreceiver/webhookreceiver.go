@@ -0,0 +1,70 @@
+package webhookreceiver
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"manuel71sj/go-api-template/api/services"
+	"net/http"
+	"sync/atomic"
+)
+
+var (
+	addr   string
+	secret string
+	status int
+	fail   int64
+
+	StartCmd = &cobra.Command{
+		Use:          "webhook-receiver",
+		Short:        "Run a local webhook receiver that prints and verifies the deliveries",
+		Example:      "{execfile} webhook-receiver -a 127.0.0.1:9000 -s <webhook secret> --fail 2",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var received atomic.Int64
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				count := received.Add(1)
+
+				verified := "unchecked"
+				if secret != "" {
+					expected := services.SignWebhook(secret, r.Header.Get(services.WebhookHeaderTimestamp), body)
+					verified = "invalid"
+					if hmac.Equal([]byte(expected), []byte(r.Header.Get(services.WebhookHeaderSignature))) {
+						verified = "valid"
+					}
+				}
+
+				code := status
+				if verified == "invalid" {
+					code = http.StatusUnauthorized
+				} else if count <= fail {
+					code = http.StatusInternalServerError
+				}
+
+				fmt.Printf("#%d %s %s delivery=%s signature=%s -> %d\n%s\n",
+					count, r.Method, r.Header.Get(services.WebhookHeaderEvent),
+					r.Header.Get(services.WebhookHeaderDelivery), verified, code, body)
+
+				w.WriteHeader(code)
+			})
+
+			fmt.Printf("Webhook receiver listening on http://%s\n", addr)
+			return http.ListenAndServe(addr, handler)
+		},
+	}
+)
+
+func init() {
+	pf := StartCmd.PersistentFlags()
+	pf.StringVarP(&addr, "addr", "a",
+		"127.0.0.1:9000", "this parameter is used to set the listen address of the receiver.")
+	pf.StringVarP(&secret, "secret", "s",
+		"", "this parameter is used to verify the signatures, unchecked when empty.")
+	pf.IntVar(&status, "status",
+		http.StatusOK, "this parameter is used to set the response status.")
+	pf.Int64Var(&fail, "fail",
+		0, "this parameter is used to answer 500 to the first requests, to try the retries.")
+}
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"manuel71sj/go-api-template/bootstrap"
+	"manuel71sj/go-api-template/lib"
+)
+
+var (
+	configFile  string
+	casbinModel string
+
+	StartCmd = &cobra.Command{
+		Use:          "worker",
+		Short:        "Start job worker and cron scheduler",
+		Example:      "{execfile} worker -c config/config.yaml",
+		SilenceUsage: true,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			lib.SetConfigPath(configFile)
+			lib.SetConfigCasbinModelPath(casbinModel)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runWorker()
+		},
+	}
+)
+
+func init() {
+	pf := StartCmd.PersistentFlags()
+	pf.StringVarP(&configFile, "config", "c",
+		"config/config.yaml", "this parameter is used to start the job worker.")
+	pf.StringVarP(&casbinModel, "casbin", "m",
+		"config/casbin_model.conf", "this parameter is used for the running configuration of casbin.")
+
+	_ = cobra.MarkFlagRequired(pf, "config")
+}
+
+// runWorker runs the jobs and the cron jobs until the process is stopped, set Jobs.Embedded
+// to false so that runserver leaves the jobs to the workers
+func runWorker() {
+	fx.New(bootstrap.WorkerModule, fx.NopLogger).Run()
+}
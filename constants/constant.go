@@ -8,7 +8,8 @@ const CaptchaExpireTimes = 90
 
 const CurrentUser = "current-user"
 
-const RoutesCacheKey = "routes"
+const MenuCacheKeyPrefix = "menu-cache"
+const MenuCacheExpireTimes = 3600
 
 const RedisMainDB = 0
 const RedisTaskDB = 1
@@ -1,6 +0,0 @@
-package constants
-
-const (
-	// DBTransaction is database transaction handle set at router context
-	DBTransaction = "db_trx"
-)
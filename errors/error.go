@@ -20,6 +20,19 @@ var (
 var (
 	DatabaseInternalError  = errors.New("database internal error")
 	DatabaseRecordNotFound = errors.New("database record not found")
+	// DatabaseVersionConflict the record was changed since the version the update is based on
+	DatabaseVersionConflict = errors.New("database record was changed by another update")
+	// IfMatchInvalid the If-Match header is neither "*" nor the ETag of a record version
+	IfMatchInvalid = errors.New("if-match header is not a record etag")
+)
+
+// Query
+var (
+	SortKeyInvalid        = errors.New("sort key is not sortable")
+	FilterFieldInvalid    = errors.New("filter field is not filterable")
+	FilterOperatorInvalid = errors.New("filter operator is not supported by the field")
+	FilterValueInvalid    = errors.New("filter value is invalid")
+	CursorInvalid         = errors.New("cursor is invalid")
 )
 
 // Redis
@@ -0,0 +1,8 @@
+package errors
+
+var (
+	JobQueueUnknown   = New("job queue is not configured")
+	JobHandlerUnknown = New("job type has no handler")
+	// JobNotActive the job ran past its visibility timeout and was made ready again
+	JobNotActive = New("job is no longer active")
+)
@@ -5,4 +5,9 @@ var (
 	MenuAlreadyExists           = New("menu already exists")
 	MenuInvalidParent           = New("menu invalid parent")
 	MenuNotAllowDeleteWithChild = New("contains children, cannot be deleted")
+	MenuFileNotExist            = New("menu file does not exist")
+	MenuFileDecodeError         = New("menu file decode error")
+	MenuMoveDuplicate           = New("menu moved more than once")
+	MenuMoveCycle               = New("menu cannot be moved under itself")
+	MenuCodeDuplicate           = New("menu code is used by another menu")
 )
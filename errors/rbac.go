@@ -0,0 +1,13 @@
+package errors
+
+var (
+	RbacBundleVersionInvalid  = New("rbac bundle version is not supported")
+	RbacBundleDecodeError     = New("rbac bundle decode error")
+	RbacImportStrategyInvalid = New("rbac import strategy is not supported")
+	RbacRoleConflict          = New("rbac role already exists")
+	RbacUserConflict          = New("rbac user already has other roles")
+	RbacMenuNotFound          = New("rbac menu not found")
+	RbacActionNotFound        = New("rbac menu action not found")
+	RbacRoleNotFound          = New("rbac role not found")
+	RbacUserNotFound          = New("rbac user not found")
+)
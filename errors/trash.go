@@ -0,0 +1,10 @@
+package errors
+
+var (
+	TrashResourceInvalid   = New("trash resource is not supported")
+	TrashUsernameConflict  = New("username has been reused since the user was deleted")
+	TrashRoleNameConflict  = New("role name has been reused since the role was deleted")
+	TrashMenuNameConflict  = New("menu name has been reused under the same parent since the menu was deleted")
+	TrashMenuParentDeleted = New("parent menu is deleted, restore it first")
+	TrashUserRoleDeleted   = New("role of the user is deleted, restore it first")
+)
@@ -0,0 +1,6 @@
+package errors
+
+var (
+	WebhookEventInvalid = New("webhook event is not an event type, an aggregate wildcard or *")
+	WebhookIsDisable    = New("webhook is disabled")
+)
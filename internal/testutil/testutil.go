@@ -0,0 +1,64 @@
+// Package testutil builds the dependencies shared by the tests of the services and repositories.
+package testutil
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+	"manuel71sj/go-api-template/lib"
+	"manuel71sj/go-api-template/migrations"
+	"strconv"
+	"testing"
+)
+
+// NewLogger returns a logger discarding every entry
+func NewLogger() lib.Logger {
+	zapLogger := zap.NewNop()
+	return lib.Logger{Zap: zapLogger.Sugar(), DesugarZap: zapLogger}
+}
+
+// NewDatabase returns an in-memory SQLite database with every migration applied,
+// closed at the end of the test
+func NewDatabase(t testing.TB, logger lib.Logger) lib.Database {
+	t.Helper()
+
+	// a single connection keeps the in-memory database for the whole test
+	db := lib.NewDatabase(lib.Config{
+		Log: &lib.LogConfig{},
+		Database: &lib.DatabaseConfig{
+			Engine:       lib.DatabaseEngineSQLite,
+			Name:         "file::memory:",
+			TablePrefix:  "test",
+			MaxOpenConns: 1,
+			MaxIdleConns: 1,
+		},
+	}, logger)
+	t.Cleanup(func() { _ = db.Close() })
+
+	list, err := migrations.All(t.TempDir(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := lib.NewMigrator(db, logger, list)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// NewRedis starts an in-memory redis server, closed at the end of the test,
+// and returns it with the config connecting to it
+func NewRedis(t testing.TB) (*miniredis.Miniredis, *lib.RedisConfig) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	port, err := strconv.Atoi(server.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, &lib.RedisConfig{Host: server.Host(), Port: port, KeyPrefix: "test"}
+}
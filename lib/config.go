@@ -6,10 +6,13 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"manuel71sj/go-api-template/pkg/file"
+	"strings"
+	"time"
 )
 
 var configPath = "config/config.yml"
 var casbinModelPath = "config/casbin_model.conf"
+var menuPath = "config/menu.yaml"
 
 var defaultConfig = Config{
 	Name: "api-backend",
@@ -25,12 +28,67 @@ var defaultConfig = Config{
 	SuperAdmin: &SuperAdminConfig{},
 	Auth:       &AuthConfig{},
 	Casbin:     &CasbinConfig{Enable: false},
+	Menu:       &MenuConfig{},
+	I18n:       &I18nConfig{DefaultLocale: "ko", Locales: []string{"ko", "en"}},
 	Redis:      &RedisConfig{Host: "192.168.5.58", Port: 6379},
 	Database: &DatabaseConfig{
-		Parameters:   "charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true&timeout=5s",
+		Engine:       DatabaseEngineMySQL,
 		MaxLifetime:  7200,
 		MaxOpenConns: 150,
 		MaxIdleConns: 50,
+		StickyWindow: 5,
+	},
+	Outbox: &OutboxConfig{
+		Enable:      true,
+		Interval:    1000,
+		BatchSize:   100,
+		Retention:   168,
+		Sinks:       []string{OutboxSinkLog},
+		RedisStream: "events",
+		RedisMaxLen: 100000,
+		HttpTimeout: 5,
+		MaxAttempts: 10,
+		BackoffBase: 1,
+		BackoffMax:  300,
+	},
+	Webhook: &WebhookConfig{
+		Enable:      true,
+		Interval:    1000,
+		BatchSize:   50,
+		Timeout:     10,
+		MaxAttempts: 8,
+		BackoffBase: 10,
+		BackoffMax:  3600,
+	},
+	Jobs: &JobsConfig{
+		Embedded:      true,
+		PollInterval:  1000,
+		MaxAttempts:   5,
+		BackoffBase:   5,
+		BackoffMax:    600,
+		DeadRetention: 168,
+		Queues:        []*JobQueueConfig{{Name: JobQueueDefault, Concurrency: 4, Visibility: 300}},
+	},
+	Cron: &CronConfig{
+		Enable:  true,
+		Timeout: 600,
+	},
+	LoginLog: &LoginLogConfig{
+		Retention:    365,
+		DeviceWindow: 90,
+	},
+	Metrics: &MetricsConfig{
+		Enable:     false,
+		ListenAddr: "127.0.0.1:9100",
+		Path:       "/metrics",
+	},
+	Tracing: &TracingConfig{
+		Enable:      false,
+		Exporter:    TracingExporterStdout,
+		Endpoint:    "localhost:4318",
+		Insecure:    true,
+		File:        "./logs/traces.json",
+		SampleRatio: 1,
 	},
 }
 
@@ -42,8 +100,17 @@ type Config struct {
 	SuperAdmin *SuperAdminConfig `mapstructure:"SuperAdmin"`
 	Auth       *AuthConfig       `mapstructure:"Auth"`
 	Casbin     *CasbinConfig     `mapstructure:"Casbin"`
+	Menu       *MenuConfig       `mapstructure:"Menu"`
+	I18n       *I18nConfig       `mapstructure:"I18n"`
 	Redis      *RedisConfig      `mapstructure:"Redis"`
 	Database   *DatabaseConfig   `mapstructure:"Database"`
+	Outbox     *OutboxConfig     `mapstructure:"Outbox"`
+	Webhook    *WebhookConfig    `mapstructure:"Webhook"`
+	Jobs       *JobsConfig       `mapstructure:"Jobs"`
+	Cron       *CronConfig       `mapstructure:"Cron"`
+	LoginLog   *LoginLogConfig   `mapstructure:"LoginLog"`
+	Metrics    *MetricsConfig    `mapstructure:"Metrics"`
+	Tracing    *TracingConfig    `mapstructure:"Tracing"`
 }
 
 func NewConfig() Config {
@@ -59,6 +126,7 @@ func NewConfig() Config {
 	}
 
 	config.Casbin.Model = casbinModelPath
+	config.Menu.File = menuPath
 
 	return config
 }
@@ -79,6 +147,14 @@ func SetConfigCasbinModelPath(path string) {
 	casbinModelPath = path
 }
 
+func SetConfigMenuPath(path string) {
+	if !file.IsFile(path) {
+		panic("menu filepath does not exist")
+	}
+
+	menuPath = path
+}
+
 type HttpConfig struct {
 	Host string `mapstructure:"Host" validate:"ipv4"`
 	Port int    `mapstructure:"Port" validate:"gte=1,lte=65535"`
@@ -132,6 +208,42 @@ type CasbinConfig struct {
 	IgnorePathPrefixes []string `mapstructure:"IgnorePathPrefixes"`
 }
 
+type MenuConfig struct {
+	File string `mapstructure:"File"`
+}
+
+// I18nConfig
+// DefaultLocale : locale of the default menu and action names : default ko
+// Locales       : supported locales : default ko, en
+type I18nConfig struct {
+	DefaultLocale string   `mapstructure:"DefaultLocale"`
+	Locales       []string `mapstructure:"Locales"`
+}
+
+// Match returns the first supported locale of the candidates, "en-US" also matching "en",
+// and the default locale when none is supported
+func (c *I18nConfig) Match(candidates ...string) string {
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(candidate)
+
+		for _, locale := range c.Locales {
+			if strings.ToLower(locale) == candidate {
+				return locale
+			}
+		}
+
+		if i := strings.IndexAny(candidate, "-_"); i > 0 {
+			for _, locale := range c.Locales {
+				if strings.ToLower(locale) == candidate[:i] {
+					return locale
+				}
+			}
+		}
+	}
+
+	return c.DefaultLocale
+}
+
 type DatabaseConfig struct {
 	Engine      string `mapstructure:"Engine"`
 	Name        string `mapstructure:"Name"`
@@ -145,10 +257,87 @@ type DatabaseConfig struct {
 	MaxLifetime  int `mapstructure:"MaxLifetime"`
 	MaxOpenConns int `mapstructure:"MaxOpenConns"`
 	MaxIdleConns int `mapstructure:"MaxIdleConns"`
+
+	// Replicas serve the reads made outside of a transaction,
+	// StickyWindow is the seconds the reads of a client stay on the primary after its writes
+	Replicas     []*DatabaseReplicaConfig `mapstructure:"Replicas"`
+	StickyWindow int                      `mapstructure:"StickyWindow"`
+}
+
+// DatabaseReplicaConfig a read replica, empty fields are those of the primary
+type DatabaseReplicaConfig struct {
+	Host     string `mapstructure:"Host"`
+	Port     int    `mapstructure:"Port"`
+	Username string `mapstructure:"Username"`
+	Password string `mapstructure:"Password"`
+	Name     string `mapstructure:"Name"`
+}
+
+const (
+	DatabaseEngineMySQL    = "mysql"
+	DatabaseEnginePostgres = "postgres"
+	DatabaseEngineSQLite   = "sqlite"
+)
+
+// defaultDatabaseParameters are used when Parameters is empty
+var defaultDatabaseParameters = map[string]string{
+	DatabaseEngineMySQL:    "charset=utf8mb4&parseTime=True&loc=Local&allowNativePasswords=true&timeout=5s",
+	DatabaseEnginePostgres: "sslmode=disable connect_timeout=5",
+	DatabaseEngineSQLite:   "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+}
+
+func (c DatabaseConfig) Addr() string {
+	if c.Engine == DatabaseEngineSQLite {
+		return c.Name
+	}
+
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// Replica returns the config of the primary overridden by the fields set on the replica
+func (c DatabaseConfig) Replica(replica *DatabaseReplicaConfig) *DatabaseConfig {
+	config := c
+	config.Replicas = nil
+
+	if replica.Host != "" {
+		config.Host = replica.Host
+	}
+	if replica.Port != 0 {
+		config.Port = replica.Port
+	}
+	if replica.Username != "" {
+		config.Username = replica.Username
+	}
+	if replica.Password != "" {
+		config.Password = replica.Password
+	}
+	if replica.Name != "" {
+		config.Name = replica.Name
+	}
+
+	return &config
 }
 
+// DSN builds the data source name of the engine, for SQLite Name is the database file
 func (c DatabaseConfig) DSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", c.Username, c.Password, c.Host, c.Port, c.Name, c.Parameters)
+	parameters := c.Parameters
+	if parameters == "" {
+		parameters = defaultDatabaseParameters[c.Engine]
+	}
+
+	switch c.Engine {
+	case DatabaseEnginePostgres:
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s %s",
+			c.Host, c.Port, c.Username, c.Password, c.Name, parameters)
+	case DatabaseEngineSQLite:
+		if parameters == "" {
+			return c.Name
+		}
+
+		return fmt.Sprintf("%s?%s", c.Name, parameters)
+	default:
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", c.Username, c.Password, c.Host, c.Port, c.Name, parameters)
+	}
 }
 
 type RedisConfig struct {
@@ -161,3 +350,164 @@ type RedisConfig struct {
 func (c *RedisConfig) Addr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
+
+const (
+	OutboxSinkLog   = "log"
+	OutboxSinkRedis = "redis"
+	OutboxSinkHttp  = "http"
+)
+
+// OutboxConfig
+// Enable      : run the relay publishing the outbox events : default true
+// Interval    : milliseconds between two reads of the outbox : default 1000
+// BatchSize   : events read at once : default 100
+// Retention   : hours the published events are kept : default 168
+// Sinks       : log, redis, http : default log
+// RedisStream : stream of the redis sink, MAXLEN ~ RedisMaxLen : default events
+// HttpURL     : the http sink POSTs every event there, HttpTimeout in seconds : default 5
+// MaxAttempts : attempts before an event is dead : default 10
+// BackoffBase : seconds before the first retry, doubled at every retry up to BackoffMax : default 1, 300
+type OutboxConfig struct {
+	Enable      bool     `mapstructure:"Enable"`
+	Interval    int      `mapstructure:"Interval"`
+	BatchSize   int      `mapstructure:"BatchSize"`
+	Retention   int      `mapstructure:"Retention"`
+	Sinks       []string `mapstructure:"Sinks"`
+	RedisStream string   `mapstructure:"RedisStream"`
+	RedisMaxLen int64    `mapstructure:"RedisMaxLen"`
+	HttpURL     string   `mapstructure:"HttpURL"`
+	HttpTimeout int      `mapstructure:"HttpTimeout"`
+	MaxAttempts int      `mapstructure:"MaxAttempts"`
+	BackoffBase int      `mapstructure:"BackoffBase"`
+	BackoffMax  int      `mapstructure:"BackoffMax"`
+}
+
+// Backoff returns the delay after the failed attempt of an event
+func (c *OutboxConfig) Backoff(attempt int) time.Duration {
+	return backoff(c.BackoffBase, c.BackoffMax, attempt)
+}
+
+// WebhookConfig
+// Enable      : send the pending webhook deliveries : default true
+// Interval    : milliseconds between two reads of the due deliveries : default 1000
+// BatchSize   : deliveries sent at once : default 50
+// Timeout     : seconds to wait for the response of a webhook : default 10
+// MaxAttempts : attempts before a delivery is dead : default 8
+// BackoffBase : seconds before the first retry, doubled at every retry up to BackoffMax : default 10, 3600
+type WebhookConfig struct {
+	Enable      bool `mapstructure:"Enable"`
+	Interval    int  `mapstructure:"Interval"`
+	BatchSize   int  `mapstructure:"BatchSize"`
+	Timeout     int  `mapstructure:"Timeout"`
+	MaxAttempts int  `mapstructure:"MaxAttempts"`
+	BackoffBase int  `mapstructure:"BackoffBase"`
+	BackoffMax  int  `mapstructure:"BackoffMax"`
+}
+
+// Backoff returns the delay after the failed attempt of a delivery
+func (c *WebhookConfig) Backoff(attempt int) time.Duration {
+	return backoff(c.BackoffBase, c.BackoffMax, attempt)
+}
+
+// JobsConfig
+// Embedded      : runserver runs the job workers too, the worker command always runs them : default true
+// PollInterval  : milliseconds between two polls of an empty queue : default 1000
+// MaxAttempts   : attempts of a job before it is dead, unless set when enqueued : default 5
+// BackoffBase   : seconds before the first retry, doubled at every retry up to BackoffMax : default 5, 600
+// DeadRetention : hours the dead jobs are kept : default 168
+// Queues        : Concurrency jobs of a queue run at a time, a job not done after Visibility seconds runs again : default default, 4, 300
+type JobsConfig struct {
+	Embedded      bool              `mapstructure:"Embedded"`
+	PollInterval  int               `mapstructure:"PollInterval"`
+	MaxAttempts   int               `mapstructure:"MaxAttempts"`
+	BackoffBase   int               `mapstructure:"BackoffBase"`
+	BackoffMax    int               `mapstructure:"BackoffMax"`
+	DeadRetention int               `mapstructure:"DeadRetention"`
+	Queues        []*JobQueueConfig `mapstructure:"Queues"`
+}
+
+type JobQueueConfig struct {
+	Name        string `mapstructure:"Name"`
+	Concurrency int    `mapstructure:"Concurrency"`
+	Visibility  int    `mapstructure:"Visibility"`
+}
+
+// Backoff returns the delay after the failed attempt of a job
+func (c *JobsConfig) Backoff(attempt int) time.Duration {
+	return backoff(c.BackoffBase, c.BackoffMax, attempt)
+}
+
+// Queue returns the config of the queue named name
+func (c *JobsConfig) Queue(name string) (*JobQueueConfig, bool) {
+	for _, queue := range c.Queues {
+		if queue.Name == name {
+			return queue, true
+		}
+	}
+
+	return nil, false
+}
+
+// CronDisabled the schedule that disables a cron job
+const CronDisabled = "-"
+
+// CronConfig
+// Enable    : run the cron jobs, a run happens on the one instance taking its lock : default true
+// Timeout   : seconds a run may take at most : default 600
+// Schedules : cron expression of a job by name instead of its own, - disables the job : default empty
+type CronConfig struct {
+	Enable    bool              `mapstructure:"Enable"`
+	Timeout   int               `mapstructure:"Timeout"`
+	Schedules map[string]string `mapstructure:"Schedules"`
+}
+
+// LoginLogConfig
+// Retention    : days the login logs are kept, 0 keeps them : default 365
+// DeviceWindow : days of successful logins a device or a network is known from : default 90
+type LoginLogConfig struct {
+	Retention    int `mapstructure:"Retention"`
+	DeviceWindow int `mapstructure:"DeviceWindow"`
+}
+
+// MetricsConfig
+// Enable     : collect and serve the prometheus metrics : default false
+// ListenAddr : host:port of the metrics listener, apart from the API and its auth : default 127.0.0.1:9100
+// Path       : path of the metrics endpoint on the listener : default /metrics
+type MetricsConfig struct {
+	Enable     bool   `mapstructure:"Enable"`
+	ListenAddr string `mapstructure:"ListenAddr"`
+	Path       string `mapstructure:"Path"`
+}
+
+// TracingConfig
+// Enable      : trace the requests with opentelemetry : default false
+// Exporter    : otlp, stdout, file : default stdout
+// Endpoint    : host:port of the otlp/http collector, Headers are sent with the spans : default localhost:4318
+// Insecure    : send the spans to the collector over http instead of https : default true
+// File        : spans of the file exporter, a JSON per line : default ./logs/traces.json
+// SampleRatio : ratio of the new traces sampled, a request follows the decision of its traceparent : default 1
+type TracingConfig struct {
+	Enable      bool              `mapstructure:"Enable"`
+	Exporter    string            `mapstructure:"Exporter"`
+	Endpoint    string            `mapstructure:"Endpoint"`
+	Insecure    bool              `mapstructure:"Insecure"`
+	Headers     map[string]string `mapstructure:"Headers"`
+	File        string            `mapstructure:"File"`
+	SampleRatio float64           `mapstructure:"SampleRatio"`
+}
+
+// backoff doubles base seconds at every attempt after the first, up to max seconds
+func backoff(base, max, attempt int) time.Duration {
+	delay := time.Duration(base) * time.Second
+	limit := time.Duration(max) * time.Second
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		return limit
+	}
+
+	return delay
+}
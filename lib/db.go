@@ -1,28 +1,31 @@
 package lib
 
 import (
+	"context"
+	"fmt"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
+	"sync"
 	"time"
 )
 
 type Database struct {
-	ORM *gorm.DB
+	ORM      *gorm.DB
+	resolver *databaseResolver
 }
 
 // NewDatabase creates a new database instance.
 func NewDatabase(config Config, logger Logger) Database {
-	mc := mysql.Config{
-		DSN:                       config.Database.DSN(),
-		DefaultStringSize:         191,   // default length fro string type field
-		SkipInitializeWithVersion: false, // Automatic configuration based on version
-		DisableDatetimePrecision:  false, // Database before MYSQL 5.6 does not support
-		DontSupportRenameIndex:    true,
-		DontSupportRenameColumn:   true,
+	// the DSN carries the password, errors name the address instead
+	dialector, err := newDialector(config.Database.Engine, config.Database.DSN())
+	if err != nil {
+		logger.Zap.Fatalf("Error to open database[%s] connection: %v", config.Database.Addr(), err)
 	}
 
-	db, err := gorm.Open(mysql.New(mc), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		NowFunc: func() time.Time {
 			return time.Now().Local()
 		},
@@ -38,7 +41,12 @@ func NewDatabase(config Config, logger Logger) Database {
 		QueryFields: true,
 	})
 	if err != nil {
-		logger.Zap.Fatalf("Error to open database[%s] connection: %v", mc.DSN, err)
+		logger.Zap.Fatalf("Error to open database[%s] connection: %v", config.Database.Addr(), err)
+	}
+
+	resolver, err := newDatabaseResolver(db, config.Database, logger)
+	if err != nil {
+		logger.Zap.Fatalf("Error to set up database replicas: %v", err)
 	}
 
 	if config.Log.Level == "debug" {
@@ -46,5 +54,145 @@ func NewDatabase(config Config, logger Logger) Database {
 	}
 
 	logger.Zap.Info("Databases connection established")
-	return Database{ORM: db}
+	return Database{ORM: db, resolver: resolver}
+}
+
+// Primary returns a session whose reads never go to a replica
+func (d Database) Primary() *gorm.DB {
+	return d.ORM.Set(resolverPrimaryKey, true)
+}
+
+// WithContext returns the transaction carried by ctx, or the database bound to ctx
+// when there is none, so that the repositories join the transaction of the request
+func (d Database) WithContext(ctx context.Context) *gorm.DB {
+	if tx, ok := TransactionFromContext(ctx); ok {
+		return tx
+	}
+
+	return d.ORM.WithContext(ctx)
+}
+
+// Transaction runs fn in a transaction carried by the ctx given to fn, committed when fn
+// returns nil. Within the transaction of ctx it runs in a savepoint, so an error of fn
+// only rolls back the changes of fn, and its AfterCommit functions wait for the outer commit.
+func (d Database) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	var committed func()
+	err := d.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var txCtx context.Context
+		txCtx, committed = ContextWithCommitHooks(ContextWithTransaction(ctx, tx))
+		return fn(txCtx)
+	})
+	if err != nil {
+		return err
+	}
+
+	committed()
+	return nil
+}
+
+// Replicated reports whether reads may be served by replicas
+func (d Database) Replicated() bool {
+	return len(d.resolver.replicas) > 0
+}
+
+// ContextWithStickiness returns a copy of ctx whose reads stay on the primary until the time
+// until, pushed back by the sticky window at every write made with the copy. The returned
+// function reports that time, for the client to send it back with its next requests.
+func (d Database) ContextWithStickiness(ctx context.Context, until time.Time) (context.Context, func() time.Time) {
+	stickiness := new(databaseStickiness)
+	stickiness.until.Store(until.UnixNano())
+
+	return context.WithValue(ctx, databaseStickinessKey{}, stickiness), func() time.Time {
+		return time.Unix(0, stickiness.until.Load())
+	}
+}
+
+// Close stops the health checks of the replicas and closes the pools of the primary and of the replicas
+func (d Database) Close() error {
+	err := d.resolver.close()
+	if cerr := d.resolver.primary.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+type transactionKey struct{}
+
+// ContextWithTransaction returns a copy of ctx carrying the transaction tx
+func ContextWithTransaction(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, transactionKey{}, tx)
+}
+
+// TransactionFromContext returns the transaction carried by ctx
+func TransactionFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(transactionKey{}).(*gorm.DB)
+	return tx, ok
+}
+
+type commitHooksKey struct{}
+
+// commitHooks the functions to run once the transaction of a context committed
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// ContextWithCommitHooks returns a copy of ctx collecting the functions given to AfterCommit,
+// and the function to call once the transaction of the copy committed. That function runs them,
+// or hands them to the transaction of ctx when the copy is one of its savepoints.
+func ContextWithCommitHooks(ctx context.Context) (context.Context, func()) {
+	hooks := new(commitHooks)
+	committed := func() {
+		hooks.mu.Lock()
+		fns := hooks.fns
+		hooks.fns = nil
+		hooks.mu.Unlock()
+
+		for _, fn := range fns {
+			AfterCommit(ctx, fn)
+		}
+	}
+
+	return context.WithValue(ctx, commitHooksKey{}, hooks), committed
+}
+
+// AfterCommit runs fn once the transaction carried by ctx committed, fn is dropped
+// when the transaction rolls back. Without a transaction fn runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn()
+		return
+	}
+
+	hooks.mu.Lock()
+	hooks.fns = append(hooks.fns, fn)
+	hooks.mu.Unlock()
+}
+
+// Stats returns the pool statistics and the health of the primary and of the replicas
+func (d Database) Stats() []*DatabaseStat {
+	return d.resolver.stats()
+}
+
+// newDialector selects the gorm dialector of the configured engine
+func newDialector(engine, dsn string) (gorm.Dialector, error) {
+	switch engine {
+	case DatabaseEngineMySQL:
+		return mysql.New(mysql.Config{
+			DSN:                       dsn,
+			DefaultStringSize:         191,   // default length fro string type field
+			SkipInitializeWithVersion: false, // Automatic configuration based on version
+			DisableDatetimePrecision:  false, // Database before MYSQL 5.6 does not support
+			DontSupportRenameIndex:    true,
+			DontSupportRenameColumn:   true,
+		}), nil
+	case DatabaseEnginePostgres:
+		return postgres.Open(dsn), nil
+	case DatabaseEngineSQLite:
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database engine %q", engine)
+	}
 }
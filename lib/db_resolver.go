@@ -0,0 +1,261 @@
+package lib
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	replicaCheckInterval = 10 * time.Second
+	resolverPrimaryKey   = "resolver:primary"
+)
+
+// DatabaseStat pool statistics of the primary or of a replica
+type DatabaseStat struct {
+	Name    string      `json:"name"`
+	Addr    string      `json:"addr"`
+	Healthy bool        `json:"healthy"`
+	Error   string      `json:"error,omitempty"`
+	Stats   sql.DBStats `json:"stats"`
+}
+
+type databaseReplica struct {
+	addr    string
+	pool    *sql.DB
+	healthy atomic.Bool
+	lastErr atomic.Value
+}
+
+// databaseResolver routes the reads made outside of a transaction to a healthy replica.
+// Writes, reads in a transaction and the reads of a context within the sticky window after
+// one of its writes stay on the primary, so a client reads its own writes despite the
+// replication lag, while the other clients keep reading from the replicas.
+type databaseResolver struct {
+	primary      *sql.DB
+	primaryAddr  string
+	replicas     []*databaseReplica
+	stickyWindow time.Duration
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+type databaseStickinessKey struct{}
+
+// databaseStickiness the time, in unix nanoseconds, until which the reads of a client stay on the primary
+type databaseStickiness struct {
+	until atomic.Int64
+}
+
+func newDatabaseResolver(db *gorm.DB, config *DatabaseConfig, logger Logger) (*databaseResolver, error) {
+	primary, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &databaseResolver{
+		primary:      primary,
+		primaryAddr:  config.Addr(),
+		stickyWindow: time.Duration(config.StickyWindow) * time.Second,
+	}
+	setDatabasePool(primary, config)
+
+	for _, replicaConfig := range config.Replicas {
+		replica := config.Replica(replicaConfig)
+
+		dialector, err := newDialector(replica.Engine, replica.DSN())
+		if err != nil {
+			return nil, err
+		}
+
+		item := &databaseReplica{addr: replica.Addr()}
+		r.replicas = append(r.replicas, item)
+
+		// an unreachable replica is reported unhealthy instead of failing the start
+		replicaDB, err := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true})
+		if err != nil {
+			logger.Zap.Errorf("Error to open database replica[%s] connection: %v", item.addr, err)
+			item.lastErr.Store(err.Error())
+			continue
+		}
+
+		if item.pool, err = replicaDB.DB(); err != nil {
+			return nil, err
+		}
+		setDatabasePool(item.pool, replica)
+	}
+
+	if len(r.replicas) == 0 {
+		return r, nil
+	}
+
+	r.checkReplicas(logger)
+	r.stop = make(chan struct{})
+	go r.watchReplicas(logger)
+
+	for _, fn := range []func() error{
+		func() error { return db.Callback().Query().Before("*").Register("resolver:read", r.read) },
+		func() error { return db.Callback().Row().Before("*").Register("resolver:read", r.read) },
+		func() error { return db.Callback().Raw().Before("*").Register("resolver:read", r.readRaw) },
+		func() error { return db.Callback().Create().After("*").Register("resolver:write", r.write) },
+		func() error { return db.Callback().Update().After("*").Register("resolver:write", r.write) },
+		func() error { return db.Callback().Delete().After("*").Register("resolver:write", r.write) },
+	} {
+		if err := fn(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func setDatabasePool(pool *sql.DB, config *DatabaseConfig) {
+	pool.SetConnMaxLifetime(time.Duration(config.MaxLifetime) * time.Second)
+	pool.SetMaxOpenConns(config.MaxOpenConns)
+	pool.SetMaxIdleConns(config.MaxIdleConns)
+}
+
+func (r *databaseResolver) read(db *gorm.DB) {
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	} else if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	} else if _, ok := db.Statement.Settings.Load(resolverPrimaryKey); ok {
+		return
+	}
+
+	if replica := r.replica(db.Statement.Context); replica != nil {
+		db.Statement.ConnPool = replica.pool
+	}
+}
+
+func (r *databaseResolver) readRaw(db *gorm.DB) {
+	sqlText := strings.ToLower(strings.TrimSpace(db.Statement.SQL.String()))
+	if strings.HasPrefix(sqlText, "select") && !strings.HasSuffix(sqlText, "for update") {
+		r.read(db)
+		return
+	}
+
+	r.write(db)
+}
+
+// write keeps the reads of the context of db on the primary for the sticky window
+func (r *databaseResolver) write(db *gorm.DB) {
+	if stickiness := databaseStickinessFrom(db.Statement.Context); stickiness != nil {
+		stickiness.until.Store(time.Now().Add(r.stickyWindow).UnixNano())
+	}
+}
+
+// replica picks a random healthy replica, nil when the read of ctx must go to the primary
+func (r *databaseResolver) replica(ctx context.Context) *databaseReplica {
+	if stickiness := databaseStickinessFrom(ctx); stickiness != nil && time.Now().UnixNano() < stickiness.until.Load() {
+		return nil
+	}
+
+	healthy := make([]*databaseReplica, 0, len(r.replicas))
+	for _, replica := range r.replicas {
+		if replica.healthy.Load() {
+			healthy = append(healthy, replica)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	return healthy[rand.Intn(len(healthy))]
+}
+
+func databaseStickinessFrom(ctx context.Context) *databaseStickiness {
+	if ctx == nil {
+		return nil
+	}
+
+	stickiness, _ := ctx.Value(databaseStickinessKey{}).(*databaseStickiness)
+	return stickiness
+}
+
+// watchReplicas checks the health of the replicas at every interval until the resolver is closed
+func (r *databaseResolver) watchReplicas(logger Logger) {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.checkReplicas(logger)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// close stops the health checks and closes the pools of the replicas
+func (r *databaseResolver) close() error {
+	if r.stop != nil {
+		r.stopOnce.Do(func() { close(r.stop) })
+	}
+
+	var err error
+	for _, replica := range r.replicas {
+		if replica.pool == nil {
+			continue
+		}
+
+		if cerr := replica.pool.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func (r *databaseResolver) checkReplicas(logger Logger) {
+	for _, replica := range r.replicas {
+		if replica.pool == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		err := replica.pool.PingContext(ctx)
+		cancel()
+
+		replica.healthy.Store(err == nil)
+		if err != nil {
+			logger.Zap.Warnf("Database replica[%s] is unhealthy: %v", replica.addr, err)
+			replica.lastErr.Store(err.Error())
+		} else {
+			replica.lastErr.Store("")
+		}
+	}
+}
+
+func (r *databaseResolver) stats() []*DatabaseStat {
+	stats := []*DatabaseStat{{Name: "primary", Addr: r.primaryAddr, Healthy: true, Stats: r.primary.Stats()}}
+	if err := r.primary.Ping(); err != nil {
+		stats[0].Healthy = false
+		stats[0].Error = err.Error()
+	}
+
+	for i, replica := range r.replicas {
+		stat := &DatabaseStat{
+			Name:    fmt.Sprintf("replica-%d", i),
+			Addr:    replica.addr,
+			Healthy: replica.healthy.Load(),
+		}
+		if replica.pool != nil {
+			stat.Stats = replica.pool.Stats()
+		}
+		stat.Error, _ = replica.lastErr.Load().(string)
+
+		stats = append(stats, stat)
+	}
+
+	return stats
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/models/dto"
 	"manuel71sj/go-api-template/pkg/echox"
 	"manuel71sj/go-api-template/pkg/slice"
 	"net/http"
@@ -38,14 +39,23 @@ func (b *BinderWithValidation) Bind(i interface{}, ctx echo.Context) error {
 		return errors.New(err.(*echo.HTTPError).Message.(string))
 	}
 
-	if err := ctx.Validate(i); err != nil {
-		// Validate only provides verification function for struct.
-		// When the requested data type is not struct,
-		// the variable should be considered legal after the bind succeeds.
-		if reflect.TypeOf(i).Kind() != reflect.Struct {
-			return nil
-		}
+	// filter[field][operator] parameters are not struct fields, the query param keeps them as a list
+	if setter, ok := i.(interface{ SetFilters([]*dto.Filter) }); ok {
+		setter.SetFilters(dto.ParseFilterQuery(ctx.QueryParams()))
+	}
 
+	// Validate only provides verification function for struct, bound by pointer.
+	// When the requested data type is not struct,
+	// the variable should be considered legal after the bind succeeds.
+	t := reflect.TypeOf(i)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if err := ctx.Validate(i); err != nil {
 		var buf bytes.Buffer
 		var ferrs validator.ValidationErrors
 		if errors.As(err, &ferrs) {
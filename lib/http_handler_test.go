@@ -0,0 +1,39 @@
+package lib
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"manuel71sj/go-api-template/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBinderWithValidation(t *testing.T) {
+	zapLogger := zap.NewNop()
+	handler := NewHttpHandler(Logger{Zap: zapLogger.Sugar(), DesugarZap: zapLogger}, Config{})
+
+	tests := []struct {
+		body    string
+		invalid bool
+	}{
+		{body: `{"name":"menu","sequence":0,"icon":"","remark":"","hidden":0,"status":0}`},
+		{body: `{"name":"menu","hidden":-1,"status":1}`},
+		{body: `{"sequence":10}`, invalid: true},
+		{body: `{"name":"menu","status":2}`, invalid: true},
+		{body: `{"name":"menu","hidden":-2}`, invalid: true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		err := handler.Engine.NewContext(req, httptest.NewRecorder()).Bind(new(models.Menu))
+		if test.invalid && err == nil {
+			t.Errorf("menu %s was accepted", test.body)
+		} else if !test.invalid && err != nil {
+			t.Errorf("menu %s: %v", test.body, err)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"manuel71sj/go-api-template/constants"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/pkg/uuid"
+	"time"
+)
+
+// JobQueueDefault the queue of the jobs enqueued without JobOnQueue
+const JobQueueDefault = "default"
+
+// Job a unit of background work, Payload is the JSON of the value given to Enqueue
+type Job struct {
+	ID          string          `json:"id"`
+	Queue       string          `json:"queue"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// JobOption changes a job before it is enqueued
+type JobOption func(job *Job)
+
+// JobOnQueue enqueues the job on the queue name instead of the default queue
+func JobOnQueue(name string) JobOption {
+	return func(job *Job) { job.Queue = name }
+}
+
+// JobDelay runs the job once delay has passed
+func JobDelay(delay time.Duration) JobOption {
+	return func(job *Job) { job.RunAt = time.Now().Add(delay) }
+}
+
+// JobAt runs the job at t
+func JobAt(t time.Time) JobOption {
+	return func(job *Job) { job.RunAt = t }
+}
+
+// JobMaxAttempts overrides the attempts of the job before it is dead
+func JobMaxAttempts(n int) JobOption {
+	return func(job *Job) { job.MaxAttempts = n }
+}
+
+// JobQueue the redis queues of the jobs, on the task db of redis. A queue is made of:
+//   - ready     the list of the ids to run now
+//   - scheduled the ids to run later, scored by the run time
+//   - active    the ids being run, scored by the time they are run again if not done
+//   - dead      the ids that failed every attempt, scored by the time they died
+//   - attempts  the hash of the attempts of the ids taken at least once
+//
+// and a data key per job holding its JSON.
+type JobQueue struct {
+	config *JobsConfig
+	client *redis.Client
+	prefix string
+}
+
+// NewJobQueue creates a new job queue on the task db of redis
+func NewJobQueue(config Config, logger Logger) JobQueue {
+	addr := config.Redis.Addr()
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		DB:       constants.RedisTaskDB,
+		Password: config.Redis.Password,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := client.Ping(ctx).Result(); err != nil {
+		logger.Zap.Fatalf("Error to open redis[%s] task connection: %v", addr, err)
+	}
+
+	return JobQueue{
+		config: config.Jobs,
+		client: client,
+		prefix: config.Redis.KeyPrefix,
+	}
+}
+
+func (q JobQueue) queueKey(queue, name string) string {
+	return fmt.Sprintf("%s:jobs:%s:%s", q.prefix, queue, name)
+}
+
+func (q JobQueue) dataPrefix() string {
+	return fmt.Sprintf("%s:jobs:data:", q.prefix)
+}
+
+// enqueueScript writes the job ARGV[1] to the data key KEYS[1], then adds its id ARGV[3]
+// to the ready list KEYS[2], or to the scheduled set KEYS[3] when it runs at ARGV[2] later
+var enqueueScript = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 then
+	redis.call("ZADD", KEYS[3], ARGV[2], ARGV[3])
+else
+	redis.call("LPUSH", KEYS[2], ARGV[3])
+end
+return 1
+`)
+
+// dequeueScript takes the oldest id of the ready list KEYS[1] into the active set KEYS[2]
+// until ARGV[1], counts the attempt in the hash KEYS[3] and returns the data of the job
+// under the key prefix ARGV[2] with its attempts
+var dequeueScript = redis.NewScript(`
+local id = redis.call("RPOP", KEYS[1])
+if not id then
+	return false
+end
+local data = redis.call("GET", ARGV[2] .. id)
+if not data then
+	return false
+end
+redis.call("ZADD", KEYS[2], ARGV[1], id)
+local attempts = redis.call("HINCRBY", KEYS[3], id, 1)
+return {data, attempts}
+`)
+
+// promoteScript moves at most ARGV[2] ids due at ARGV[1] from the scheduled set KEYS[1] and
+// the ids not done in time from the active set KEYS[2] to the ready list KEYS[3], and drops
+// the ids of the dead set KEYS[4] that died before ARGV[3]
+var promoteScript = redis.NewScript(`
+local moved = 0
+for i = 1, 2 do
+	local ids = redis.call("ZRANGEBYSCORE", KEYS[i], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+	for _, id in ipairs(ids) do
+		redis.call("ZREM", KEYS[i], id)
+		redis.call("LPUSH", KEYS[3], id)
+		moved = moved + 1
+	end
+end
+redis.call("ZREMRANGEBYSCORE", KEYS[4], "-inf", ARGV[3])
+return moved
+`)
+
+// finishScript ends the run of the id ARGV[1] still in the active set KEYS[1]. Its attempts
+// are dropped from the hash KEYS[4] unless ARGV[5] is "1". An empty ARGV[2] deletes the data
+// key KEYS[2], otherwise ARGV[2] is written to it with the ttl ARGV[4] when positive and the
+// id is added to the set KEYS[3] with the score ARGV[3].
+var finishScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+if ARGV[5] ~= "1" then
+	redis.call("HDEL", KEYS[4], ARGV[1])
+end
+if ARGV[2] == "" then
+	redis.call("DEL", KEYS[2])
+	return 1
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[4])
+else
+	redis.call("SET", KEYS[2], ARGV[2])
+end
+redis.call("ZADD", KEYS[3], ARGV[3], ARGV[1])
+return 1
+`)
+
+// Enqueue adds a job of jobType with the JSON of payload, it returns the id of the job
+func (q JobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, opts ...JobOption) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	job := &Job{
+		ID:          uuid.MustString(),
+		Queue:       JobQueueDefault,
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: q.config.MaxAttempts,
+		EnqueuedAt:  time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	if _, ok := q.config.Queue(job.Queue); !ok {
+		return "", errors.Wrapf(errors.JobQueueUnknown, "queue %s", job.Queue)
+	}
+
+	body, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	var runAt int64
+	if job.RunAt.After(time.Now()) {
+		runAt = job.RunAt.UnixMilli()
+	}
+
+	keys := []string{q.dataPrefix() + job.ID, q.queueKey(job.Queue, "ready"), q.queueKey(job.Queue, "scheduled")}
+	if err := enqueueScript.Run(ctx, q.client, keys, body, runAt, job.ID).Err(); err != nil {
+		return "", err
+	}
+
+	return job.ID, nil
+}
+
+// Dequeue takes the next ready job of queue, which runs again after visibility unless
+// Ack, Retry or Kill ends it first. It returns nil when no job is ready.
+func (q JobQueue) Dequeue(ctx context.Context, queue string, visibility time.Duration) (*Job, error) {
+	keys := []string{q.queueKey(queue, "ready"), q.queueKey(queue, "active"), q.queueKey(queue, "attempts")}
+	deadline := time.Now().Add(visibility).UnixMilli()
+
+	// the attempt is counted with the take, before the job runs, so a job that never ends still dies
+	values, err := dequeueScript.Run(ctx, q.client, keys, deadline, q.dataPrefix()).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	data, _ := values[0].(string)
+	attempts, _ := values[1].(int64)
+
+	job := new(Job)
+	if err := json.Unmarshal([]byte(data), job); err != nil {
+		return nil, err
+	}
+	job.Attempts = int(attempts)
+
+	return job, nil
+}
+
+// Promote makes the due scheduled jobs and the active jobs not done in time of queue ready,
+// and drops the dead jobs older than the retention. It returns the number of jobs made ready.
+func (q JobQueue) Promote(ctx context.Context, queue string, limit int) (int, error) {
+	keys := []string{
+		q.queueKey(queue, "scheduled"), q.queueKey(queue, "active"),
+		q.queueKey(queue, "ready"), q.queueKey(queue, "dead"),
+	}
+	now := time.Now()
+	deadBefore := now.Add(-q.deadRetention()).UnixMilli()
+
+	return promoteScript.Run(ctx, q.client, keys, now.UnixMilli(), limit, deadBefore).Int()
+}
+
+// Ack ends the job as done
+func (q JobQueue) Ack(ctx context.Context, job *Job) error {
+	return q.finish(ctx, job, "", 0, 0, false)
+}
+
+// Retry schedules the job to run again after delay, with the error of the attempt
+func (q JobQueue) Retry(ctx context.Context, job *Job, delay time.Duration, cause error) error {
+	job.LastError = cause.Error()
+	return q.finish(ctx, job, "scheduled", time.Now().Add(delay).UnixMilli(), 0, true)
+}
+
+// Kill ends the job as dead, it is kept for the dead retention
+func (q JobQueue) Kill(ctx context.Context, job *Job, cause error) error {
+	job.LastError = cause.Error()
+	return q.finish(ctx, job, "dead", time.Now().UnixMilli(), q.deadRetention().Milliseconds(), false)
+}
+
+// finish removes the job from the active set, then deletes its data when set is empty,
+// otherwise writes it and adds it to set with score. The attempts of the job are counted on
+// only when retry. It returns JobNotActive when the job was made ready again after its
+// visibility timeout, the outcome is then dropped.
+func (q JobQueue) finish(ctx context.Context, job *Job, set string, score, ttl int64, retry bool) error {
+	var body string
+	if set != "" {
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	}
+
+	keep := "0"
+	if retry {
+		keep = "1"
+	}
+
+	keys := []string{
+		q.queueKey(job.Queue, "active"), q.dataPrefix() + job.ID,
+		q.queueKey(job.Queue, set), q.queueKey(job.Queue, "attempts"),
+	}
+	finished, err := finishScript.Run(ctx, q.client, keys, job.ID, body, score, ttl, keep).Int()
+	if err != nil {
+		return err
+	} else if finished == 0 {
+		return errors.JobNotActive
+	}
+
+	return nil
+}
+
+// Dead returns the dead jobs of queue, the latest first
+func (q JobQueue) Dead(ctx context.Context, queue string, limit int64) ([]*Job, error) {
+	ids, err := q.client.ZRevRange(ctx, q.queueKey(queue, "dead"), 0, limit-1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for index, id := range ids {
+		keys[index] = q.dataPrefix() + id
+	}
+
+	values, err := q.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, value := range values {
+		if data, ok := value.(string); ok {
+			job := new(Job)
+			if err := json.Unmarshal([]byte(data), job); err == nil {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+func (q JobQueue) deadRetention() time.Duration {
+	return time.Duration(q.config.DeadRetention) * time.Hour
+}
+
+func (q JobQueue) Close() error {
+	return q.client.Close()
+}
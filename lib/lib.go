@@ -9,5 +9,8 @@ var Module = fx.Options(
 	fx.Provide(NewLogger),
 	fx.Provide(NewDatabase),
 	fx.Provide(NewRedis),
+	fx.Provide(NewJobQueue),
 	fx.Provide(NewCaptcha),
+	fx.Provide(NewMetrics),
+	fx.Provide(NewTracing),
 )
@@ -1,6 +1,8 @@
 package lib
 
 import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -58,6 +60,30 @@ func NewLogger(config Config) Logger {
 	return Logger{Zap: logger.Sugar(), DesugarZap: logger}
 }
 
+// WithContext returns the logger adding the trace and span ids of the span of ctx to its lines
+func (l Logger) WithContext(ctx context.Context) Logger {
+	fields := TraceFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+
+	logger := l.DesugarZap.With(fields...)
+	return Logger{Zap: logger.Sugar(), DesugarZap: logger}
+}
+
+// TraceFields returns the trace and span ids of the span of ctx, none outside of a trace
+func TraceFields(ctx context.Context) []zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("trace_id", spanContext.TraceID().String()),
+		zap.String("span_id", spanContext.SpanID().String()),
+	}
+}
+
 func toWriter(config Config) zapcore.WriteSyncer {
 	fp := ""
 	sp := string(filepath.Separator)
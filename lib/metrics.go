@@ -0,0 +1,309 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	metricsDatabaseStartKey = "metrics:start"
+
+	MetricsDecisionAllow = "allow"
+	MetricsDecisionDeny  = "deny"
+	MetricsDecisionError = "error"
+)
+
+// Metrics the prometheus metrics of the http requests, of the database and redis commands,
+// of the casbin decisions and of the captchas and logins, on a registry of their own, served
+// on a listener of their own. When disabled nothing is instrumented and the observations are dropped.
+type Metrics struct {
+	enable   bool
+	logger   Logger
+	registry *prometheus.Registry
+	server   *http.Server
+
+	httpDuration     *prometheus.HistogramVec
+	databaseDuration *prometheus.HistogramVec
+	databaseErrors   *prometheus.CounterVec
+	redisDuration    *prometheus.HistogramVec
+	redisErrors      *prometheus.CounterVec
+	enforceDuration  prometheus.Histogram
+	enforceDecisions *prometheus.CounterVec
+	captchas         *prometheus.CounterVec
+	logins           *prometheus.CounterVec
+}
+
+// NewMetrics creates the metrics, and instruments the database with gorm callbacks and
+// the redis clients of the cache and of the job queue with hooks
+func NewMetrics(config Config, logger Logger, db Database, cache Redis, jobQueue JobQueue) Metrics {
+	m := Metrics{enable: config.Metrics.Enable, logger: logger}
+	if !m.enable {
+		return m
+	}
+
+	m.registry = prometheus.NewRegistry()
+
+	mux := http.NewServeMux()
+	mux.Handle(config.Metrics.Path, m.Handler())
+	m.server = &http.Server{Addr: config.Metrics.ListenAddr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	m.httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "http_request_duration_seconds",
+		Help: "Duration of the http requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+	m.databaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "gorm_query_duration_seconds",
+		Help: "Duration of the database statements by operation and table.",
+	}, []string{"operation", "table"})
+	m.databaseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gorm_query_errors_total",
+		Help: "Database statements failed by operation and table, record not found excluded.",
+	}, []string{"operation", "table"})
+	m.redisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "redis_command_duration_seconds",
+		Help: "Duration of the redis commands by client and command, a pipeline as a whole.",
+	}, []string{"client", "command"})
+	m.redisErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "redis_command_errors_total",
+		Help: "Redis commands failed by client and command, nil replies excluded.",
+	}, []string{"client", "command"})
+	m.enforceDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "casbin_enforce_duration_seconds",
+		Help: "Duration of the casbin enforcements.",
+	})
+	m.enforceDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "casbin_enforce_decisions_total",
+		Help: "Casbin enforcements by decision, allow, deny or error.",
+	}, []string{"decision"})
+	m.captchas = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "captcha_total",
+		Help: "Captchas generated, and verified by result.",
+	}, []string{"action", "result"})
+	m.logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "login_events_total",
+		Help: "Logins, logouts, token refreshes and captcha checks of the users by outcome.",
+	}, []string{"event", "outcome"})
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.databaseDuration,
+		m.databaseErrors,
+		m.redisDuration,
+		m.redisErrors,
+		m.enforceDuration,
+		m.enforceDecisions,
+		m.captchas,
+		m.logins,
+	)
+
+	// the pools of the primary and of the replicas, as named by the database stats
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db.resolver.primary, "primary"))
+	for i, replica := range db.resolver.replicas {
+		if replica.pool != nil {
+			m.registry.MustRegister(collectors.NewDBStatsCollector(replica.pool, fmt.Sprintf("replica-%d", i)))
+		}
+	}
+
+	if err := m.instrumentDatabase(db.ORM); err != nil {
+		logger.Zap.Errorf("Error to instrument database metrics: %v", err)
+	}
+
+	cache.client.AddHook(metricsRedisHook{metrics: m, client: "main"})
+	jobQueue.client.AddHook(metricsRedisHook{metrics: m, client: "jobs"})
+
+	return m
+}
+
+// Enabled reports whether the metrics are collected and served
+func (m Metrics) Enabled() bool {
+	return m.enable
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func (m Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Start serves the metrics on the metrics listener in the background until Shutdown
+func (m Metrics) Start() {
+	if !m.enable {
+		return
+	}
+
+	go func() {
+		m.logger.Zap.Infof("Serving metrics on %s", m.server.Addr)
+		if err := m.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.logger.Zap.Errorf("Error to serve metrics: %v", err)
+		}
+	}()
+}
+
+// Shutdown stops serving the metrics
+func (m Metrics) Shutdown(ctx context.Context) error {
+	if !m.enable {
+		return nil
+	}
+
+	return m.server.Shutdown(ctx)
+}
+
+// ObserveHttp records a request answered with status, route is the template of the matched route
+func (m Metrics) ObserveHttp(method, route string, status int, duration time.Duration) {
+	if !m.enable {
+		return
+	}
+
+	m.httpDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveEnforce records a casbin enforcement and its decision
+func (m Metrics) ObserveEnforce(ok bool, err error, duration time.Duration) {
+	if !m.enable {
+		return
+	}
+
+	decision := MetricsDecisionAllow
+	if err != nil {
+		decision = MetricsDecisionError
+	} else if !ok {
+		decision = MetricsDecisionDeny
+	}
+
+	m.enforceDuration.Observe(duration.Seconds())
+	m.enforceDecisions.WithLabelValues(decision).Inc()
+}
+
+// CaptchaGenerated counts a generated captcha, result is the error of the generation
+func (m Metrics) CaptchaGenerated(err error) {
+	if !m.enable {
+		return
+	}
+
+	m.captchas.WithLabelValues("generate", metricsResult(err == nil)).Inc()
+}
+
+// CaptchaVerified counts a verified captcha, ok when the answer matched
+func (m Metrics) CaptchaVerified(ok bool) {
+	if !m.enable {
+		return
+	}
+
+	m.captchas.WithLabelValues("verify", metricsResult(ok)).Inc()
+}
+
+// LoginEvent counts a login event of a user with its outcome
+func (m Metrics) LoginEvent(event, outcome string) {
+	if !m.enable {
+		return
+	}
+
+	m.logins.WithLabelValues(event, outcome).Inc()
+}
+
+func metricsResult(ok bool) string {
+	if ok {
+		return "success"
+	}
+
+	return "failure"
+}
+
+// instrumentDatabase times every statement of db by callbacks around those of gorm
+func (m Metrics) instrumentDatabase(db *gorm.DB) error {
+	before := func(db *gorm.DB) {
+		db.InstanceSet(metricsDatabaseStartKey, time.Now())
+	}
+
+	after := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			value, ok := db.InstanceGet(metricsDatabaseStartKey)
+			if !ok {
+				return
+			}
+
+			table := db.Statement.Table
+			m.databaseDuration.WithLabelValues(operation, table).Observe(time.Since(value.(time.Time)).Seconds())
+			if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+				m.databaseErrors.WithLabelValues(operation, table).Inc()
+			}
+		}
+	}
+
+	callback := db.Callback()
+	for _, fn := range []func() error{
+		func() error { return callback.Create().Before("gorm:create").Register("metrics:before", before) },
+		func() error { return callback.Create().After("gorm:create").Register("metrics:after", after("create")) },
+		func() error { return callback.Query().Before("gorm:query").Register("metrics:before", before) },
+		func() error { return callback.Query().After("gorm:query").Register("metrics:after", after("query")) },
+		func() error { return callback.Update().Before("gorm:update").Register("metrics:before", before) },
+		func() error { return callback.Update().After("gorm:update").Register("metrics:after", after("update")) },
+		func() error { return callback.Delete().Before("gorm:delete").Register("metrics:before", before) },
+		func() error { return callback.Delete().After("gorm:delete").Register("metrics:after", after("delete")) },
+		func() error { return callback.Row().Before("gorm:row").Register("metrics:before", before) },
+		func() error { return callback.Row().After("gorm:row").Register("metrics:after", after("row")) },
+		func() error { return callback.Raw().Before("gorm:raw").Register("metrics:before", before) },
+		func() error { return callback.Raw().After("gorm:raw").Register("metrics:after", after("raw")) },
+	} {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type metricsRedisStartKey struct{}
+
+// metricsRedisHook times the commands of a redis client
+type metricsRedisHook struct {
+	metrics Metrics
+	client  string
+}
+
+func (h metricsRedisHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, metricsRedisStartKey{}, time.Now()), nil
+}
+
+func (h metricsRedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.observe(ctx, cmd.FullName(), cmd.Err())
+	return nil
+}
+
+func (h metricsRedisHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, metricsRedisStartKey{}, time.Now()), nil
+}
+
+func (h metricsRedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+
+	h.observe(ctx, "pipeline", err)
+	return nil
+}
+
+func (h metricsRedisHook) observe(ctx context.Context, command string, err error) {
+	start, ok := ctx.Value(metricsRedisStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	h.metrics.redisDuration.WithLabelValues(h.client, command).Observe(time.Since(start).Seconds())
+	if err != nil && err != redis.Nil {
+		h.metrics.redisErrors.WithLabelValues(h.client, command).Inc()
+	}
+}
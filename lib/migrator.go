@@ -0,0 +1,374 @@
+package lib
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	migrationLockID      = 1
+	migrationLockTimeout = 10 * time.Minute
+	migrationLockRefresh = time.Minute
+	migrationLockWait    = 2 * time.Minute
+)
+
+// Migration a versioned schema change, Version is a sortable timestamp like 20231001000000
+type Migration struct {
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus a known migration and when it was applied, nil when pending
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+}
+
+// SchemaMigration a row of the migration history
+type SchemaMigration struct {
+	Version   string    `gorm:"column:version;size:32;primaryKey;"`
+	Name      string    `gorm:"column:name;not null;"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null;"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// SchemaMigrationLock the single row held while migrating, so concurrent deploys wait for each other
+type SchemaMigrationLock struct {
+	ID       int       `gorm:"column:id;primaryKey;autoIncrement:false;"`
+	LockedBy string    `gorm:"column:locked_by;not null;"`
+	LockedAt time.Time `gorm:"column:locked_at;not null;"`
+}
+
+func (SchemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Migrator applies and reverts migrations and records them in schema_migrations
+type Migrator struct {
+	db         Database
+	logger     Logger
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator of the migrations, sorted by version
+func NewMigrator(db Database, logger Logger, migrations []Migration) (Migrator, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return Migrator{}, fmt.Errorf("duplicate migration version %s", sorted[i].Version)
+		}
+	}
+
+	if err := db.Primary().AutoMigrate(&SchemaMigration{}, &SchemaMigrationLock{}); err != nil {
+		return Migrator{}, err
+	}
+
+	return Migrator{db: db, logger: logger, migrations: sorted}, nil
+}
+
+// Status lists every known migration with its applied time
+func (m Migrator) Status() ([]*MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := &MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if v, ok := applied[migration.Version]; ok {
+			appliedAt := v.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+
+		list = append(list, status)
+	}
+
+	return list, nil
+}
+
+// Up applies every pending migration in version order
+func (m Migrator) Up() error {
+	return m.withLock(func() error {
+		return m.up("")
+	})
+}
+
+// Down reverts the last steps applied migrations
+func (m Migrator) Down(steps int) error {
+	return m.withLock(func() error {
+		return m.down(steps, "")
+	})
+}
+
+// To migrates up or down so that version is the last applied migration,
+// "0" reverts all of them
+func (m Migrator) To(version string) error {
+	if version != "0" && !m.exists(version) {
+		return fmt.Errorf("unknown migration version %s", version)
+	}
+
+	return m.withLock(func() error {
+		if err := m.up(version); err != nil {
+			return err
+		}
+
+		return m.down(-1, version)
+	})
+}
+
+func (m Migrator) up(target string) error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if target != "" && migration.Version > target {
+			break
+		} else if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		m.logger.Zap.Infof("Applying migration %s_%s", migration.Version, migration.Name)
+
+		err := m.db.Primary().Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// down reverts at most steps migrations (all when negative) newer than target
+func (m Migrator) down(steps int, target string) error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0 && steps != 0; i-- {
+		migration := m.migrations[i]
+		if target != "" && migration.Version <= target {
+			break
+		} else if _, ok := applied[migration.Version]; !ok {
+			continue
+		} else if migration.Down == nil {
+			return fmt.Errorf("migration %s_%s is not reversible", migration.Version, migration.Name)
+		}
+
+		m.logger.Zap.Infof("Reverting migration %s_%s", migration.Version, migration.Name)
+
+		err := m.db.Primary().Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+
+			return tx.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		steps--
+	}
+
+	return nil
+}
+
+func (m Migrator) applied() (map[string]*SchemaMigration, error) {
+	var list []*SchemaMigration
+	if err := m.db.Primary().Order("version").Find(&list).Error; err != nil {
+		return nil, err
+	}
+
+	mApplied := make(map[string]*SchemaMigration)
+	for _, item := range list {
+		mApplied[item.Version] = item
+	}
+
+	return mApplied, nil
+}
+
+func (m Migrator) exists(version string) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withLock runs fn while holding the migration lock. The lock is the row with the
+// primary key migrationLockID, inserting it fails on every engine while another
+// process holds it. The holder refreshes the lock every migrationLockRefresh while fn
+// runs, a lock not refreshed for migrationLockTimeout is considered abandoned.
+func (m Migrator) withLock(fn func() error) error {
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	deadline := time.Now().Add(migrationLockWait)
+
+	for {
+		m.db.Primary().Where("id = ? AND locked_at < ?", migrationLockID, time.Now().Add(-migrationLockTimeout)).
+			Delete(&SchemaMigrationLock{})
+
+		lock := &SchemaMigrationLock{ID: migrationLockID, LockedBy: owner, LockedAt: time.Now()}
+		if err := m.db.Primary().Create(lock).Error; err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("migration lock is held by another process, see table %s", lock.TableName())
+		}
+
+		m.logger.Zap.Info("Waiting for the migration lock")
+		time.Sleep(2 * time.Second)
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go m.refreshLock(owner, stop, stopped)
+
+	defer func() {
+		close(stop)
+		<-stopped
+		m.db.Primary().Where("id = ? AND locked_by = ?", migrationLockID, owner).Delete(&SchemaMigrationLock{})
+	}()
+
+	return fn()
+}
+
+// refreshLock writes the time of the lock held by owner every migrationLockRefresh until stop is closed
+func (m Migrator) refreshLock(owner string, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(migrationLockRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		result := m.db.Primary().Model(&SchemaMigrationLock{}).
+			Where("id = ? AND locked_by = ?", migrationLockID, owner).
+			Update("locked_at", time.Now())
+		if result.Error != nil {
+			m.logger.Zap.Warnf("Migration lock refresh error: %v", result.Error)
+		} else if result.RowsAffected == 0 {
+			m.logger.Zap.Warnf("Migration lock of %s was taken by another process", owner)
+		}
+	}
+}
+
+// LoadSQLMigrations reads the migrations of dir, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Statements end with ";" at the end of a line and
+// "{prefix}" is replaced by the table prefix.
+func LoadSQLMigrations(dir, tablePrefix string) ([]Migration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		base := strings.TrimSuffix(filepath.Base(file), ".up.sql")
+		version, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+
+		up, err := readSQLStatements(file, tablePrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := Migration{Version: version, Name: name, Up: execSQLStatements(up)}
+
+		downFile := filepath.Join(dir, base+".down.sql")
+		if _, err := os.Stat(downFile); err == nil {
+			down, err := readSQLStatements(downFile, tablePrefix)
+			if err != nil {
+				return nil, err
+			}
+
+			migration.Down = execSQLStatements(down)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, nil
+}
+
+func readSQLStatements(file, tablePrefix string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	var statement strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.ReplaceAll(statement.String(), "{prefix}", tablePrefix))
+			statement.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(statement.String()); rest != "" {
+		statements = append(statements, strings.ReplaceAll(rest, "{prefix}", tablePrefix))
+	}
+
+	return statements, nil
+}
+
+func execSQLStatements(statements []string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
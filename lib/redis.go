@@ -7,6 +7,7 @@ import (
 	"github.com/go-redis/redis/v8"
 	"manuel71sj/go-api-template/constants"
 	"manuel71sj/go-api-template/errors"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +15,7 @@ type Redis struct {
 	cache  *cache.Cache
 	client *redis.Client
 	prefix string
+	ctx    context.Context
 }
 
 // NewRedis creates a new redis client instance
@@ -38,6 +40,7 @@ func NewRedis(config Config, logger Logger) Redis {
 	return Redis{
 		client: client,
 		prefix: config.Redis.KeyPrefix,
+		ctx:    context.Background(),
 		cache: cache.New(&cache.Options{
 			Redis:      client,
 			LocalCache: cache.NewTinyLFU(1000, time.Minute),
@@ -45,13 +48,19 @@ func NewRedis(config Config, logger Logger) Redis {
 	}
 }
 
+// WithContext binds the commands to ctx, they are traced as children of its span
+func (r Redis) WithContext(ctx context.Context) Redis {
+	r.ctx = ctx
+	return r
+}
+
 func (r Redis) wrapperKey(key string) string {
 	return fmt.Sprintf("%s:%s", r.prefix, key)
 }
 
 func (r Redis) Set(key string, value interface{}, expiration time.Duration) error {
 	return r.cache.Set(&cache.Item{
-		Ctx:            context.TODO(),
+		Ctx:            r.ctx,
 		Key:            r.wrapperKey(key),
 		Value:          value,
 		TTL:            expiration,
@@ -59,8 +68,19 @@ func (r Redis) Set(key string, value interface{}, expiration time.Duration) erro
 	})
 }
 
+// SetLocal is Set that also keeps the value in the local TinyLFU cache,
+// only for keys whose value never changes once written
+func (r Redis) SetLocal(key string, value interface{}, expiration time.Duration) error {
+	return r.cache.Set(&cache.Item{
+		Ctx:   r.ctx,
+		Key:   r.wrapperKey(key),
+		Value: value,
+		TTL:   expiration,
+	})
+}
+
 func (r Redis) Get(key string, value interface{}) error {
-	err := r.cache.Get(context.TODO(), r.wrapperKey(key), value)
+	err := r.cache.Get(r.ctx, r.wrapperKey(key), value)
 	if errors.Is(err, cache.ErrCacheMiss) {
 		err = errors.RedisKeyNoExist
 	}
@@ -74,7 +94,7 @@ func (r Redis) Delete(keys ...string) (bool, error) {
 		wrapperKeys[index] = r.wrapperKey(key)
 	}
 
-	cmd := r.client.Del(context.TODO(), wrapperKeys...)
+	cmd := r.client.Del(r.ctx, wrapperKeys...)
 	if err := cmd.Err(); err != nil {
 		return false, err
 	}
@@ -88,7 +108,7 @@ func (r Redis) Check(keys ...string) (bool, error) {
 		wrapperKeys[index] = r.wrapperKey(key)
 	}
 
-	cmd := r.client.Exists(context.TODO(), wrapperKeys...)
+	cmd := r.client.Exists(r.ctx, wrapperKeys...)
 	if err := cmd.Err(); err != nil {
 		return false, err
 	}
@@ -96,6 +116,102 @@ func (r Redis) Check(keys ...string) (bool, error) {
 	return cmd.Val() > 0, nil
 }
 
+func (r Redis) Incr(key string) (int64, error) {
+	return r.client.Incr(r.ctx, r.wrapperKey(key)).Result()
+}
+
+// GetInts returns the integer values of keys, 0 for the keys that do not exist
+func (r Redis) GetInts(keys ...string) ([]int64, error) {
+	wrapperKeys := make([]string, len(keys))
+	for index, key := range keys {
+		wrapperKeys[index] = r.wrapperKey(key)
+	}
+
+	values, err := r.client.MGet(r.ctx, wrapperKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ints := make([]int64, len(values))
+	for index, value := range values {
+		if v, ok := value.(string); ok {
+			ints[index], _ = strconv.ParseInt(v, 10, 64)
+		}
+	}
+
+	return ints, nil
+}
+
+// leaseScript extends the lease of the owner ARGV[1] or takes the free lease, for ARGV[2] milliseconds
+var leaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease only if ARGV[1] still owns it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lease takes or extends the lease key for owner, it reports whether owner holds the lease
+func (r Redis) Lease(key, owner string, ttl time.Duration) (bool, error) {
+	result, err := leaseScript.Run(r.ctx, r.client, []string{r.wrapperKey(key)}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return result == 1, nil
+}
+
+// ReleaseLease gives the lease key up if owner holds it
+func (r Redis) ReleaseLease(key, owner string) error {
+	return releaseScript.Run(r.ctx, r.client, []string{r.wrapperKey(key)}, owner).Err()
+}
+
+// claimScript takes the lock KEYS[1] for the owner ARGV[1] for ARGV[3] milliseconds, unless the
+// slot ARGV[2] is not after the last claimed slot in KEYS[2]. A claimed slot is never claimed again.
+var claimScript = redis.NewScript(`
+if tonumber(redis.call("GET", KEYS[2]) or "0") >= tonumber(ARGV[2]) then
+	return 0
+end
+if not redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[3]) then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2])
+return 1
+`)
+
+// Claim takes the lock key for owner and the slot, e.g. the scheduled time of a run, it reports
+// false while another owner holds the lock or when the slot was claimed already. ReleaseLease
+// gives the lock up.
+func (r Redis) Claim(key, owner string, slot int64, ttl time.Duration) (bool, error) {
+	keys := []string{r.wrapperKey(key), r.wrapperKey(key + ":slot")}
+	result, err := claimScript.Run(r.ctx, r.client, keys, owner, slot, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return result == 1, nil
+}
+
+// XAdd appends values to the stream, trimmed to about maxLen entries when maxLen is positive
+func (r Redis) XAdd(ctx context.Context, stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.wrapperKey(stream),
+		MaxLen: maxLen,
+		Approx: maxLen > 0,
+		Values: values,
+	}).Result()
+}
+
 func (r Redis) Close() error {
 	return r.client.Close()
 }
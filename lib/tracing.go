@@ -0,0 +1,281 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"io"
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/pkg/file"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	TracingExporterOtlp   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+
+	tracingName            = "manuel71sj/go-api-template"
+	tracingDatabaseSpanKey = "tracing:span"
+)
+
+// tracingDatabaseSystems the db.system of the database engines
+var tracingDatabaseSystems = map[string]attribute.KeyValue{
+	DatabaseEngineMySQL:    semconv.DBSystemMySQL,
+	DatabaseEnginePostgres: semconv.DBSystemPostgreSQL,
+	DatabaseEngineSQLite:   semconv.DBSystemSqlite,
+}
+
+// Tracing the opentelemetry tracing of the requests, propagated by the W3C trace context.
+// The database statements and the redis commands are traced as children of the span of
+// their context, those run outside of a traced request, e.g. by the background loops, are not.
+type Tracing struct {
+	enable     bool
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	// the file of the file exporter
+	closer io.Closer
+}
+
+// NewTracing creates the tracing with the exporter of the config, and instruments the database
+// with gorm callbacks and the redis clients of the cache and of the job queue with hooks
+func NewTracing(config Config, logger Logger, db Database, cache Redis, jobQueue JobQueue) Tracing {
+	t := Tracing{
+		enable:     config.Tracing.Enable,
+		tracer:     trace.NewNoopTracerProvider().Tracer(tracingName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+	if !t.enable {
+		return t
+	}
+
+	exporter, closer, err := newTracingExporter(config.Tracing)
+	if err != nil {
+		logger.Zap.Fatalf("Error to create tracing exporter[%s]: %v", config.Tracing.Exporter, err)
+	}
+
+	t.closer = closer
+	t.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.Name))),
+		// a request with a sampled parent is traced, a new trace is sampled at the ratio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.Tracing.SampleRatio))),
+	)
+	t.tracer = t.provider.Tracer(tracingName)
+
+	otel.SetTracerProvider(t.provider)
+	otel.SetTextMapPropagator(t.propagator)
+
+	if err := t.instrumentDatabase(db.ORM, config.Database.Engine); err != nil {
+		logger.Zap.Errorf("Error to instrument database tracing: %v", err)
+	}
+
+	cache.client.AddHook(tracingRedisHook{tracer: t.tracer, db: cache.client.Options().DB})
+	jobQueue.client.AddHook(tracingRedisHook{tracer: t.tracer, db: jobQueue.client.Options().DB})
+
+	logger.Zap.Infof("Tracing to the %s exporter, sampling %v of the new traces", config.Tracing.Exporter, config.Tracing.SampleRatio)
+	return t
+}
+
+// newTracingExporter creates the span exporter of the config, and the file to close of the file exporter
+func newTracingExporter(config *TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch config.Exporter {
+	case TracingExporterOtlp:
+		options := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(config.Endpoint),
+			otlptracehttp.WithHeaders(config.Headers),
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err := otlptracehttp.New(context.Background(), options...)
+		return exporter, nil, err
+	case TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		return exporter, nil, err
+	case TracingExporterFile:
+		if err := file.EnsureDir(filepath.Dir(config.File)); err != nil {
+			return nil, nil, err
+		}
+
+		f, err := os.OpenFile(config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		return exporter, f, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported tracing exporter %q", config.Exporter)
+	}
+}
+
+// Enabled reports whether the requests are traced
+func (t Tracing) Enabled() bool {
+	return t.enable
+}
+
+// Start starts a span, the child of the span of ctx if any
+func (t Tracing) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, opts...)
+}
+
+// Extract returns a copy of ctx carrying the remote span of the traceparent and the baggage of header
+func (t Tracing) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Shutdown exports the spans not exported yet and stops the exporter
+func (t Tracing) Shutdown(ctx context.Context) error {
+	if !t.enable {
+		return nil
+	}
+
+	err := t.provider.Shutdown(ctx)
+	if t.closer != nil {
+		if cerr := t.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// instrumentDatabase traces every statement of db run with the context of a span
+func (t Tracing) instrumentDatabase(db *gorm.DB, engine string) error {
+	system := tracingDatabaseSystems[engine]
+
+	start := func(operation string) func(db *gorm.DB) {
+		return func(db *gorm.DB) {
+			ctx := db.Statement.Context
+			if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+
+			_, span := t.tracer.Start(ctx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient))
+			db.InstanceSet(tracingDatabaseSpanKey, span)
+		}
+	}
+
+	end := func(db *gorm.DB) {
+		value, ok := db.InstanceGet(tracingDatabaseSpanKey)
+		if !ok {
+			return
+		}
+
+		span := value.(trace.Span)
+		defer span.End()
+
+		span.SetAttributes(
+			system,
+			semconv.DBSQLTable(db.Statement.Table),
+			semconv.DBStatement(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+
+	callback := db.Callback()
+	for _, fn := range []func() error{
+		func() error { return callback.Create().Before("gorm:create").Register("otel:before", start("create")) },
+		func() error { return callback.Create().After("gorm:create").Register("otel:after", end) },
+		func() error { return callback.Query().Before("gorm:query").Register("otel:before", start("query")) },
+		func() error { return callback.Query().After("gorm:query").Register("otel:after", end) },
+		func() error { return callback.Update().Before("gorm:update").Register("otel:before", start("update")) },
+		func() error { return callback.Update().After("gorm:update").Register("otel:after", end) },
+		func() error { return callback.Delete().Before("gorm:delete").Register("otel:before", start("delete")) },
+		func() error { return callback.Delete().After("gorm:delete").Register("otel:after", end) },
+		func() error { return callback.Row().Before("gorm:row").Register("otel:before", start("row")) },
+		func() error { return callback.Row().After("gorm:row").Register("otel:after", end) },
+		func() error { return callback.Raw().Before("gorm:raw").Register("otel:before", start("raw")) },
+		func() error { return callback.Raw().After("gorm:raw").Register("otel:after", end) },
+	} {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type tracingRedisSpanKey struct{}
+
+// tracingRedisHook traces the commands of a redis client run with the context of a span,
+// the arguments of the commands are not recorded
+type tracingRedisHook struct {
+	tracer trace.Tracer
+	db     int
+}
+
+func (h tracingRedisHook) start(ctx context.Context, name string, attributes ...attribute.KeyValue) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	attributes = append(attributes, semconv.DBSystemRedis, semconv.DBRedisDBIndex(h.db))
+	ctx, span := h.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+	return context.WithValue(ctx, tracingRedisSpanKey{}, span)
+}
+
+func (h tracingRedisHook) end(ctx context.Context, err error) {
+	span, ok := ctx.Value(tracingRedisSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (h tracingRedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.start(ctx, "redis."+cmd.FullName(), semconv.DBOperation(cmd.Name())), nil
+}
+
+func (h tracingRedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.end(ctx, cmd.Err())
+	return nil
+}
+
+func (h tracingRedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+
+	return h.start(ctx, "redis.pipeline", semconv.DBStatement(strings.Join(names, " "))), nil
+}
+
+func (h tracingRedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+
+	h.end(ctx, err)
+	return nil
+}
@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"database/sql"
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register("20231001000000", "init", upInit, downInit)
+}
+
+// initModels is a snapshot of the models when migrations were introduced. The types are
+// declared here, named like the models, so later model changes need their own migration
+// while table and index names stay those of the former AutoMigrate.
+func initModels() []interface{} {
+	type Model struct {
+		RecordID  uint           `gorm:"column:record_id;primaryKey;autoIncrement;"`
+		CreatedAt sql.NullTime   `gorm:"column:created_at;autoCreateTime;"`
+		UpdatedAt sql.NullTime   `gorm:"column:updated_at;autoUpdateTime;"`
+		DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index;"`
+		Deleted   bool           `gorm:"column:deleted;default:false;"`
+	}
+
+	type User struct {
+		Model
+		ID        string `gorm:"column:id;size:36;index;not null;"`
+		Username  string `gorm:"column:username;size:64;not null;index;"`
+		Realname  string `gorm:"column:realname;size:64;not null;"`
+		Password  string `gorm:"column:password;not null;"`
+		Email     string `gorm:"column:email;default:'';"`
+		Phone     string `gorm:"column:phone;default:'';"`
+		Status    int    `gorm:"column:status;not null;default:0;"`
+		Locale    string `gorm:"column:locale;size:16;default:'';"`
+		CreatedBy string `gorm:"column:created_by;not null;"`
+	}
+
+	type UserRole struct {
+		Model
+		ID     string `gorm:"column:id;size:36;not null;"`
+		UserID string `gorm:"column:user_id;size:36;index;not null;"`
+		RoleID string `gorm:"column:role_id;size:36;index;not null;"`
+	}
+
+	type Role struct {
+		Model
+		ID        string `gorm:"column:id;size:36;index;not null;"`
+		Name      string `gorm:"column:name;not null;"`
+		Remark    string `gorm:"column:remark;default:'';"`
+		Sequence  int    `gorm:"column:sequence;not null;index;"`
+		Status    int    `gorm:"column:status;not null;default:0;"`
+		CreatedBy string `gorm:"column:created_by;not null;"`
+	}
+
+	type RoleMenu struct {
+		Model
+		ID       string `gorm:"column:id;size:36;not null;"`
+		RoleID   string `gorm:"column:role_id;size:36;not null;index;"`
+		MenuID   string `gorm:"column:menu_id;size:36;not null;index;"`
+		ActionID string `gorm:"column:action_id;size:36;not null;index;"`
+	}
+
+	type Menu struct {
+		Model
+		ID         string `gorm:"column:id;size:36;not null;index;"`
+		Name       string `gorm:"column:name;not null;index;"`
+		Sequence   int    `gorm:"column:sequence;not null;index;"`
+		Icon       string `gorm:"column:icon;"`
+		Router     string `gorm:"column:router;"`
+		Component  string `gorm:"column:component;"`
+		ParentID   string `gorm:"column:parent_id;size:36;index;"`
+		ParentPath string `gorm:"column:parent_path;"`
+		Hidden     int    `gorm:"column:hidden;not null;"`
+		Status     int    `gorm:"column:status;not null;"`
+		Remark     string `gorm:"column:remark;"`
+		CreatedBy  string `gorm:"column:created_by;not null;"`
+	}
+
+	type MenuAction struct {
+		Model
+		ID     string `gorm:"column:id;size:36;not null;index;"`
+		MenuID string `gorm:"column:menu_id;size:36;not null;index;"`
+		Code   string `gorm:"column:code;not null;"`
+		Name   string `gorm:"column:name;not null;"`
+	}
+
+	type MenuActionResource struct {
+		Model
+		ID       string `gorm:"column:id;size:36;index;not null;"`
+		ActionID string `gorm:"column:action_id;size:36;index;not null;"`
+		Method   string `gorm:"column:method;not null;"`
+		Path     string `gorm:"column:path;not null;"`
+	}
+
+	type MenuI18n struct {
+		Model
+		ID       string `gorm:"column:id;size:36;not null;index;"`
+		MenuID   string `gorm:"column:menu_id;size:36;not null;index;"`
+		ActionID string `gorm:"column:action_id;size:36;not null;default:'';index;"`
+		Locale   string `gorm:"column:locale;size:16;not null;"`
+		Name     string `gorm:"column:name;not null;"`
+	}
+
+	return []interface{}{
+		&User{}, &UserRole{}, &Role{}, &RoleMenu{},
+		&Menu{}, &MenuAction{}, &MenuActionResource{}, &MenuI18n{},
+	}
+}
+
+// upInit also adopts the tables of a database created by the former AutoMigrate
+func upInit(tx *gorm.DB) error {
+	return tx.AutoMigrate(initModels()...)
+}
+
+func downInit(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(initModels()...)
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register("20231015000000", "add_version", upAddVersion, downAddVersion)
+}
+
+// versionTables are the models embedding database.Model
+var versionTables = []string{
+	"User", "UserRole", "Role", "RoleMenu", "Menu", "MenuAction", "MenuActionResource", "MenuI18n",
+}
+
+type versionColumn struct {
+	Version int `gorm:"column:version;not null;default:1;"`
+}
+
+func upAddVersion(tx *gorm.DB) error {
+	for _, name := range versionTables {
+		migrator := tx.Table(tx.NamingStrategy.TableName(name)).Migrator()
+		if migrator.HasColumn(&versionColumn{}, "Version") {
+			continue
+		}
+
+		if err := migrator.AddColumn(&versionColumn{}, "Version"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func downAddVersion(tx *gorm.DB) error {
+	for _, name := range versionTables {
+		if err := tx.Table(tx.NamingStrategy.TableName(name)).Migrator().DropColumn(&versionColumn{}, "Version"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+func init() {
+	Register("20231101000000", "add_outbox_event", upAddOutboxEvent, downAddOutboxEvent)
+}
+
+// outboxEventModel is the snapshot of models.OutboxEvent
+func outboxEventModel() interface{} {
+	type OutboxEvent struct {
+		ID            uint64     `gorm:"column:id;primaryKey;autoIncrement;"`
+		EventID       string     `gorm:"column:event_id;size:36;not null;uniqueIndex;"`
+		Type          string     `gorm:"column:type;size:64;not null;"`
+		AggregateType string     `gorm:"column:aggregate_type;size:32;not null;"`
+		AggregateID   string     `gorm:"column:aggregate_id;size:36;not null;index;"`
+		Payload       string     `gorm:"column:payload;type:text;not null;"`
+		CreatedAt     time.Time  `gorm:"column:created_at;not null;"`
+		PublishedAt   *time.Time `gorm:"column:published_at;index;"`
+		Attempts      int        `gorm:"column:attempts;not null;default:0;"`
+		LastError     string     `gorm:"column:last_error;type:text;"`
+	}
+
+	return &OutboxEvent{}
+}
+
+func upAddOutboxEvent(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(outboxEventModel())
+}
+
+func downAddOutboxEvent(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(outboxEventModel())
+}
@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"database/sql"
+	"gorm.io/gorm"
+	"time"
+)
+
+func init() {
+	Register("20231105000000", "add_webhook", upAddWebhook, downAddWebhook)
+}
+
+// webhookModels is the snapshot of models.Webhook and models.WebhookDelivery
+func webhookModels() []interface{} {
+	type Model struct {
+		RecordID  uint           `gorm:"column:record_id;primaryKey;autoIncrement;"`
+		CreatedAt sql.NullTime   `gorm:"column:created_at;autoCreateTime;"`
+		UpdatedAt sql.NullTime   `gorm:"column:updated_at;autoUpdateTime;"`
+		DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index;"`
+		Deleted   bool           `gorm:"column:deleted;default:false;"`
+		Version   int            `gorm:"column:version;not null;default:1;"`
+	}
+
+	type Webhook struct {
+		Model
+		ID        string `gorm:"column:id;size:36;index;not null;"`
+		Name      string `gorm:"column:name;size:64;not null;"`
+		URL       string `gorm:"column:url;not null;"`
+		Secret    string `gorm:"column:secret;size:64;not null;"`
+		Events    string `gorm:"column:events;type:text;"`
+		Status    int    `gorm:"column:status;not null;default:0;"`
+		Remark    string `gorm:"column:remark;default:'';"`
+		CreatedBy string `gorm:"column:created_by;not null;"`
+	}
+
+	type WebhookDelivery struct {
+		Model
+		ID            string     `gorm:"column:id;size:36;index;not null;"`
+		WebhookID     string     `gorm:"column:webhook_id;size:36;not null;index;"`
+		EventID       string     `gorm:"column:event_id;size:36;not null;index;"`
+		EventType     string     `gorm:"column:event_type;size:64;not null;"`
+		Payload       string     `gorm:"column:payload;type:text;not null;"`
+		Status        string     `gorm:"column:status;size:16;not null;index;"`
+		Attempts      int        `gorm:"column:attempts;not null;default:0;"`
+		NextAttemptAt *time.Time `gorm:"column:next_attempt_at;index;"`
+		LastAttemptAt *time.Time `gorm:"column:last_attempt_at;"`
+		ResponseCode  int        `gorm:"column:response_code;not null;default:0;"`
+		ResponseBody  string     `gorm:"column:response_body;type:text;"`
+		LastError     string     `gorm:"column:last_error;type:text;"`
+		DeliveredAt   *time.Time `gorm:"column:delivered_at;"`
+	}
+
+	return []interface{}{&Webhook{}, &WebhookDelivery{}}
+}
+
+func upAddWebhook(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(webhookModels()...)
+}
+
+func downAddWebhook(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(webhookModels()...)
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+func init() {
+	Register("20231110000000", "add_audit_log", upAddAuditLog, downAddAuditLog)
+}
+
+// auditLogModel is the snapshot of models.AuditLog
+func auditLogModel() interface{} {
+	type AuditLog struct {
+		RecordID     uint64    `gorm:"column:record_id;primaryKey;autoIncrement;"`
+		ActorID      string    `gorm:"column:actor_id;size:36;not null;index;"`
+		Actor        string    `gorm:"column:actor;size:64;not null;index;"`
+		Impersonator string    `gorm:"column:impersonator;size:64;not null;default:'';"`
+		Action       string    `gorm:"column:action;size:32;not null;index;"`
+		Resource     string    `gorm:"column:resource;size:32;not null;index;"`
+		ResourceID   string    `gorm:"column:resource_id;size:64;not null;index;"`
+		Changes      string    `gorm:"column:changes;type:text;"`
+		IP           string    `gorm:"column:ip;size:64;not null;default:'';"`
+		RequestID    string    `gorm:"column:request_id;size:64;not null;default:'';index;"`
+		Outcome      string    `gorm:"column:outcome;size:16;not null;"`
+		Error        string    `gorm:"column:error;type:text;"`
+		CreatedAt    time.Time `gorm:"column:created_at;not null;index;"`
+	}
+
+	return &AuditLog{}
+}
+
+func upAddAuditLog(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(auditLogModel())
+}
+
+func downAddAuditLog(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(auditLogModel())
+}
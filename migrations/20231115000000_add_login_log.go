@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+func init() {
+	Register("20231115000000", "add_login_log", upAddLoginLog, downAddLoginLog)
+}
+
+// loginLogModel is the snapshot of models.LoginLog
+func loginLogModel() interface{} {
+	type LoginLog struct {
+		RecordID    uint64    `gorm:"column:record_id;primaryKey;autoIncrement;"`
+		UserID      string    `gorm:"column:user_id;size:36;not null;default:'';index;"`
+		Username    string    `gorm:"column:username;size:64;not null;index;"`
+		Event       string    `gorm:"column:event;size:32;not null;index;"`
+		Outcome     string    `gorm:"column:outcome;size:16;not null;index;"`
+		Reason      string    `gorm:"column:reason;type:text;"`
+		IP          string    `gorm:"column:ip;size:64;not null;default:'';"`
+		Network     string    `gorm:"column:network;size:64;not null;default:'';"`
+		UserAgent   string    `gorm:"column:user_agent;size:255;not null;default:'';"`
+		RequestID   string    `gorm:"column:request_id;size:64;not null;default:'';"`
+		NewDevice   bool      `gorm:"column:new_device;not null;default:false;"`
+		NewLocation bool      `gorm:"column:new_location;not null;default:false;"`
+		CreatedAt   time.Time `gorm:"column:created_at;not null;index;"`
+	}
+
+	return &LoginLog{}
+}
+
+func upAddLoginLog(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(loginLogModel())
+}
+
+func downAddLoginLog(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(loginLogModel())
+}
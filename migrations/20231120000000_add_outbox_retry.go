@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+func init() {
+	Register("20231120000000", "add_outbox_retry", upAddOutboxRetry, downAddOutboxRetry)
+}
+
+// outboxRetryModel is the snapshot of the retry columns of models.OutboxEvent
+func outboxRetryModel() interface{} {
+	type OutboxEvent struct {
+		NextAttemptAt  *time.Time `gorm:"column:next_attempt_at;index;"`
+		DeadAt         *time.Time `gorm:"column:dead_at;"`
+		PublishedSinks string     `gorm:"column:published_sinks;type:text;"`
+	}
+
+	return &OutboxEvent{}
+}
+
+var outboxRetryColumns = []string{"NextAttemptAt", "DeadAt", "PublishedSinks"}
+
+func upAddOutboxRetry(tx *gorm.DB) error {
+	model := outboxRetryModel()
+	migrator := tx.Migrator()
+
+	for _, column := range outboxRetryColumns {
+		if migrator.HasColumn(model, column) {
+			continue
+		}
+
+		if err := migrator.AddColumn(model, column); err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasIndex(model, "NextAttemptAt") {
+		if err := migrator.CreateIndex(model, "NextAttemptAt"); err != nil {
+			return err
+		}
+	}
+
+	// the pending events are due right away
+	return tx.Model(model).Where("published_at IS NULL AND next_attempt_at IS NULL").
+		Update("next_attempt_at", gorm.Expr("created_at")).Error
+}
+
+func downAddOutboxRetry(tx *gorm.DB) error {
+	model := outboxRetryModel()
+	migrator := tx.Migrator()
+
+	if err := migrator.DropIndex(model, "NextAttemptAt"); err != nil {
+		return err
+	}
+
+	for _, column := range outboxRetryColumns {
+		if err := migrator.DropColumn(model, column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
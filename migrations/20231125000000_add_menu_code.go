@@ -0,0 +1,45 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register("20231125000000", "add_menu_code", upAddMenuCode, downAddMenuCode)
+}
+
+// menuCodeModel is the snapshot of the code column of models.Menu
+func menuCodeModel() interface{} {
+	type Menu struct {
+		Code string `gorm:"column:code;size:64;not null;default:'';index;"`
+	}
+
+	return &Menu{}
+}
+
+// the menus are given their code by the next setup --sync, matched by their name path
+func upAddMenuCode(tx *gorm.DB) error {
+	model := menuCodeModel()
+	migrator := tx.Migrator()
+
+	if !migrator.HasColumn(model, "Code") {
+		if err := migrator.AddColumn(model, "Code"); err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasIndex(model, "Code") {
+		return migrator.CreateIndex(model, "Code")
+	}
+
+	return nil
+}
+
+func downAddMenuCode(tx *gorm.DB) error {
+	model := menuCodeModel()
+	migrator := tx.Migrator()
+
+	if err := migrator.DropIndex(model, "Code"); err != nil {
+		return err
+	}
+
+	return migrator.DropColumn(model, "Code")
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+	"manuel71sj/go-api-template/lib"
+)
+
+// SQLDir holds the <version>_<name>.up.sql and <version>_<name>.down.sql migrations
+const SQLDir = "migrations/sql"
+
+var registry []lib.Migration
+
+// Register adds a Go migration, called from the init function of its file
+func Register(version, name string, up, down func(tx *gorm.DB) error) {
+	registry = append(registry, lib.Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+// All returns the Go migrations and the SQL migrations read from sqlDir
+func All(sqlDir, tablePrefix string) ([]lib.Migration, error) {
+	sqlMigrations, err := lib.LoadSQLMigrations(sqlDir, tablePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]lib.Migration{}, registry...), sqlMigrations...), nil
+}
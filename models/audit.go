@@ -0,0 +1,190 @@
+package models
+
+import (
+	"encoding/json"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/slice"
+	"reflect"
+	"time"
+)
+
+const (
+	AuditCreate        = "create"
+	AuditUpdate        = "update"
+	AuditDelete        = "delete"
+	AuditUpdateStatus  = "update_status"
+	AuditUpdateActions = "update_actions"
+	AuditMove          = "move"
+	AuditSync          = "sync"
+	AuditRestore       = "restore"
+	AuditPurge         = "purge"
+	AuditImport        = "import"
+	AuditPing          = "ping"
+	AuditRedeliver     = "redeliver"
+	AuditRotateSecret  = "rotate_secret"
+)
+
+const (
+	AuditResourceWebhook = "webhook"
+	AuditResourceRbac    = "rbac"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditActorSystem the actor of the changes made outside of a request, e.g. by the commands
+const AuditActorSystem = "system"
+
+// auditSecretFields the values of these fields never reach the audit log
+var auditSecretFields = []string{"password", "secret"}
+
+// auditSkippedFields the bookkeeping fields that change with every update
+var auditSkippedFields = []string{"created_at", "updated_at", "version", "deleted"}
+
+// AuditChange the value of a field before and after the change, nil when absent
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges the changed fields by name
+type AuditChanges map[string]*AuditChange
+
+// NewAuditChanges compares the JSON fields of before and after, either may be nil. The
+// secret fields are masked and only tell whether they changed.
+func NewAuditChanges(before, after interface{}) AuditChanges {
+	bFields, aFields := auditFields(before), auditFields(after)
+
+	changes := make(AuditChanges)
+	for _, fields := range []map[string]interface{}{bFields, aFields} {
+		for name := range fields {
+			if _, ok := changes[name]; ok || slice.ContainsString(auditSkippedFields, name) {
+				continue
+			}
+
+			bValue, aValue := bFields[name], aFields[name]
+			if reflect.DeepEqual(bValue, aValue) {
+				continue
+			}
+
+			if slice.ContainsString(auditSecretFields, name) {
+				bValue, aValue = auditMask(bValue), auditMask(aValue)
+			}
+			changes[name] = &AuditChange{Before: bValue, After: aValue}
+		}
+	}
+
+	return changes
+}
+
+// auditFields returns the JSON fields of v, or its JSON value as field "value" when v is not an object
+func auditFields(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		var value interface{}
+		_ = json.Unmarshal(data, &value)
+		return map[string]interface{}{"value": value}
+	}
+
+	return fields
+}
+
+func auditMask(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+
+	return "******"
+}
+
+// AuditLog a change made to a resource, by whom, from where and whether it succeeded
+type AuditLog struct {
+	RecordID     uint64       `gorm:"column:record_id;primaryKey;autoIncrement;" json:"id"`
+	ActorID      string       `gorm:"column:actor_id;size:36;not null;index;" json:"actor_id"`
+	Actor        string       `gorm:"column:actor;size:64;not null;index;" json:"actor"`
+	Impersonator string       `gorm:"column:impersonator;size:64;not null;default:'';" json:"impersonator"`
+	Action       string       `gorm:"column:action;size:32;not null;index;" json:"action"`
+	Resource     string       `gorm:"column:resource;size:32;not null;index;" json:"resource"`
+	ResourceID   string       `gorm:"column:resource_id;size:64;not null;index;" json:"resource_id"`
+	Changes      AuditChanges `gorm:"column:changes;type:text;serializer:json;" json:"changes"`
+	IP           string       `gorm:"column:ip;size:64;not null;default:'';" json:"ip"`
+	RequestID    string       `gorm:"column:request_id;size:64;not null;default:'';index;" json:"request_id"`
+	Outcome      string       `gorm:"column:outcome;size:16;not null;" json:"outcome"`
+	Error        string       `gorm:"column:error;type:text;" json:"error"`
+	CreatedAt    time.Time    `gorm:"column:created_at;not null;index;" json:"created_at"`
+}
+
+// NewAuditLog creates the audit log of the action of the actor on the resource, a failure when err is not nil
+func NewAuditLog(actor *dto.AuditActor, action, resource, resourceID string, changes AuditChanges, err error) *AuditLog {
+	log := &AuditLog{
+		ActorID:      actor.UserID,
+		Actor:        actor.Username,
+		Impersonator: actor.Impersonator,
+		Action:       action,
+		Resource:     resource,
+		ResourceID:   resourceID,
+		Changes:      changes,
+		IP:           actor.IP,
+		RequestID:    actor.RequestID,
+		Outcome:      AuditOutcomeSuccess,
+	}
+
+	if err != nil {
+		log.Outcome = AuditOutcomeFailure
+		log.Error = err.Error()
+	}
+
+	return log
+}
+
+type AuditLogs []*AuditLog
+
+type AuditLogQueryParam struct {
+	dto.PaginationParam
+	dto.OrderParam
+	dto.FilterParam
+
+	Actor      string `query:"actor"`
+	Action     string `query:"action"`
+	Resource   string `query:"resource"`
+	ResourceID string `query:"resource_id"`
+	RequestID  string `query:"request_id"`
+	Outcome    string `query:"outcome" validate:"in=success;failure"`
+}
+
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable audit log columns
+func (p *AuditLogQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at")
+}
+
+// ParseFilters checks the filters of the query against the filterable audit log columns
+func (p *AuditLogQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"actor_id":     dto.FilterString,
+		"actor":        dto.FilterString,
+		"impersonator": dto.FilterString,
+		"action":       dto.FilterString,
+		"resource":     dto.FilterString,
+		"resource_id":  dto.FilterString,
+		"ip":           dto.FilterString,
+		"request_id":   dto.FilterString,
+		"outcome":      dto.FilterString,
+		"created_at":   dto.FilterTime,
+	})
+}
+
+type AuditLogQueryResult struct {
+	List       AuditLogs       `json:"list"`
+	Pagination *dto.Pagination `json:"pagination"`
+}
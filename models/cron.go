@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CronRun the outcome of a run of a cron job
+type CronRun struct {
+	Instance  string    `json:"instance"`
+	StartedAt time.Time `json:"started_at"`
+	// Duration in milliseconds
+	Duration int64  `json:"duration"`
+	Error    string `json:"error"`
+}
+
+// CronJob a cron job, its schedule and its last run across the instances
+type CronJob struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Disabled  bool       `json:"disabled"`
+	Running   bool       `json:"running"`
+	NextRunAt *time.Time `json:"next_run_at"`
+	LastRun   *CronRun   `json:"last_run"`
+}
+
+type CronJobs []*CronJob
@@ -11,4 +11,19 @@ type Model struct {
 	UpdatedAt sql.NullTime   `gorm:"column:updated_at;autoUpdateTime;" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index;" json:"-"`
 	Deleted   bool           `gorm:"column:deleted;default:false;" json:"deleted"`
+	Version   int            `gorm:"column:version;not null;default:1;" json:"version"`
+}
+
+// Versioned a model with the optimistic locking version of Model
+type Versioned interface {
+	GetVersion() int
+	SetVersion(version int)
+}
+
+func (m *Model) GetVersion() int {
+	return m.Version
+}
+
+func (m *Model) SetVersion(version int) {
+	m.Version = version
 }
@@ -0,0 +1,11 @@
+package dto
+
+// AuditActor who makes the changes of a request and where the request comes from,
+// Impersonator is the user acting as Username
+type AuditActor struct {
+	UserID       string
+	Username     string
+	Impersonator string
+	IP           string
+	RequestID    string
+}
@@ -0,0 +1,8 @@
+package dto
+
+// CacheStat hit and miss counters of a cached kind since the process started
+type CacheStat struct {
+	Kind   string `json:"kind"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
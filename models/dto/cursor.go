@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"manuel71sj/go-api-template/errors"
+)
+
+// Cursor the position of a keyset page, the sort key values of the row the page
+// starts after, or before when Prev. Order is the sort of the query that made it.
+type Cursor struct {
+	Order  string            `json:"o"`
+	Values []json.RawMessage `json:"v"`
+	Prev   bool              `json:"p,omitempty"`
+}
+
+// Encode returns the opaque form of the cursor given to clients
+func (c *Cursor) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ParseCursor decodes a cursor returned by Encode
+func ParseCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrap(errors.CursorInvalid, err.Error())
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, errors.Wrap(errors.CursorInvalid, err.Error())
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,161 @@
+package dto
+
+import (
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/pkg/slice"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FilterType int
+
+const (
+	FilterString FilterType = iota
+	FilterInt
+	FilterTime
+	FilterBool
+)
+
+const (
+	FilterEq       = "eq"
+	FilterNe       = "ne"
+	FilterGt       = "gt"
+	FilterGte      = "gte"
+	FilterLt       = "lt"
+	FilterLte      = "lte"
+	FilterIn       = "in"
+	FilterNin      = "nin"
+	FilterPrefix   = "prefix"
+	FilterContains = "contains"
+	FilterNull     = "null"
+)
+
+// filterOperators the operators allowed on each type of field
+var filterOperators = map[FilterType][]string{
+	FilterString: {FilterEq, FilterNe, FilterIn, FilterNin, FilterPrefix, FilterContains, FilterNull},
+	FilterInt:    {FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterIn, FilterNin, FilterNull},
+	FilterTime:   {FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterNull},
+	FilterBool:   {FilterEq, FilterNe, FilterNull},
+}
+
+var filterTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// filterKeyRegexp matches filter[field] and filter[field][operator]
+var filterKeyRegexp = regexp.MustCompile(`^filter\[(\w+)](?:\[(\w+)])?$`)
+
+// FilterFields the filterable columns of a model and their type
+type FilterFields map[string]FilterType
+
+// Filter a condition of the filter query, e.g. filter[created_at][gte]=2023-10-01.
+// Values are the raw query values, Args those converted by FilterParam.ParseFilters.
+type Filter struct {
+	Field    string
+	Operator string
+	Values   []string
+	Args     []interface{}
+}
+
+// FilterParam the filters of a list query, read from the query string by the binder
+type FilterParam struct {
+	Filters []*Filter `query:"-" json:"-"`
+}
+
+// SetFilters is called by the binder with the filters of the query string
+func (f *FilterParam) SetFilters(filters []*Filter) {
+	f.Filters = filters
+}
+
+// ParseFilterQuery reads the filter[field][operator] parameters, the operator defaults to eq
+// and in, nin take comma separated values
+func ParseFilterQuery(values url.Values) []*Filter {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filters []*Filter
+	for _, key := range keys {
+		matches := filterKeyRegexp.FindStringSubmatch(key)
+		if matches == nil {
+			continue
+		}
+
+		operator := matches[2]
+		if operator == "" {
+			operator = FilterEq
+		}
+
+		for _, value := range values[key] {
+			filter := &Filter{Field: matches[1], Operator: operator, Values: []string{value}}
+			if operator == FilterIn || operator == FilterNin {
+				filter.Values = strings.Split(value, ",")
+			}
+
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters
+}
+
+// ParseFilters checks the filters against the filterable fields and converts their values
+func (f *FilterParam) ParseFilters(fields FilterFields) ([]*Filter, error) {
+	for _, filter := range f.Filters {
+		fieldType, ok := fields[filter.Field]
+		if !ok {
+			keys := make([]string, 0, len(fields))
+			for key := range fields {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			return nil, errors.Wrapf(errors.FilterFieldInvalid, "filter %q, allowed fields %s", filter.Field, strings.Join(keys, ","))
+		}
+
+		operators := filterOperators[fieldType]
+		if !slice.ContainsString(operators, filter.Operator) {
+			return nil, errors.Wrapf(errors.FilterOperatorInvalid, "filter %q operator %q, allowed operators %s",
+				filter.Field, filter.Operator, strings.Join(operators, ","))
+		}
+
+		filter.Args = make([]interface{}, len(filter.Values))
+		for i, value := range filter.Values {
+			arg, err := parseFilterValue(fieldType, filter.Operator, strings.TrimSpace(value))
+			if err != nil {
+				return nil, errors.Wrapf(errors.FilterValueInvalid, "filter %q value %q", filter.Field, value)
+			}
+
+			filter.Args[i] = arg
+		}
+	}
+
+	return f.Filters, nil
+}
+
+func parseFilterValue(fieldType FilterType, operator, value string) (interface{}, error) {
+	if operator == FilterNull {
+		return strconv.ParseBool(value)
+	}
+
+	switch fieldType {
+	case FilterInt:
+		return strconv.Atoi(value)
+	case FilterBool:
+		return strconv.ParseBool(value)
+	case FilterTime:
+		for _, layout := range filterTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				return t, nil
+			}
+		}
+
+		return nil, errors.FilterValueInvalid
+	default:
+		return value, nil
+	}
+}
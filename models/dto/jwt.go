@@ -5,5 +5,7 @@ import "github.com/golang-jwt/jwt"
 type JwtClaims struct {
 	ID       string
 	Username string
+	// Impersonator the username of the user acting as Username, empty for the own tokens of a user
+	Impersonator string `json:",omitempty"`
 	jwt.StandardClaims
 }
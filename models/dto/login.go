@@ -6,3 +6,10 @@ type Login struct {
 	CaptchaID   string `json:"captcha_id"`
 	CaptchaCode string `json:"captcha_code"`
 }
+
+// LoginClient where a login, a logout or a token refresh comes from
+type LoginClient struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
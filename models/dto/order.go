@@ -1,30 +1,44 @@
 package dto
 
-import "fmt"
-
-type OrderDirection string
-
-const (
-	OrderByASC      OrderDirection = "ASC"
-	OrderByDESC     OrderDirection = "DESC"
-	OrderDefaultJey                = "record_id"
+import (
+	"manuel71sj/go-api-template/errors"
+	"manuel71sj/go-api-template/pkg/slice"
+	"strings"
 )
 
+// OrderDefault the order of a query without sort spec
+const OrderDefault = "record_id DESC"
+
+// OrderParam sort spec of a query, comma separated keys sorted ascending,
+// or descending with a "-" prefix, e.g. "-sequence,name"
 type OrderParam struct {
-	Key       string         `query:"order_key"`
-	Direction OrderDirection `query:"order_direction"`
+	Sort string `query:"sort"`
 }
 
-func (o *OrderParam) ParseOrder() string {
-	if o.Key == "" {
-		o.Key = OrderDefaultJey
+// ParseOrder builds the ORDER BY clause of the sort spec, every key must be
+// one of the sortable columns so that no user input reaches the SQL
+func (o *OrderParam) ParseOrder(sortable ...string) (string, error) {
+	if strings.TrimSpace(o.Sort) == "" {
+		return OrderDefault, nil
 	}
 
-	key := o.Key
-	direction := "DESC"
-	if o.Direction == OrderByASC {
-		direction = "ASC"
+	var orders []string
+	for _, key := range strings.Split(o.Sort, ",") {
+		key = strings.TrimSpace(key)
+
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			key, direction = key[1:], "DESC"
+		} else {
+			key = strings.TrimPrefix(key, "+")
+		}
+
+		if !slice.ContainsString(sortable, key) {
+			return "", errors.Wrapf(errors.SortKeyInvalid, "sort %q, allowed keys %s", key, strings.Join(sortable, ","))
+		}
+
+		orders = append(orders, key+" "+direction)
 	}
 
-	return fmt.Sprintf("%s %s", key, direction)
+	return strings.Join(orders, ", "), nil
 }
@@ -1,14 +1,47 @@
 package dto
 
+import "encoding/json"
+
+const (
+	PaginationOffset = "offset"
+	PaginationCursor = "cursor"
+)
+
+// Pagination Total is the count of the rows in offset mode, it is left out of the JSON
+// when the query skipped the count, with skip_total or in cursor mode. The cursors are set
+// in cursor mode when there is a next or previous page.
 type Pagination struct {
-	Total    int64 `json:"total"`
-	Current  int   `json:"current"`
-	PageSize int   `json:"pageSize"`
+	Total      int64  `json:"total"`
+	Current    int    `json:"current"`
+	PageSize   int    `json:"pageSize"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	// SkipTotal the rows were not counted
+	SkipTotal bool `json:"-"`
+}
+
+// MarshalJSON leaves the total out when the rows were not counted
+func (p Pagination) MarshalJSON() ([]byte, error) {
+	type pagination Pagination
+	if !p.SkipTotal {
+		return json.Marshal(pagination(p))
+	}
+
+	return json.Marshal(struct {
+		pagination
+		Total *int64 `json:"total,omitempty"`
+	}{pagination: pagination(p)})
 }
 
+// PaginationParam pages by current and page_size, or by cursor with pagination=cursor.
+// The first cursor page has no cursor, the next pages pass the next_cursor or prev_cursor
+// of the previous response. A page has at most 128 rows in both modes.
 type PaginationParam struct {
-	Current  int `query:"current"`
-	PageSize int `query:"page_size" validate:"max=128"`
+	Current   int    `query:"current"`
+	PageSize  int    `query:"page_size" validate:"max=128"`
+	Mode      string `query:"pagination" validate:"in=offset;cursor"`
+	Cursor    string `query:"cursor"`
+	SkipTotal bool   `query:"skip_total"`
 }
 
 func (p *PaginationParam) GetCurrent() int {
@@ -23,3 +56,13 @@ func (p *PaginationParam) GetPageSize() int {
 
 	return pageSize
 }
+
+// IsCursor reports whether the query pages by cursor instead of offset
+func (p *PaginationParam) IsCursor() bool {
+	return p.Mode == PaginationCursor || p.Cursor != ""
+}
+
+// GetPaginationParam returns the pagination of a query param embedding PaginationParam
+func (p *PaginationParam) GetPaginationParam() PaginationParam {
+	return *p
+}
@@ -0,0 +1,34 @@
+package dto
+
+const (
+	ResourceSourceDatabase = "database"
+	ResourceSourceYAML     = "yaml"
+)
+
+// ResourceCheckReport result of comparing menu action resources with the registered routes
+type ResourceCheckReport struct {
+	UnknownResources []*ResourceCheckItem  `json:"unknown_resources"`
+	UncoveredRoutes  []*ResourceCheckRoute `json:"uncovered_routes"`
+	ShadowedRoutes   []*ResourceCheckRoute `json:"shadowed_routes"`
+}
+
+// ResourceCheckItem a menu action resource that does not match any registered route
+type ResourceCheckItem struct {
+	Source string `json:"source"`
+	Menu   string `json:"menu"`
+	Action string `json:"action"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// ResourceCheckRoute a registered route reported by the resource check,
+// Prefix is the casbin ignore prefix that shadows the route
+type ResourceCheckRoute struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+func (r *ResourceCheckReport) HasProblems() bool {
+	return len(r.UnknownResources) > 0 || len(r.UncoveredRoutes) > 0 || len(r.ShadowedRoutes) > 0
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"manuel71sj/go-api-template/models/dto"
+	"net"
+	"time"
+)
+
+const (
+	LoginEventLogin   = "login"
+	LoginEventLogout  = "logout"
+	LoginEventRefresh = "token_refresh"
+	LoginEventCaptcha = "captcha"
+)
+
+const (
+	LoginOutcomeSuccess = "success"
+	LoginOutcomeFailure = "failure"
+)
+
+// loginUserAgentSize the bytes of the user agent kept in the login log
+const loginUserAgentSize = 255
+
+// LoginLog a login, a logout or a token refresh of a user, or a failed attempt at one.
+// A successful login from a device or a network unseen lately is flagged as new.
+type LoginLog struct {
+	RecordID    uint64    `gorm:"column:record_id;primaryKey;autoIncrement;" json:"id"`
+	UserID      string    `gorm:"column:user_id;size:36;not null;default:'';index;" json:"user_id"`
+	Username    string    `gorm:"column:username;size:64;not null;index;" json:"username"`
+	Event       string    `gorm:"column:event;size:32;not null;index;" json:"event"`
+	Outcome     string    `gorm:"column:outcome;size:16;not null;index;" json:"outcome"`
+	Reason      string    `gorm:"column:reason;type:text;" json:"reason"`
+	IP          string    `gorm:"column:ip;size:64;not null;default:'';" json:"ip"`
+	Network     string    `gorm:"column:network;size:64;not null;default:'';" json:"network"`
+	UserAgent   string    `gorm:"column:user_agent;size:255;not null;default:'';" json:"user_agent"`
+	RequestID   string    `gorm:"column:request_id;size:64;not null;default:'';" json:"request_id"`
+	NewDevice   bool      `gorm:"column:new_device;not null;default:false;" json:"new_device"`
+	NewLocation bool      `gorm:"column:new_location;not null;default:false;" json:"new_location"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null;index;" json:"created_at"`
+}
+
+// NewLoginLog creates the login log of the event of the user from the client, a failure when err is not nil
+func NewLoginLog(event, userID, username string, client *dto.LoginClient, err error) *LoginLog {
+	log := &LoginLog{
+		UserID:    userID,
+		Username:  username,
+		Event:     event,
+		Outcome:   LoginOutcomeSuccess,
+		IP:        client.IP,
+		Network:   LoginNetwork(client.IP),
+		UserAgent: client.UserAgent,
+		RequestID: client.RequestID,
+	}
+
+	if len(log.UserAgent) > loginUserAgentSize {
+		log.UserAgent = log.UserAgent[:loginUserAgentSize]
+	}
+
+	if err != nil {
+		log.Outcome = LoginOutcomeFailure
+		log.Reason = err.Error()
+	}
+
+	return log
+}
+
+// Unfamiliar reports whether the login came from a new device or a new location
+func (l *LoginLog) Unfamiliar() bool {
+	return l.NewDevice || l.NewLocation
+}
+
+// LoginNetwork returns the network of ip standing for its location, the /24 of an IPv4
+// and the /48 of an IPv6 address, or ip itself when it does not parse
+func LoginNetwork(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ip
+	}
+
+	if v4 := addr.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+
+	return (&net.IPNet{IP: addr.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+type LoginLogs []*LoginLog
+
+type LoginLogQueryParam struct {
+	dto.PaginationParam
+	dto.OrderParam
+	dto.FilterParam
+
+	UserID   string `query:"user_id"`
+	Username string `query:"username"`
+	Event    string `query:"event" validate:"in=login;logout;token_refresh;captcha"`
+	Outcome  string `query:"outcome" validate:"in=success;failure"`
+	IP       string `query:"ip"`
+}
+
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable login log columns
+func (p *LoginLogQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at")
+}
+
+// ParseFilters checks the filters of the query against the filterable login log columns
+func (p *LoginLogQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"user_id":      dto.FilterString,
+		"username":     dto.FilterString,
+		"event":        dto.FilterString,
+		"outcome":      dto.FilterString,
+		"ip":           dto.FilterString,
+		"network":      dto.FilterString,
+		"user_agent":   dto.FilterString,
+		"request_id":   dto.FilterString,
+		"new_device":   dto.FilterBool,
+		"new_location": dto.FilterBool,
+		"created_at":   dto.FilterTime,
+	})
+}
+
+type LoginLogQueryResult struct {
+	List       LoginLogs       `json:"list"`
+	Pagination *dto.Pagination `json:"pagination"`
+}
@@ -8,34 +8,38 @@ import (
 
 type Menu struct {
 	database.Model
-	ID         string      `gorm:"column:id;size:36;not null;index;" json:"id"`
-	Name       string      `gorm:"column:name;not null;index;" json:"name" validate:"required"`
-	Sequence   int         `gorm:"column:sequence;not null;index;" json:"sequence" validate:"required"`
-	Icon       string      `gorm:"column:icon;" json:"icon" validate:"required"`
-	Router     string      `gorm:"column:router;" json:"router"`
-	Component  string      `gorm:"column:component;" json:"component"`
-	ParentID   string      `gorm:"column:parent_id;size:36;index;" json:"parent_id"`
-	ParentPath string      `gorm:"column:parent_path;" json:"parent_path"`
-	Hidden     int         `gorm:"column:hidden;not null;" json:"hidden" validate:"required,max=1,min=-1"`
-	Status     int         `gorm:"column:status;not null;" json:"status" validate:"required,max=1,min=-1"`
-	Remark     string      `gorm:"column:remark;" json:"remark" validate:"required"`
-	CreatedBy  string      `gorm:"column:created_by;not null;" json:"created_by"`
-	Actions    MenuActions `gorm:"-" json:"actions,omitempty"`
+	ID         string            `gorm:"column:id;size:36;not null;index;" json:"id"`
+	Code       string            `gorm:"column:code;size:64;not null;default:'';index;" json:"code"`
+	Name       string            `gorm:"column:name;not null;index;" json:"name" validate:"required"`
+	Sequence   int               `gorm:"column:sequence;not null;index;" json:"sequence"`
+	Icon       string            `gorm:"column:icon;" json:"icon"`
+	Router     string            `gorm:"column:router;" json:"router"`
+	Component  string            `gorm:"column:component;" json:"component"`
+	ParentID   string            `gorm:"column:parent_id;size:36;index;" json:"parent_id"`
+	ParentPath string            `gorm:"column:parent_path;" json:"parent_path"`
+	Hidden     int               `gorm:"column:hidden;not null;" json:"hidden" validate:"max=1,min=-1"`
+	Status     int               `gorm:"column:status;not null;" json:"status" validate:"max=1,min=-1"`
+	Remark     string            `gorm:"column:remark;" json:"remark"`
+	CreatedBy  string            `gorm:"column:created_by;not null;" json:"created_by"`
+	I18n       map[string]string `gorm:"-" json:"i18n,omitempty"`
+	Actions    MenuActions       `gorm:"-" json:"actions,omitempty"`
 }
 
 type MenuTree struct {
-	ID         string      `yaml:"-" json:"id"`
-	Name       string      `yaml:"name" json:"name"`
-	Icon       string      `yaml:"icon" json:"icon"`
-	Router     string      `yaml:"router,omitempty" json:"router"`
-	Component  string      `yaml:"component,omitempty" json:"component"`
-	ParentID   string      `yaml:"-" json:"parent_id"`
-	ParentPath string      `yaml:"-" json:"parent_path"`
-	Sequence   int         `yaml:"sequence" json:"sequence"`
-	Hidden     int         `yaml:"-" json:"hidden"`
-	Status     int         `yaml:"-" json:"status"`
-	Actions    MenuActions `yaml:"actions,omitempty" json:"actions"`
-	Children   MenuTrees   `yaml:"children,omitempty" json:"children,omitempty"`
+	ID         string            `yaml:"-" json:"id"`
+	Code       string            `yaml:"code,omitempty" json:"code"`
+	Name       string            `yaml:"name" json:"name"`
+	Icon       string            `yaml:"icon" json:"icon"`
+	Router     string            `yaml:"router,omitempty" json:"router"`
+	Component  string            `yaml:"component,omitempty" json:"component"`
+	ParentID   string            `yaml:"-" json:"parent_id"`
+	ParentPath string            `yaml:"-" json:"parent_path"`
+	Sequence   int               `yaml:"sequence" json:"sequence"`
+	Hidden     int               `yaml:"hidden,omitempty" json:"hidden"`
+	Status     int               `yaml:"-" json:"status"`
+	I18n       map[string]string `yaml:"i18n,omitempty" json:"i18n,omitempty"`
+	Actions    MenuActions       `yaml:"actions,omitempty" json:"actions"`
+	Children   MenuTrees         `yaml:"children,omitempty" json:"children,omitempty"`
 }
 
 type Menus []*Menu
@@ -92,11 +96,34 @@ func (ms Menus) ToIDs() []string {
 	return ids
 }
 
+// ToNamePathMap maps every menu id to the names of its ancestors and itself
+// joined by "/", e.g. "시스템 관리/메뉴 관리".
+func (ms Menus) ToNamePathMap() map[string]string {
+	mMenus := ms.ToMap()
+	m := make(map[string]string)
+
+	for _, v := range ms {
+		var names []string
+		if v.ParentPath != "" {
+			for _, pid := range strings.Split(v.ParentPath, "/") {
+				if parent, ok := mMenus[pid]; ok {
+					names = append(names, parent.Name)
+				}
+			}
+		}
+
+		m[v.ID] = strings.Join(append(names, v.Name), "/")
+	}
+
+	return m
+}
+
 func (ms Menus) ToMenuTrees() MenuTrees {
 	menuTrees := make(MenuTrees, len(ms))
 	for i, v := range ms {
 		menuTrees[i] = &MenuTree{
 			ID:         v.ID,
+			Code:       v.Code,
 			Name:       v.Name,
 			Icon:       v.Icon,
 			Router:     v.Router,
@@ -106,6 +133,7 @@ func (ms Menus) ToMenuTrees() MenuTrees {
 			Sequence:   v.Sequence,
 			Hidden:     v.Hidden,
 			Status:     v.Status,
+			I18n:       v.I18n,
 			Actions:    v.Actions,
 		}
 	}
@@ -124,6 +152,36 @@ func (ms Menus) FillMenuAction(mActions map[string]MenuActions, mResources map[s
 	return ms
 }
 
+// FillI18n sets the translated names of the menus and of their actions, see MenuI18ns.ToNamesMap
+func (ms Menus) FillI18n(mNames map[string]map[string]string) Menus {
+	for _, item := range ms {
+		item.I18n = mNames[item.ID]
+
+		for _, action := range item.Actions {
+			action.I18n = mNames[action.ID]
+		}
+	}
+
+	return ms
+}
+
+// Localize replaces the menu and action names by their translation in locale, when there is one
+func (ms Menus) Localize(locale string) Menus {
+	for _, item := range ms {
+		if v, ok := item.I18n[locale]; ok {
+			item.Name = v
+		}
+
+		for _, action := range item.Actions {
+			if v, ok := action.I18n[locale]; ok {
+				action.Name = v
+			}
+		}
+	}
+
+	return ms
+}
+
 type MenuTrees []*MenuTree
 
 func (ms MenuTrees) ToTree() MenuTrees {
@@ -155,8 +213,10 @@ func (ms MenuTrees) ToTree() MenuTrees {
 type MenuQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	IDs              []string `query:"ids"`
+	Code             string   `query:"code"`
 	Name             string   `query:"name"`
 	PrefixParentPath string   `query:"prefix_parent_path"`
 	QueryValue       string   `query:"query_value"`
@@ -167,6 +227,30 @@ type MenuQueryParam struct {
 	IncludeActions   bool     `query:"include_actions"`
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable menu columns
+func (p *MenuQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "updated_at", "name", "sequence", "status", "hidden")
+}
+
+// ParseFilters checks the filters of the query against the filterable menu columns
+func (p *MenuQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"id":          dto.FilterString,
+		"code":        dto.FilterString,
+		"name":        dto.FilterString,
+		"router":      dto.FilterString,
+		"component":   dto.FilterString,
+		"parent_id":   dto.FilterString,
+		"parent_path": dto.FilterString,
+		"sequence":    dto.FilterInt,
+		"hidden":      dto.FilterInt,
+		"status":      dto.FilterInt,
+		"created_by":  dto.FilterString,
+		"created_at":  dto.FilterTime,
+		"updated_at":  dto.FilterTime,
+	})
+}
+
 type MenuQueryResult struct {
 	List       Menus           `json:"list"`
 	Pagination *dto.Pagination `json:"pagination"`
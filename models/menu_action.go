@@ -3,15 +3,17 @@ package models
 import (
 	"manuel71sj/go-api-template/models/database"
 	"manuel71sj/go-api-template/models/dto"
+	"sort"
 )
 
 type MenuAction struct {
-	database.Model
-	ID        string              `gorm:"column:id;size:36;not null;index;" json:"id" yaml:"-"`
-	MenuID    string              `gorm:"column:menu_id;size:36;not null;index;" json:"menu_id" yaml:"-"`
-	Code      string              `gorm:"column:code;not null;" json:"code" validate:"required" yaml:"code"`
-	Name      string              `gorm:"column:name;not null;" json:"name" validate:"required" yaml:"name"`
-	Resources MenuActionResources `gorm:"-" json:"resources" yaml:"resources"`
+	database.Model `yaml:"-"`
+	ID             string              `gorm:"column:id;size:36;not null;index;" json:"id" yaml:"-"`
+	MenuID         string              `gorm:"column:menu_id;size:36;not null;index;" json:"menu_id" yaml:"-"`
+	Code           string              `gorm:"column:code;not null;" json:"code" validate:"required" yaml:"code"`
+	Name           string              `gorm:"column:name;not null;" json:"name" validate:"required" yaml:"name"`
+	I18n           map[string]string   `gorm:"-" json:"i18n,omitempty" yaml:"i18n,omitempty"`
+	Resources      MenuActionResources `gorm:"-" json:"resources" yaml:"resources,omitempty"`
 }
 
 type MenuActions []*MenuAction
@@ -24,6 +26,25 @@ func (ma MenuActions) ToMap() map[string]*MenuAction {
 	return m
 }
 
+// Audited returns the actions as recorded by the audit log, by code with their sorted resources
+func (ma MenuActions) Audited() interface{} {
+	type auditedAction struct {
+		Name      string   `json:"name"`
+		Resources []string `json:"resources"`
+	}
+
+	m := make(map[string]*auditedAction)
+	for _, v := range ma {
+		action := &auditedAction{Name: v.Name, Resources: make([]string, 0, len(v.Resources))}
+		for _, resource := range v.Resources {
+			action.Resources = append(action.Resources, resource.Method+" "+resource.Path)
+		}
+		sort.Strings(action.Resources)
+		m[v.Code] = action
+	}
+	return m
+}
+
 func (ma MenuActions) FillResources(maResources map[string]MenuActionResources) {
 	for i, v := range ma {
 		ma[i].Resources = maResources[v.ID]
@@ -41,11 +62,27 @@ func (ma MenuActions) ToMenuIDMap() map[string]MenuActions {
 type MenuActionQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	MenuID string
 	IDs    []string
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable menu action columns
+func (p *MenuActionQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "code", "name")
+}
+
+// ParseFilters checks the filters of the query against the filterable menu action columns
+func (p *MenuActionQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"menu_id":    dto.FilterString,
+		"code":       dto.FilterString,
+		"name":       dto.FilterString,
+		"created_at": dto.FilterTime,
+	})
+}
+
 type MenuActionQueryResult struct {
 	List       MenuActions     `json:"list"`
 	Pagination *dto.Pagination `json:"pagination"`
@@ -6,11 +6,11 @@ import (
 )
 
 type MenuActionResource struct {
-	database.Model
-	ID       string `gorm:"column:id;size:36;index;not null;" json:"-" yaml:"-"`
-	ActionID string `gorm:"column:action_id;size:36;index;not null;" json:"-" yaml:"-"`
-	Method   string `gorm:"column:method;not null;" json:"method" validate:"required" yaml:"method"`
-	Path     string `gorm:"column:path;not null;" json:"path" validate:"required" yaml:"path"`
+	database.Model `yaml:"-"`
+	ID             string `gorm:"column:id;size:36;index;not null;" json:"-" yaml:"-"`
+	ActionID       string `gorm:"column:action_id;size:36;index;not null;" json:"-" yaml:"-"`
+	Method         string `gorm:"column:method;not null;" json:"method" validate:"required" yaml:"method"`
+	Path           string `gorm:"column:path;not null;" json:"path" validate:"required" yaml:"path"`
 }
 
 type MenuActionResources []*MenuActionResource
@@ -36,11 +36,27 @@ func (mars MenuActionResources) ToActionIDMap() map[string]MenuActionResources {
 type MenuActionResourceQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	MenuID  string
 	MenuIDs []string
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable menu action resource columns
+func (p *MenuActionResourceQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "method", "path")
+}
+
+// ParseFilters checks the filters of the query against the filterable menu action resource columns
+func (p *MenuActionResourceQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"action_id":  dto.FilterString,
+		"method":     dto.FilterString,
+		"path":       dto.FilterString,
+		"created_at": dto.FilterTime,
+	})
+}
+
 type MenuActionResourceQueryResult struct {
 	List       MenuActionResources `json:"list"`
 	Pagination *dto.Pagination     `json:"pagination"`
@@ -0,0 +1,17 @@
+package models
+
+const (
+	MenuCacheKindSubject     = "subject"
+	MenuCacheKindTree        = "tree"
+	MenuCacheKindPermissions = "permissions"
+)
+
+// MenuCacheSubject what the menu trees and permissions of a user are computed from
+type MenuCacheSubject struct {
+	SuperAdmin bool
+	RoleIDs    []string
+	Locale     string
+}
+
+// UserPermissions the action codes granted to a user by menu id
+type UserPermissions map[string][]string
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"manuel71sj/go-api-template/models/database"
+	"manuel71sj/go-api-template/models/dto"
+)
+
+// MenuI18n name of a menu in another locale, or of one of its actions when ActionID is set
+type MenuI18n struct {
+	database.Model
+	ID       string `gorm:"column:id;size:36;not null;index;" json:"id"`
+	MenuID   string `gorm:"column:menu_id;size:36;not null;index;" json:"menu_id"`
+	ActionID string `gorm:"column:action_id;size:36;not null;default:'';index;" json:"action_id"`
+	Locale   string `gorm:"column:locale;size:16;not null;" json:"locale"`
+	Name     string `gorm:"column:name;not null;" json:"name"`
+}
+
+type MenuI18ns []*MenuI18n
+
+// ToNamesMap maps the menu id, or the action id for action names, to the names by locale
+func (ms MenuI18ns) ToNamesMap() map[string]map[string]string {
+	m := make(map[string]map[string]string)
+	for _, item := range ms {
+		id := item.MenuID
+		if item.ActionID != "" {
+			id = item.ActionID
+		}
+
+		if _, ok := m[id]; !ok {
+			m[id] = make(map[string]string)
+		}
+
+		m[id][item.Locale] = item.Name
+	}
+
+	return m
+}
+
+type MenuI18nQueryParam struct {
+	dto.PaginationParam
+	dto.OrderParam
+	dto.FilterParam
+
+	MenuID  string
+	MenuIDs []string
+	Locale  string
+}
+
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable menu i18n columns
+func (p *MenuI18nQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "locale")
+}
+
+// ParseFilters checks the filters of the query against the filterable menu i18n columns
+func (p *MenuI18nQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"menu_id":    dto.FilterString,
+		"action_id":  dto.FilterString,
+		"locale":     dto.FilterString,
+		"name":       dto.FilterString,
+		"created_at": dto.FilterTime,
+	})
+}
+
+type MenuI18nQueryResult struct {
+	List       MenuI18ns       `json:"list"`
+	Pagination *dto.Pagination `json:"pagination"`
+}
+
+// EqualI18n reports whether two locale to name maps hold the same names
+func EqualI18n(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for locale, name := range a {
+		if v, ok := b[locale]; !ok || v != name {
+			return false
+		}
+	}
+
+	return true
+}
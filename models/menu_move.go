@@ -0,0 +1,33 @@
+package models
+
+// MenuMove new position of a menu, the children are moved under it.
+// A zero Sequence keeps the current sequence.
+type MenuMove struct {
+	ID       string    `json:"id" validate:"required"`
+	ParentID string    `json:"parent_id"`
+	Sequence int       `json:"sequence"`
+	Children MenuMoves `json:"children,omitempty"`
+}
+
+type MenuMoves []*MenuMove
+
+// MenuMoveParam a full or partial menu tree of ids
+type MenuMoveParam struct {
+	Menus MenuMoves `json:"menus" validate:"required"`
+}
+
+// Flatten lists every move with its parent id resolved from the enclosing node
+func (ms MenuMoves) Flatten(parentID string) MenuMoves {
+	list := make(MenuMoves, 0, len(ms))
+	for _, v := range ms {
+		pid := v.ParentID
+		if parentID != "" {
+			pid = parentID
+		}
+
+		list = append(list, &MenuMove{ID: v.ID, ParentID: pid, Sequence: v.Sequence})
+		list = append(list, v.Children.Flatten(v.ID)...)
+	}
+
+	return list
+}
@@ -0,0 +1,44 @@
+package models
+
+import "fmt"
+
+const (
+	MenuSyncCreate = "create"
+	MenuSyncUpdate = "update"
+	MenuSyncDelete = "delete"
+
+	MenuSyncKindMenu     = "menu"
+	MenuSyncKindAction   = "action"
+	MenuSyncKindResource = "resource"
+)
+
+// MenuSyncChange a single change of the menu file synchronization plan.
+// Menus are keyed by their name path in the file, actions by menu key and code,
+// resources by action key, method and path.
+type MenuSyncChange struct {
+	Op     string   `json:"op"`
+	Kind   string   `json:"kind"`
+	Key    string   `json:"key"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+func (c MenuSyncChange) String() string {
+	if len(c.Fields) > 0 {
+		return fmt.Sprintf("%-6s %-8s %s %v", c.Op, c.Kind, c.Key, c.Fields)
+	}
+
+	return fmt.Sprintf("%-6s %-8s %s", c.Op, c.Kind, c.Key)
+}
+
+type MenuSyncChanges []*MenuSyncChange
+
+func (cs MenuSyncChanges) Count(op string) int {
+	n := 0
+	for _, c := range cs {
+		if c.Op == op {
+			n++
+		}
+	}
+
+	return n
+}
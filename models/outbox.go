@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"manuel71sj/go-api-template/pkg/slice"
+	"time"
+)
+
+const (
+	AggregateUser = "user"
+	AggregateRole = "role"
+	AggregateMenu = "menu"
+)
+
+const (
+	EventUserCreated       = "user.created"
+	EventUserUpdated       = "user.updated"
+	EventUserDeleted       = "user.deleted"
+	EventUserStatusChanged = "user.status_changed"
+	EventUserRolesUpdated  = "user.roles_updated"
+	// EventUserNewDeviceLogin a login from a device or a network the user did not log in from lately
+	EventUserNewDeviceLogin = "user.new_device_login"
+
+	EventRoleCreated       = "role.created"
+	EventRoleUpdated       = "role.updated"
+	EventRoleDeleted       = "role.deleted"
+	EventRoleStatusChanged = "role.status_changed"
+	EventRoleMenusUpdated  = "role.menus_updated"
+
+	EventMenuCreated        = "menu.created"
+	EventMenuUpdated        = "menu.updated"
+	EventMenuDeleted        = "menu.deleted"
+	EventMenuStatusChanged  = "menu.status_changed"
+	EventMenuActionsUpdated = "menu.actions_updated"
+	EventMenuMoved          = "menu.moved"
+)
+
+// EventTypes the types of the domain events
+var EventTypes = []string{
+	EventUserCreated, EventUserUpdated, EventUserDeleted, EventUserStatusChanged, EventUserRolesUpdated, EventUserNewDeviceLogin,
+	EventRoleCreated, EventRoleUpdated, EventRoleDeleted, EventRoleStatusChanged, EventRoleMenusUpdated,
+	EventMenuCreated, EventMenuUpdated, EventMenuDeleted, EventMenuStatusChanged, EventMenuActionsUpdated, EventMenuMoved,
+}
+
+// OutboxEvent a domain event written in the transaction of the change it describes,
+// published by the outbox relay in the order of ID. A failed event is retried at NextAttemptAt
+// by the sinks not in PublishedSinks, it is dead once it failed the last attempt.
+type OutboxEvent struct {
+	ID             uint64     `gorm:"column:id;primaryKey;autoIncrement;"`
+	EventID        string     `gorm:"column:event_id;size:36;not null;uniqueIndex;"`
+	Type           string     `gorm:"column:type;size:64;not null;"`
+	AggregateType  string     `gorm:"column:aggregate_type;size:32;not null;"`
+	AggregateID    string     `gorm:"column:aggregate_id;size:36;not null;index;"`
+	Payload        string     `gorm:"column:payload;type:text;not null;"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null;"`
+	PublishedAt    *time.Time `gorm:"column:published_at;index;"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0;"`
+	LastError      string     `gorm:"column:last_error;type:text;"`
+	NextAttemptAt  *time.Time `gorm:"column:next_attempt_at;index;"`
+	DeadAt         *time.Time `gorm:"column:dead_at;"`
+	PublishedSinks []string   `gorm:"column:published_sinks;type:text;serializer:json;"`
+}
+
+// AggregateKey identifies the aggregate, the events of an aggregate are published in order
+func (e *OutboxEvent) AggregateKey() string {
+	return e.AggregateType + ":" + e.AggregateID
+}
+
+// PublishedTo reports whether the sink already published the event
+func (e *OutboxEvent) PublishedTo(sink string) bool {
+	return slice.ContainsString(e.PublishedSinks, sink)
+}
+
+// DomainEvent returns the event as handed to the event sinks
+func (e *OutboxEvent) DomainEvent() *DomainEvent {
+	return &DomainEvent{
+		ID:            e.EventID,
+		Type:          e.Type,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		OccurredAt:    e.CreatedAt,
+		Data:          json.RawMessage(e.Payload),
+	}
+}
+
+type OutboxEvents []*OutboxEvent
+
+// DomainEvent a published event, consumers deduplicate the redeliveries by ID
+type DomainEvent struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// EventDeletedData the data of the deleted events
+type EventDeletedData struct {
+	ID string `json:"id"`
+}
+
+// EventStatusData the data of the status_changed events
+type EventStatusData struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+}
+
+// EventUserRolesData the data of user.roles_updated, the added and removed role ids
+type EventUserRolesData struct {
+	ID      string   `json:"id"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// EventRoleMenusData the data of role.menus_updated
+type EventRoleMenusData struct {
+	ID      string    `json:"id"`
+	Added   RoleMenus `json:"added"`
+	Removed RoleMenus `json:"removed"`
+}
+
+// EventMenuActionsData the data of menu.actions_updated, the actions of the menu after the update
+type EventMenuActionsData struct {
+	ID      string      `json:"id"`
+	Actions MenuActions `json:"actions"`
+}
+
+// EventNewDeviceLoginData the data of user.new_device_login
+type EventNewDeviceLoginData struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"user_agent"`
+	NewDevice   bool      `json:"new_device"`
+	NewLocation bool      `json:"new_location"`
+	LoggedInAt  time.Time `json:"logged_in_at"`
+}
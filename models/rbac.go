@@ -0,0 +1,63 @@
+package models
+
+const RbacBundleVersion = 1
+
+const (
+	RbacImportSkip      = "skip"
+	RbacImportOverwrite = "overwrite"
+	RbacImportFail      = "fail"
+
+	RbacImportCreate = "create"
+
+	RbacKindRole = "role"
+	RbacKindUser = "user"
+)
+
+// RbacBundle portable snapshot of the roles, their menus and optionally the user roles.
+// Menus are referenced by name path (see Menus.ToNamePathMap) and actions by code,
+// users by username and roles by name, so a bundle can be moved between environments.
+type RbacBundle struct {
+	Version int         `json:"version" yaml:"version"`
+	Roles   []*RbacRole `json:"roles" yaml:"roles"`
+	Users   []*RbacUser `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+type RbacRole struct {
+	Name     string          `json:"name" yaml:"name"`
+	Remark   string          `json:"remark,omitempty" yaml:"remark,omitempty"`
+	Sequence int             `json:"sequence" yaml:"sequence"`
+	Status   int             `json:"status" yaml:"status"`
+	Menus    []*RbacRoleMenu `json:"menus" yaml:"menus"`
+}
+
+type RbacRoleMenu struct {
+	Menu    string   `json:"menu" yaml:"menu"`
+	Actions []string `json:"actions" yaml:"actions"`
+}
+
+type RbacUser struct {
+	Username string   `json:"username" yaml:"username"`
+	Roles    []string `json:"roles" yaml:"roles"`
+}
+
+type RbacExportParam struct {
+	Format       string `query:"format" validate:"in=json;yaml"`
+	IncludeUsers bool   `query:"include_users"`
+}
+
+type RbacImportParam struct {
+	DryRun   bool   `query:"dry_run"`
+	Strategy string `query:"strategy" validate:"in=skip;overwrite;fail"`
+	Operator string `query:"-"`
+}
+
+type RbacImportChange struct {
+	Op   string `json:"op"`
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+}
+
+type RbacImportResult struct {
+	DryRun  bool                `json:"dry_run"`
+	Changes []*RbacImportChange `json:"changes"`
+}
@@ -9,13 +9,25 @@ type Role struct {
 	database.Model
 	ID        string    `gorm:"column:id;size:36;index;not null;" json:"id"`
 	Name      string    `gorm:"column:name;not null;" json:"name" validate:"required"`
-	Remark    string    `gorm:"column:remark;default:'';" json:"remark" validate:"required"`
-	Sequence  int       `gorm:"column:sequence;not null;index;" json:"sequence" validate:"required"`
-	Status    int       `gorm:"column:status;not null;default:0;" json:"status" validate:"required,max=1,min=-1"`
+	Remark    string    `gorm:"column:remark;default:'';" json:"remark"`
+	Sequence  int       `gorm:"column:sequence;not null;index;" json:"sequence"`
+	Status    int       `gorm:"column:status;not null;default:0;" json:"status" validate:"max=1,min=-1"`
 	CreatedBy string    `gorm:"column:created_by;not null;" json:"created_by"`
 	RoleMenus RoleMenus `gorm:"-" json:"role_menus"`
 }
 
+// Audited returns the role as recorded by the audit log, with its menus by key
+func (r *Role) Audited() interface{} {
+	if r == nil {
+		return nil
+	}
+
+	return &struct {
+		*Role
+		RoleMenus []string `json:"role_menus"`
+	}{r, r.RoleMenus.ToKeys()}
+}
+
 type Roles []*Role
 
 func (r Roles) ToNames() []string {
@@ -39,6 +51,7 @@ func (r Roles) ToMap() map[string]*Role {
 type RoleQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	IDs        []string `query:"ids"`
 	Name       string   `query:"name"`
@@ -47,6 +60,25 @@ type RoleQueryParam struct {
 	Status     int      `query:"status" validate:"max=1,min=-1"`
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable role columns
+func (p *RoleQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "updated_at", "name", "sequence", "status")
+}
+
+// ParseFilters checks the filters of the query against the filterable role columns
+func (p *RoleQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"id":         dto.FilterString,
+		"name":       dto.FilterString,
+		"remark":     dto.FilterString,
+		"sequence":   dto.FilterInt,
+		"status":     dto.FilterInt,
+		"created_by": dto.FilterString,
+		"created_at": dto.FilterTime,
+		"updated_at": dto.FilterTime,
+	})
+}
+
 type RoleQueryResult struct {
 	List       Roles           `json:"list"`
 	Pagination *dto.Pagination `json:"pagination"`
@@ -3,6 +3,7 @@ package models
 import (
 	"manuel71sj/go-api-template/models/database"
 	"manuel71sj/go-api-template/models/dto"
+	"sort"
 )
 
 type RoleMenu struct {
@@ -18,11 +19,27 @@ type RoleMenus []*RoleMenu
 type RoleMenuQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	RoleID  string
 	RoleIDs []string
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable role menu columns
+func (p *RoleMenuQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at")
+}
+
+// ParseFilters checks the filters of the query against the filterable role menu columns
+func (p *RoleMenuQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"role_id":    dto.FilterString,
+		"menu_id":    dto.FilterString,
+		"action_id":  dto.FilterString,
+		"created_at": dto.FilterTime,
+	})
+}
+
 type RoleMenuQueryResult struct {
 	List       RoleMenus       `json:"list"`
 	Pagination *dto.Pagination `json:"pagination"`
@@ -75,3 +92,14 @@ func (r RoleMenus) ToActionIDs() []string {
 
 	return idList
 }
+
+// ToKeys returns the sorted "menu_id-action_id" keys of the role menus
+func (r RoleMenus) ToKeys() []string {
+	keys := make([]string, 0, len(r))
+	for key := range r.ToMap() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
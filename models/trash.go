@@ -0,0 +1,40 @@
+package models
+
+import (
+	"manuel71sj/go-api-template/models/dto"
+	"time"
+)
+
+const (
+	TrashResourceUsers = "users"
+	TrashResourceRoles = "roles"
+	TrashResourceMenus = "menus"
+)
+
+// TrashAggregates the aggregate type of the resources of the trash
+var TrashAggregates = map[string]string{
+	TrashResourceUsers: AggregateUser,
+	TrashResourceRoles: AggregateRole,
+	TrashResourceMenus: AggregateMenu,
+}
+
+// TrashItem a soft deleted user, role or menu
+type TrashItem struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type TrashItems []*TrashItem
+
+type TrashQueryParam struct {
+	dto.PaginationParam
+
+	Resource   string `param:"resource" validate:"in=users;roles;menus"`
+	QueryValue string `query:"query_value"`
+}
+
+type TrashQueryResult struct {
+	List       TrashItems      `json:"list"`
+	Pagination *dto.Pagination `json:"pagination"`
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"manuel71sj/go-api-template/models/database"
 	"manuel71sj/go-api-template/models/dto"
+	"sort"
 )
 
 type User struct {
@@ -13,7 +14,8 @@ type User struct {
 	Password  string    `gorm:"column:password;not null;" json:"password" json:"phone"`
 	Email     string    `gorm:"column:email;default:'';" json:"email"`
 	Phone     string    `gorm:"column:phone;default:'';" json:"phone"`
-	Status    int       `gorm:"column:status;not null;default:0;" json:"status" validate:"required,max=1,min=-1"`
+	Status    int       `gorm:"column:status;not null;default:0;" json:"status" validate:"max=1,min=-1"`
+	Locale    string    `gorm:"column:locale;size:16;default:'';" json:"locale"`
 	CreatedBy string    `gorm:"column:created_by;not null;" json:"created_by"`
 	UserRoles UserRoles `gorm:"-" json:"user_roles"`
 }
@@ -23,6 +25,20 @@ func (u *User) CleanSecure() *User {
 	return u
 }
 
+// Audited returns the user as recorded by the audit log, with its roles by id
+func (u *User) Audited() interface{} {
+	if u == nil {
+		return nil
+	}
+
+	roleIDs := u.UserRoles.ToRoleIDs()
+	sort.Strings(roleIDs)
+	return &struct {
+		*User
+		UserRoles []string `json:"user_roles"`
+	}{u, roleIDs}
+}
+
 type Users []*User
 
 func (u Users) ToIDs() []string {
@@ -44,6 +60,7 @@ type UserInfo struct {
 type UserQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	QueryPassword bool
 	Username      string   `query:"username"`
@@ -53,6 +70,27 @@ type UserQueryParam struct {
 	RoleIDs       []string `query:"-"`
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable user columns
+func (p *UserQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "updated_at", "username", "realname", "status")
+}
+
+// ParseFilters checks the filters of the query against the filterable user columns
+func (p *UserQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"id":         dto.FilterString,
+		"username":   dto.FilterString,
+		"realname":   dto.FilterString,
+		"email":      dto.FilterString,
+		"phone":      dto.FilterString,
+		"status":     dto.FilterInt,
+		"locale":     dto.FilterString,
+		"created_by": dto.FilterString,
+		"created_at": dto.FilterTime,
+		"updated_at": dto.FilterTime,
+	})
+}
+
 type UserQueryResult struct {
 	List       Users           `json:"list"`
 	Pagination *dto.Pagination `json:"pagination"`
@@ -44,11 +44,26 @@ func (u UserRoles) ToUserIDMap() map[string]UserRoles {
 type UserRoleQueryParam struct {
 	dto.PaginationParam
 	dto.OrderParam
+	dto.FilterParam
 
 	UserID  string
 	UserIDs []string
 }
 
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable user role columns
+func (p *UserRoleQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at")
+}
+
+// ParseFilters checks the filters of the query against the filterable user role columns
+func (p *UserRoleQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"user_id":    dto.FilterString,
+		"role_id":    dto.FilterString,
+		"created_at": dto.FilterTime,
+	})
+}
+
 type UserRoleQueryResult struct {
 	List       UserRoles       `json:"list"`
 	Pagination *dto.Pagination `json:"pagination"`
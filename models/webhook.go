@@ -0,0 +1,157 @@
+package models
+
+import (
+	"manuel71sj/go-api-template/models/database"
+	"manuel71sj/go-api-template/models/dto"
+	"manuel71sj/go-api-template/pkg/slice"
+	"strings"
+	"time"
+)
+
+// EventWebhookPing the event sent by the ping of a webhook, never written to the outbox
+const EventWebhookPing = "webhook.ping"
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryDead the delivery failed every attempt, only a redelivery sends it again
+	WebhookDeliveryDead = "dead"
+)
+
+// Webhook a subscription of an url to the domain events. Events are event types,
+// "user.*" for the events of an aggregate or "*" for every event.
+type Webhook struct {
+	database.Model
+	ID        string   `gorm:"column:id;size:36;index;not null;" json:"id"`
+	Name      string   `gorm:"column:name;size:64;not null;" json:"name" validate:"required"`
+	URL       string   `gorm:"column:url;not null;" json:"url" validate:"required,url"`
+	Secret    string   `gorm:"column:secret;size:64;not null;" json:"-"`
+	Events    []string `gorm:"column:events;type:text;serializer:json;" json:"events" validate:"required,min=1"`
+	Status    int      `gorm:"column:status;not null;default:0;" json:"status" validate:"max=1,min=-1"`
+	Remark    string   `gorm:"column:remark;default:'';" json:"remark"`
+	CreatedBy string   `gorm:"column:created_by;not null;" json:"created_by"`
+}
+
+// WebhookForm the webhook of a create or an update request. The secret is write-only:
+// it is read from the forms, and returned only by the create and the rotation of the secret.
+type WebhookForm struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// ToWebhook returns the webhook of the form with its secret
+func (f *WebhookForm) ToWebhook() *Webhook {
+	f.Webhook.Secret = f.Secret
+	return &f.Webhook
+}
+
+// Subscribes reports whether the event type matches one of the events of the webhook
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, pattern := range w.Events {
+		if pattern == "*" || pattern == eventType ||
+			(strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidWebhookEvent reports whether pattern is an event type, an aggregate wildcard or "*"
+func ValidWebhookEvent(pattern string) bool {
+	switch pattern {
+	case "*", AggregateUser + ".*", AggregateRole + ".*", AggregateMenu + ".*":
+		return true
+	default:
+		return slice.ContainsString(EventTypes, pattern)
+	}
+}
+
+type Webhooks []*Webhook
+
+type WebhookQueryParam struct {
+	dto.PaginationParam
+	dto.OrderParam
+	dto.FilterParam
+
+	Name       string `query:"name"`
+	QueryValue string `query:"query_value"`
+	Status     int    `query:"status" validate:"max=1,min=-1"`
+}
+
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable webhook columns
+func (p *WebhookQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "updated_at", "name", "status")
+}
+
+// ParseFilters checks the filters of the query against the filterable webhook columns
+func (p *WebhookQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"id":         dto.FilterString,
+		"name":       dto.FilterString,
+		"url":        dto.FilterString,
+		"status":     dto.FilterInt,
+		"created_by": dto.FilterString,
+		"created_at": dto.FilterTime,
+		"updated_at": dto.FilterTime,
+	})
+}
+
+type WebhookQueryResult struct {
+	List       Webhooks        `json:"list"`
+	Pagination *dto.Pagination `json:"pagination"`
+}
+
+// WebhookDelivery an event to deliver to a webhook and the outcome of its last attempt
+type WebhookDelivery struct {
+	database.Model
+	ID            string     `gorm:"column:id;size:36;index;not null;" json:"id"`
+	WebhookID     string     `gorm:"column:webhook_id;size:36;not null;index;" json:"webhook_id"`
+	EventID       string     `gorm:"column:event_id;size:36;not null;index;" json:"event_id"`
+	EventType     string     `gorm:"column:event_type;size:64;not null;" json:"event_type"`
+	Payload       string     `gorm:"column:payload;type:text;not null;" json:"payload"`
+	Status        string     `gorm:"column:status;size:16;not null;index;" json:"status"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0;" json:"attempts"`
+	NextAttemptAt *time.Time `gorm:"column:next_attempt_at;index;" json:"next_attempt_at"`
+	LastAttemptAt *time.Time `gorm:"column:last_attempt_at;" json:"last_attempt_at"`
+	ResponseCode  int        `gorm:"column:response_code;not null;default:0;" json:"response_code"`
+	ResponseBody  string     `gorm:"column:response_body;type:text;" json:"response_body"`
+	LastError     string     `gorm:"column:last_error;type:text;" json:"last_error"`
+	DeliveredAt   *time.Time `gorm:"column:delivered_at;" json:"delivered_at"`
+}
+
+type WebhookDeliveries []*WebhookDelivery
+
+type WebhookDeliveryQueryParam struct {
+	dto.PaginationParam
+	dto.OrderParam
+	dto.FilterParam
+
+	WebhookID string `param:"id"`
+	EventType string `query:"event_type"`
+	Status    string `query:"status" validate:"in=pending;succeeded;dead"`
+}
+
+// ParseOrder builds the ORDER BY clause of the sort spec with the sortable delivery columns
+func (p *WebhookDeliveryQueryParam) ParseOrder() (string, error) {
+	return p.OrderParam.ParseOrder("record_id", "created_at", "updated_at", "next_attempt_at", "attempts")
+}
+
+// ParseFilters checks the filters of the query against the filterable delivery columns
+func (p *WebhookDeliveryQueryParam) ParseFilters() ([]*dto.Filter, error) {
+	return p.FilterParam.ParseFilters(dto.FilterFields{
+		"event_id":        dto.FilterString,
+		"event_type":      dto.FilterString,
+		"status":          dto.FilterString,
+		"attempts":        dto.FilterInt,
+		"response_code":   dto.FilterInt,
+		"next_attempt_at": dto.FilterTime,
+		"delivered_at":    dto.FilterTime,
+		"created_at":      dto.FilterTime,
+	})
+}
+
+type WebhookDeliveryQueryResult struct {
+	List       WebhookDeliveries `json:"list"`
+	Pagination *dto.Pagination   `json:"pagination"`
+}
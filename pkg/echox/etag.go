@@ -0,0 +1,35 @@
+package echox
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/errors"
+	"strconv"
+	"strings"
+)
+
+// SetETag sets the ETag header to the record version, sent back in If-Match to update the record
+func SetETag(ctx echo.Context, version int) {
+	ctx.Response().Header().Set("ETag", fmt.Sprintf(`W/"%d"`, version))
+}
+
+// IfMatchVersion returns the record version of the If-Match header, 0 when it is absent or "*",
+// which update whatever the current version is. Any other value but an ETag of SetETag is an error.
+func IfMatchVersion(ctx echo.Context) (int, error) {
+	tag := strings.TrimSpace(ctx.Request().Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+
+	quoted := strings.TrimPrefix(tag, "W/")
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return 0, errors.Wrap(errors.IfMatchInvalid, tag)
+	}
+
+	version, err := strconv.Atoi(quoted[1 : len(quoted)-1])
+	if err != nil || version <= 0 {
+		return 0, errors.Wrap(errors.IfMatchInvalid, tag)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,44 @@
+package echox
+
+import (
+	"github.com/labstack/echo/v4"
+	"manuel71sj/go-api-template/errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int
+		invalid bool
+	}{
+		{header: "", version: 0},
+		{header: "*", version: 0},
+		{header: `W/"3"`, version: 3},
+		{header: ` "7" `, version: 7},
+		{header: "3", invalid: true},
+		{header: `W/"abc"`, invalid: true},
+		{header: `"0"`, invalid: true},
+		{header: `"-1"`, invalid: true},
+		{header: `"`, invalid: true},
+	}
+
+	e := echo.New()
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/", nil)
+		if test.header != "" {
+			req.Header.Set("If-Match", test.header)
+		}
+
+		version, err := IfMatchVersion(e.NewContext(req, httptest.NewRecorder()))
+		if test.invalid {
+			if !errors.Is(err, errors.IfMatchInvalid) {
+				t.Errorf("If-Match %q: error %v, want %v", test.header, err, errors.IfMatchInvalid)
+			}
+		} else if err != nil || version != test.version {
+			t.Errorf("If-Match %q: version %d, error %v, want %d", test.header, version, err, test.version)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package echox
+
+import (
+	"github.com/labstack/echo/v4"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptLanguages returns the locales of the Accept-Language header ordered by quality
+func AcceptLanguages(ctx echo.Context) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(ctx.Request().Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil {
+				quality = q
+			}
+		}
+
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, v := range languages {
+		tags[i] = v.tag
+	}
+
+	return tags
+}
@@ -29,6 +29,10 @@ func (r Response) JSON(ctx echo.Context) error {
 			r.Code = http.StatusNotFound
 		}
 
+		if errors.Is(err, errors.DatabaseVersionConflict) {
+			r.Code = http.StatusConflict
+		}
+
 		r.Message = err.Error()
 	}
 
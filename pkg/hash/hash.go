@@ -1,6 +1,7 @@
 package hash
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
@@ -22,3 +23,10 @@ func SHA256(s string) string {
 	sum := sha256.Sum256(str.S(s).Bytes())
 	return hex.EncodeToString(sum[:])
 }
+
+// HMACSHA256 returns the hex HMAC-SHA256 of s keyed with key
+func HMACSHA256(key, s string) string {
+	mac := hmac.New(sha256.New, str.S(key).Bytes())
+	mac.Write(str.S(s).Bytes())
+	return hex.EncodeToString(mac.Sum(nil))
+}